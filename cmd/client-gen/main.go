@@ -15,6 +15,7 @@ import (
 	"github.com/dagger/dagger/codegen/generator"
 	gogenerator "github.com/dagger/dagger/codegen/generator/go"
 	nodegenerator "github.com/dagger/dagger/codegen/generator/nodejs"
+	pythongenerator "github.com/dagger/dagger/codegen/generator/python"
 	"github.com/dagger/dagger/codegen/introspection"
 	"github.com/dagger/dagger/engine"
 	internalengine "github.com/dagger/dagger/internal/engine"
@@ -36,62 +37,85 @@ func init() {
 	rootCmd.Flags().StringP("output", "o", "", "output file")
 	rootCmd.Flags().String("package", "", "package name")
 	rootCmd.Flags().String("lang", "", "language to generate in")
+	rootCmd.Flags().Bool("sync", false, "generate a client for synchronous code (python only)")
+	rootCmd.Flags().String("schema", "", "generate from a schema file exported by `dagger schema export` (.json or .graphql) instead of starting an engine")
 }
 
 func ClientGen(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	schemaFile, err := cmd.Flags().GetString("schema")
+	if err != nil {
+		return err
+	}
+	if schemaFile != "" {
+		schema, err := generator.IntrospectFile(ctx, schemaFile)
+		if err != nil {
+			return err
+		}
+		return clientGen(ctx, cmd, schema)
+	}
+
 	engineConf := engine.Config{
 		Workdir:    workdir,
 		RunnerHost: internalengine.RunnerHost(),
 	}
 	return engine.Start(ctx, engineConf, func(ctx context.Context, r *router.Router) error {
-		lang, err := getLang(cmd)
+		schema, err := generator.Introspect(ctx, r)
 		if err != nil {
 			return err
 		}
+		return clientGen(ctx, cmd, schema)
+	})
+}
 
-		pkg, err := getPackage(cmd)
-		if err != nil {
-			return err
-		}
+func clientGen(ctx context.Context, cmd *cobra.Command, schema *introspection.Schema) error {
+	lang, err := getLang(cmd)
+	if err != nil {
+		return err
+	}
 
-		schema, err := generator.Introspect(ctx, r)
-		if err != nil {
-			return err
-		}
+	pkg, err := getPackage(cmd)
+	if err != nil {
+		return err
+	}
 
-		generated, err := generate(ctx, schema, generator.Config{
-			Package: pkg,
-			Lang:    generator.SDKLang(lang),
-		})
-		if err != nil {
+	sync, err := cmd.Flags().GetBool("sync")
+	if err != nil {
+		return err
+	}
+
+	generated, err := generate(ctx, schema, generator.Config{
+		Package: pkg,
+		Lang:    generator.SDKLang(lang),
+		Sync:    sync,
+	})
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	if output == "" || output == "-" {
+		fmt.Fprint(os.Stdout, string(generated))
+	} else {
+		if err := os.MkdirAll(filepath.Dir(output), 0o700); err != nil {
 			return err
 		}
-
-		output, err := cmd.Flags().GetString("output")
-		if err != nil {
+		if err := os.WriteFile(output, generated, 0o600); err != nil {
 			return err
 		}
 
-		if output == "" || output == "-" {
-			fmt.Fprint(os.Stdout, string(generated))
-		} else {
-			if err := os.MkdirAll(filepath.Dir(output), 0o700); err != nil {
-				return err
-			}
-			if err := os.WriteFile(output, generated, 0o600); err != nil {
-				return err
-			}
-
-			gitAttributes := fmt.Sprintf("/%s linguist-generated=true", filepath.Base(output))
-			if err := os.WriteFile(path.Join(filepath.Dir(output), ".gitattributes"), []byte(gitAttributes), 0o600); err != nil {
-				return err
-			}
+		gitAttributes := fmt.Sprintf("/%s linguist-generated=true", filepath.Base(output))
+		if err := os.WriteFile(path.Join(filepath.Dir(output), ".gitattributes"), []byte(gitAttributes), 0o600); err != nil {
+			return err
 		}
+	}
 
-		return nil
-	})
+	return nil
 }
 
 func getLang(cmd *cobra.Command) (string, error) {
@@ -154,11 +178,16 @@ func generate(ctx context.Context, schema *introspection.Schema, cfg generator.C
 		}
 	case generator.SDKLangNodeJS:
 		gen = &nodegenerator.NodeGenerator{}
+	case generator.SDKLangPython:
+		gen = &pythongenerator.PythonGenerator{
+			Config: cfg,
+		}
 
 	default:
 		sdks := []string{
 			string(generator.SDKLangGo),
 			string(generator.SDKLangNodeJS),
+			string(generator.SDKLangPython),
 		}
 		return []byte{}, fmt.Errorf("use target SDK language: %s: %w", sdks, generator.ErrUnknownSDKLang)
 	}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/router"
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache volumes in a Dagger engine",
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List the cache volumes in the engine",
+	Long: `List the cache volumes in the engine, with their size and when they were last used.
+
+Cache volumes are identified by a checksum of their keys, since the keys themselves aren't stored in the engine.`,
+	Args: cobra.NoArgs,
+	RunE: CacheLs,
+}
+
+var (
+	cacheAllFlag      bool
+	cacheChecksumFlag bool
+)
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune [key...]",
+	Short: "Delete cache volumes from the engine",
+	Long: `Delete the contents of the cache volumes with the given keys from the engine, or all of them with --all.
+
+With --checksum, the arguments are the checksums printed by "dagger cache ls" instead of keys.
+
+Cache volumes which are mounted by a running container can't be deleted.`,
+	Example: `  Delete a single cache volume:
+    dagger cache prune go-mod
+
+  Delete a cache volume listed by "dagger cache ls":
+    dagger cache prune --checksum 'n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg='
+
+  Delete every cache volume:
+    dagger cache prune --all`,
+	RunE: CachePrune,
+}
+
+func init() {
+	cachePruneCmd.Flags().BoolVar(&cacheAllFlag, "all", false, "delete every cache volume")
+	cachePruneCmd.Flags().BoolVar(&cacheChecksumFlag, "checksum", false, "identify the cache volumes by their checksum instead of their keys")
+
+	cacheCmd.AddCommand(cacheLsCmd, cachePruneCmd)
+}
+
+type cacheVolumeUsage struct {
+	Checksum string
+	Size     int64
+	LastUsed *int64
+}
+
+func CacheLs(cmd *cobra.Command, args []string) error {
+	var res struct {
+		CacheVolumes []cacheVolumeUsage
+	}
+
+	err := withEngineAndTUI(cmd.Context(), engine.Config{}, func(ctx context.Context, r *router.Router) error {
+		_, err := r.Do(ctx, `{ cacheVolumes { checksum size lastUsed } }`, "", nil, &res)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECKSUM\tSIZE\tLAST USED")
+	for _, vol := range res.CacheVolumes {
+		lastUsed := "-"
+		if vol.LastUsed != nil {
+			lastUsed = units.HumanDuration(time.Since(time.Unix(*vol.LastUsed, 0))) + " ago"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", vol.Checksum, units.HumanSize(float64(vol.Size)), lastUsed)
+	}
+
+	return tw.Flush()
+}
+
+func CachePrune(cmd *cobra.Command, args []string) error {
+	switch {
+	case cacheAllFlag && len(args) > 0:
+		return errors.New("cannot specify keys with --all")
+	case !cacheAllFlag && len(args) == 0:
+		return errors.New("specify the keys of the cache volumes to delete, or --all")
+	case cacheAllFlag && cacheChecksumFlag:
+		return errors.New("cannot specify --checksum with --all")
+	}
+
+	return withEngineAndTUI(cmd.Context(), engine.Config{}, func(ctx context.Context, r *router.Router) error {
+		if cacheAllFlag {
+			_, err := r.Do(ctx, `{ cacheVolumes { prune } }`, "", nil, nil)
+			return err
+		}
+
+		if cacheChecksumFlag {
+			return pruneChecksums(ctx, r, args)
+		}
+
+		for _, key := range args {
+			_, err := r.Do(ctx, `query Prune($key: String!) { cacheVolume(key: $key) { prune } }`, "", map[string]any{
+				"key": key,
+			}, nil)
+			if err != nil {
+				return fmt.Errorf("prune %s: %w", key, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// pruneChecksums deletes the listed cache volumes with the given checksums.
+func pruneChecksums(ctx context.Context, r *router.Router, checksums []string) error {
+	var res struct {
+		CacheVolumes []struct {
+			Checksum string
+		}
+	}
+	_, err := r.Do(ctx, `query Volumes($checksums: [String!]) { cacheVolumes(checksums: $checksums) { checksum } }`, "", map[string]any{
+		"checksums": checksums,
+	}, &res)
+	if err != nil {
+		return err
+	}
+
+	found := map[string]bool{}
+	for _, vol := range res.CacheVolumes {
+		found[vol.Checksum] = true
+	}
+
+	for _, sum := range checksums {
+		if !found[sum] {
+			return fmt.Errorf("prune %s: no cache volume with this checksum", sum)
+		}
+
+		_, err := r.Do(ctx, `query Prune($checksums: [String!]) { cacheVolumes(checksums: $checksums) { prune } }`, "", map[string]any{
+			"checksums": []string{sum},
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("prune %s: %w", sum, err)
+		}
+	}
+
+	return nil
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"os"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/dagger/dagger/engine"
@@ -16,8 +20,12 @@ import (
 )
 
 var (
-	listenAddress string
-	disableHostRW bool
+	listenAddress      string
+	disableHostRW      bool
+	queryLimits        router.Limits
+	tokensFile         string
+	sessionIdleTimeout time.Duration
+	maxSessions        int
 )
 
 var listenCmd = &cobra.Command{
@@ -26,16 +34,72 @@ var listenCmd = &cobra.Command{
 	Run:     Listen,
 	Hidden:  true,
 	Short:   "Starts the engine server",
+	Long: `Starts the engine server
+
+Each client is served from its own engine session, so that clients don't share secrets, registry credentials or progress. Clients are told apart by the token they authenticate with, i.e. their DAGGER_SESSION_TOKEN, and requests without a token are rejected with 401 Unauthorized.
+
+Without --tokens-file, any non-empty token is accepted and starts a new session, so anyone who can reach the listener can start up to --max-sessions sessions. With --tokens-file, only the listed tokens are accepted, each limited to its scope.`,
 }
 
 func init() {
-	listenCmd.Flags().StringVarP(&listenAddress, "listen", "", "127.0.0.1:8080", "Listen on network address ADDR")
+	listenCmd.Flags().StringVarP(&listenAddress, "listen", "", "127.0.0.1:8080", "Listen on network address ADDR, or on a unix socket with unix:///path/to/sock")
 	listenCmd.Flags().BoolVar(&disableHostRW, "disable-host-read-write", false, "disable host read/write access")
+	listenCmd.Flags().IntVar(&queryLimits.MaxDepth, "max-query-depth", 0, "reject queries nesting fields deeper than this (0 for unlimited)")
+	listenCmd.Flags().IntVar(&queryLimits.MaxAliases, "max-query-aliases", 0, "reject queries with more aliased fields than this, counting fragments each time they're spread (0 for unlimited)")
+	listenCmd.Flags().IntVar(&queryLimits.MaxBatchSize, "max-batch-size", 100, "reject batches of more queries than this (0 for unlimited)")
+	listenCmd.Flags().DurationVar(&queryLimits.Timeout, "query-timeout", 0, "cancel queries running longer than this (0 for no timeout)")
+	listenCmd.Flags().StringVar(&tokensFile, "tokens-file", "", "require one of the scoped API tokens listed in this JSON file")
+	listenCmd.Flags().DurationVar(&sessionIdleTimeout, "session-idle-timeout", 10*time.Minute, "stop a client's session once it has been idle for this long (0 to keep it until shutdown)")
+	listenCmd.Flags().IntVar(&maxSessions, "max-sessions", 32, "reject clients with 503 Service Unavailable while this many have a session (0 for unlimited)")
+}
+
+// tokensConfig is the format of the --tokens-file, e.g.:
+//
+//	{"tokens": [{
+//	  "token": "...",
+//	  "fields": ["Query.container", "Container.from", "Container.publish"],
+//	  "argPrefixes": {"Container.publish.address": ["registry.example.com/team/"]}
+//	}]}
+type tokensConfig struct {
+	Tokens []struct {
+		Token string `json:"token"`
+		router.Scope
+	} `json:"tokens"`
+}
+
+func loadTokens(path string) (map[string]router.Scope, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config tokensConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	tokens := map[string]router.Scope{}
+	for i, t := range config.Tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("%s: token %d is empty", path, i)
+		}
+		if _, ok := tokens[t.Token]; ok {
+			return nil, fmt.Errorf("%s: token %d is listed twice", path, i)
+		}
+		tokens[t.Token] = t.Scope
+	}
+	return tokens, nil
 }
 
 func Listen(cmd *cobra.Command, args []string) {
 	ctx := context.Background()
-	if err := withEngineAndTUI(ctx, engine.Config{}, func(ctx context.Context, r *router.Router) error {
+	tokens, err := loadTokens(tokensFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := withEngineAndTUI(ctx, engine.Config{QueryLimits: queryLimits}, func(ctx context.Context, _ *router.Router) error {
 		rec := progrock.RecorderFromContext(ctx)
 
 		var stderr io.Writer
@@ -46,14 +110,18 @@ func Listen(cmd *cobra.Command, args []string) {
 			stderr = vtx.Stderr()
 		}
 
-		sessionL, err := net.Listen("tcp", listenAddress)
+		sessionL, err := sessionListener(listenAddress)
 		if err != nil {
 			return fmt.Errorf("session listen: %w", err)
 		}
 		defer sessionL.Close()
 
+		// each client gets its own session, see listenSessions
+		sessions := newListenSessions(ctx, clientEngineConfig(rec), tokens, sessionIdleTimeout, maxSessions, stderr)
+		defer sessions.Close()
+
 		srv := &http.Server{
-			Handler: r,
+			Handler: sessions,
 			// Gosec G112: prevent slowloris attacks
 			ReadHeaderTimeout: 10 * time.Second,
 		}
@@ -64,7 +132,11 @@ func Listen(cmd *cobra.Command, args []string) {
 			srv.Shutdown(context.Background())
 		}()
 
-		fmt.Fprintf(stderr, "==> server listening on http://%s/query\n", listenAddress)
+		if sessionL.Addr().Network() == "unix" {
+			fmt.Fprintf(stderr, "==> server listening on %s\n", listenAddress)
+		} else {
+			fmt.Fprintf(stderr, "==> server listening on http://%s/query\n", listenAddress)
+		}
 
 		return srv.Serve(sessionL)
 	}); err != nil {
@@ -72,3 +144,42 @@ func Listen(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 }
+
+// sessionListener listens on addr, which is either a TCP address or the path
+// of a unix socket as unix:///path/to/sock. Unix sockets are only accessible
+// to the current user, so that access is controlled by file permissions
+// rather than by the secrecy of the session token alone.
+func sessionListener(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix://")
+	if !ok {
+		return net.Listen("tcp", strings.TrimPrefix(addr, "tcp://"))
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	return listenUnix(path)
+}
+
+// removeStaleSocket removes the socket at path if it was left behind by a
+// listener that didn't shut down cleanly, i.e. if nothing accepts connections
+// on it anymore.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		// nothing to remove; listening reports anything else in the way
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another listener", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("check %s: %w", path, err)
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/engine"
+	internalengine "github.com/dagger/dagger/internal/engine"
+	"github.com/dagger/dagger/router"
+	"github.com/vito/progrock"
+)
+
+// listenSessions serves each client of `dagger listen` from its own engine
+// session, so that clients don't share secrets, registry credentials or
+// progress. Clients are told apart by the token they authenticate with, i.e.
+// the session token of their SDK connection; requests without one are
+// rejected, since there would be no session to serve them from that isn't
+// shared with other clients.
+type listenSessions struct {
+	ctx    context.Context
+	conf   engine.Config
+	stderr io.Writer
+
+	// tokens are the tokens clients may authenticate with; any non-empty
+	// token is accepted if nil, so that only --max-sessions limits how many
+	// sessions clients can start
+	tokens map[string]router.Scope
+
+	// idleTimeout is how long a client's session is kept once it has no
+	// requests in flight
+	idleTimeout time.Duration
+
+	// maxSessions is how many clients may have a session at once; 0 for
+	// unlimited
+	maxSessions int
+
+	// startEngine starts a client's session, i.e. engine.Start
+	startEngine func(context.Context, engine.Config, engine.StartCallback) error
+
+	sessions map[string]*listenSession
+	wg       sync.WaitGroup
+	l        sync.Mutex
+}
+
+type listenSession struct {
+	id     string
+	router *router.Router
+	err    error
+	ready  chan struct{}
+	stop   context.CancelFunc
+
+	// active is the number of requests in flight, and lastUsed when the last
+	// one completed
+	active   int
+	lastUsed time.Time
+}
+
+func newListenSessions(ctx context.Context, conf engine.Config, tokens map[string]router.Scope, idleTimeout time.Duration, maxSessions int, stderr io.Writer) *listenSessions {
+	s := &listenSessions{
+		ctx:         ctx,
+		conf:        conf,
+		stderr:      stderr,
+		tokens:      tokens,
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+		startEngine: engine.Start,
+		sessions:    map[string]*listenSession{},
+	}
+	if idleTimeout > 0 {
+		go s.reap()
+	}
+	return s
+}
+
+func (s *listenSessions) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	token, _, _ := req.BasicAuth()
+	if _, ok := s.tokens[token]; token == "" || s.tokens != nil && !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Access to the Dagger engine session"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	sess, ok := s.acquire(token)
+	if !ok {
+		// every client starts a whole engine session, so don't let them
+		// exhaust the engine, e.g. by making up tokens
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "too many client sessions", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.release(sess)
+
+	select {
+	case <-sess.ready:
+	case <-req.Context().Done():
+		return
+	}
+	if sess.err != nil {
+		http.Error(w, fmt.Sprintf("start session: %v", sess.err), http.StatusServiceUnavailable)
+		return
+	}
+	sess.router.ServeHTTP(w, req)
+}
+
+// acquire returns the client's session, starting it if needed, or false if
+// there are already as many sessions as allowed.
+func (s *listenSessions) acquire(token string) (*listenSession, bool) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	sess, ok := s.sessions[token]
+	if !ok {
+		if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+			return nil, false
+		}
+		sess = s.start(token)
+		s.sessions[token] = sess
+	}
+	sess.active++
+	return sess, true
+}
+
+func (s *listenSessions) release(sess *listenSession) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	sess.active--
+	sess.lastUsed = time.Now()
+}
+
+func (s *listenSessions) start(token string) *listenSession {
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	// identify the client in progress without revealing its token
+	sum := sha256.Sum256([]byte(token))
+	sess := &listenSession{
+		id:    hex.EncodeToString(sum[:])[:12],
+		ready: make(chan struct{}),
+		stop:  cancel,
+	}
+
+	conf := s.conf
+	conf.Labels = append([]pipeline.Label{{Name: "dagger.io/client.id", Value: sess.id}}, conf.Labels...)
+	if scope, ok := s.tokens[token]; ok {
+		conf.Tokens = map[string]router.Scope{token: scope}
+	} else {
+		conf.SessionToken = token
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.remove(sess)
+
+		fmt.Fprintf(s.stderr, "==> starting session for client %s\n", sess.id)
+		err := s.startEngine(ctx, conf, func(ctx context.Context, r *router.Router) error {
+			sess.router = r
+			close(sess.ready)
+			<-ctx.Done()
+			return nil
+		})
+		if sess.router == nil {
+			sess.err = err
+			close(sess.ready)
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintf(s.stderr, "==> session for client %s failed: %v\n", sess.id, err)
+			return
+		}
+		fmt.Fprintf(s.stderr, "==> session for client %s stopped\n", sess.id)
+	}()
+
+	return sess
+}
+
+// remove forgets the session, so that the client's next request starts a
+// new one.
+func (s *listenSessions) remove(sess *listenSession) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for token, other := range s.sessions {
+		if other == sess {
+			delete(s.sessions, token)
+		}
+	}
+}
+
+// reap stops the sessions which have been idle for longer than the idle
+// timeout.
+func (s *listenSessions) reap() {
+	ticker := time.NewTicker(s.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.l.Lock()
+			for token, sess := range s.sessions {
+				if sess.active == 0 && now.Sub(sess.lastUsed) > s.idleTimeout {
+					delete(s.sessions, token)
+					sess.stop()
+				}
+			}
+			s.l.Unlock()
+		}
+	}
+}
+
+// Close stops every session and waits for them to shut down.
+func (s *listenSessions) Close() {
+	s.l.Lock()
+	for _, sess := range s.sessions {
+		sess.stop()
+	}
+	s.l.Unlock()
+	s.wg.Wait()
+}
+
+// recorderWriter forwards the progress of the clients' sessions to the
+// listener's own, without closing it when a session ends.
+type recorderWriter struct {
+	rec *progrock.Recorder
+}
+
+func (w recorderWriter) WriteStatus(update *progrock.StatusUpdate) error {
+	return w.rec.Record(update)
+}
+
+func (w recorderWriter) Close() error {
+	return nil
+}
+
+// clientEngineConfig returns the configuration of the clients' sessions.
+func clientEngineConfig(rec *progrock.Recorder) engine.Config {
+	return engine.Config{
+		Workdir:        workdir,
+		RunnerHost:     internalengine.RunnerHost(),
+		DisableHostRW:  disableHostRW,
+		QueryLimits:    queryLimits,
+		ProgrockWriter: recorderWriter{rec},
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/router"
+	"github.com/dagger/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine starts sessions whose router resolves {client} to the client
+// id of the session, without starting an engine.
+type fakeEngine struct {
+	err     error
+	started int
+	stopped int
+	l       sync.Mutex
+}
+
+func (e *fakeEngine) start(ctx context.Context, conf engine.Config, fn engine.StartCallback) error {
+	e.l.Lock()
+	e.started++
+	e.l.Unlock()
+	defer func() {
+		e.l.Lock()
+		e.stopped++
+		e.l.Unlock()
+	}()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	r := router.New(conf.SessionToken, nil, router.Limits{}, conf.Tokens)
+	err := r.Add(router.StaticSchema(router.StaticSchemaParams{
+		Name:   "test",
+		Schema: `type Query { client: String! }`,
+		Resolvers: router.Resolvers{
+			"Query": router.ObjectResolver{
+				"client": func(p graphql.ResolveParams) (any, error) {
+					return conf.Labels[0].Value, nil
+				},
+			},
+		},
+	}))
+	if err != nil {
+		return err
+	}
+	return fn(ctx, r)
+}
+
+func (e *fakeEngine) counts() (int, int) {
+	e.l.Lock()
+	defer e.l.Unlock()
+	return e.started, e.stopped
+}
+
+func newTestSessions(t *testing.T, e *fakeEngine, tokens map[string]router.Scope, idleTimeout time.Duration, maxSessions int) *listenSessions {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newListenSessions(ctx, engine.Config{}, tokens, idleTimeout, maxSessions, io.Discard)
+	s.startEngine = e.start
+	t.Cleanup(func() {
+		cancel()
+		s.Close()
+	})
+	return s
+}
+
+func queryClient(t *testing.T, h http.Handler, token string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/query?query="+url.QueryEscape("{client}"), nil)
+	req.SetBasicAuth(token, "")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		return resp.Code, ""
+	}
+	var result struct {
+		Data struct {
+			Client string
+		}
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+	return resp.Code, result.Data.Client
+}
+
+func TestListenSessionsReuse(t *testing.T) {
+	t.Parallel()
+
+	e := &fakeEngine{}
+	s := newTestSessions(t, e, nil, 0, 0)
+
+	code, a := queryClient(t, s, "a")
+	require.Equal(t, http.StatusOK, code)
+	code, again := queryClient(t, s, "a")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, a, again)
+	started, _ := e.counts()
+	require.Equal(t, 1, started)
+
+	code, b := queryClient(t, s, "b")
+	require.Equal(t, http.StatusOK, code)
+	require.NotEqual(t, a, b)
+	started, _ = e.counts()
+	require.Equal(t, 2, started)
+}
+
+func TestListenSessionsIdle(t *testing.T) {
+	t.Parallel()
+
+	e := &fakeEngine{}
+	s := newTestSessions(t, e, nil, 50*time.Millisecond, 0)
+
+	code, _ := queryClient(t, s, "a")
+	require.Equal(t, http.StatusOK, code)
+
+	require.Eventually(t, func() bool {
+		_, stopped := e.counts()
+		return stopped == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the next request starts a new session
+	code, _ = queryClient(t, s, "a")
+	require.Equal(t, http.StatusOK, code)
+	started, _ := e.counts()
+	require.Equal(t, 2, started)
+}
+
+func TestListenSessionsAnonymous(t *testing.T) {
+	t.Parallel()
+
+	e := &fakeEngine{}
+	s := newTestSessions(t, e, nil, 0, 0)
+
+	// clients without a token would have to share a session
+	code, _ := queryClient(t, s, "")
+	require.Equal(t, http.StatusUnauthorized, code)
+	started, _ := e.counts()
+	require.Zero(t, started)
+}
+
+func TestListenSessionsUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	e := &fakeEngine{}
+	s := newTestSessions(t, e, map[string]router.Scope{
+		"known": {Fields: []string{"Query.client"}},
+	}, 0, 0)
+
+	code, _ := queryClient(t, s, "unknown")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = queryClient(t, s, "")
+	require.Equal(t, http.StatusUnauthorized, code)
+	started, _ := e.counts()
+	require.Zero(t, started)
+
+	code, _ = queryClient(t, s, "known")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestListenSessionsMax(t *testing.T) {
+	t.Parallel()
+
+	e := &fakeEngine{}
+	s := newTestSessions(t, e, nil, 0, 1)
+
+	code, _ := queryClient(t, s, "a")
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = queryClient(t, s, "b")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	started, _ := e.counts()
+	require.Equal(t, 1, started)
+
+	// existing sessions are still served
+	code, _ = queryClient(t, s, "a")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestListenSessionsStartError(t *testing.T) {
+	t.Parallel()
+
+	e := &fakeEngine{err: errors.New("no engine")}
+	s := newTestSessions(t, e, nil, 0, 1)
+
+	code, _ := queryClient(t, s, "a")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	// failed sessions don't count towards the limit
+	require.Eventually(t, func() bool {
+		s.l.Lock()
+		defer s.l.Unlock()
+		return len(s.sessions) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,89 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionListenerUnix(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.sock")
+	l, err := sessionListener("unix://" + path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	require.Equal(t, path, l.Addr().String())
+
+	// the private directory it was created in is cleaned up
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// a live listener's socket is left alone
+	_, err = sessionListener("unix://" + path)
+	require.ErrorContains(t, err, "in use")
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+
+	require.NoError(t, l.Close())
+	_, err = os.Lstat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSessionListenerStaleSocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "session.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	// as if the listener didn't shut down cleanly
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	l, err := sessionListener("unix://" + path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestSessionListenerNotASocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "session.sock")
+	require.NoError(t, os.WriteFile(path, []byte("keep me"), 0o600))
+
+	_, err := sessionListener("unix://" + path)
+	require.Error(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "keep me", string(content))
+}
+
+func TestSessionListenerTCP(t *testing.T) {
+	t.Parallel()
+
+	l, err := sessionListener("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	require.Equal(t, "tcp", l.Addr().Network())
+}
@@ -0,0 +1,67 @@
+//go:build unix
+// +build unix
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// listenUnix listens on a unix socket only accessible to the current user.
+// The socket is created in a private directory and chmod-ed there before
+// it's linked into place, so that nobody else can connect in between, without
+// changing the umask of the whole process.
+func listenUnix(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".dagger-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// the socket is unlinked at its final path instead, see unixListener
+	l.SetUnlinkOnClose(false)
+
+	if err := os.Chmod(tmpPath, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	// unlike a rename, linking fails rather than replacing anything already
+	// at the path
+	if err := os.Link(tmpPath, path); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("listen unix %s: %w", path, err)
+	}
+
+	return &unixListener{UnixListener: l, path: path}, nil
+}
+
+// unixListener removes the socket from its path once closed. It's closed
+// both by the HTTP server and by the listen command, so only the first close
+// removes it, in case another listener has taken the path since.
+type unixListener struct {
+	*net.UnixListener
+	path   string
+	remove sync.Once
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.remove.Do(func() {
+		os.Remove(l.path)
+	})
+	return err
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
@@ -38,6 +38,10 @@ func init() {
 		versionCmd,
 		queryCmd,
 		runCmd,
+		watchCmd,
+		cacheCmd,
+		engineCmd,
+		schemaCmd,
 		sessionCmd(),
 	)
 }
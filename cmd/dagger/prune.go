@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/router"
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+var engineCmd = &cobra.Command{
+	Use:   "engine",
+	Short: "Manage the Dagger engine",
+}
+
+var (
+	pruneFilters      []string
+	pruneKeepDuration time.Duration
+	pruneKeepStorage  string
+	pruneAll          bool
+)
+
+var enginePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete records from the engine's build cache",
+	Long: `Delete records from the engine's build cache, the same way the engine's garbage collection does, and list the deleted records.
+
+Records which are in use are never deleted.`,
+	Example: `  Delete every cache volume which wasn't used in the last week:
+    dagger engine prune --filter type==exec.cachemount --keep-duration 168h
+
+  Delete uploaded host directories until the build cache is under 10GB:
+    dagger engine prune --filter type==source.local --keep-storage 10GB
+
+  Delete everything which isn't in use:
+    dagger engine prune --all`,
+	Args: cobra.NoArgs,
+	RunE: EnginePrune,
+}
+
+func init() {
+	enginePruneCmd.Flags().StringArrayVar(&pruneFilters, "filter", nil, "only delete records matching a filter, e.g. type==exec.cachemount (can be repeated, matching any of them)")
+	enginePruneCmd.Flags().DurationVar(&pruneKeepDuration, "keep-duration", 0, "keep records used more recently than this")
+	enginePruneCmd.Flags().StringVar(&pruneKeepStorage, "keep-storage", "", "stop deleting once the build cache is under this size, e.g. 10GB")
+	enginePruneCmd.Flags().BoolVar(&pruneAll, "all", false, "also delete internal records")
+
+	engineCmd.AddCommand(enginePruneCmd)
+}
+
+type engineCacheRecord struct {
+	Description string
+	RecordType  string
+	Size        int64
+}
+
+func EnginePrune(cmd *cobra.Command, args []string) error {
+	vars := map[string]any{
+		"filters":      pruneFilters,
+		"keepDuration": int(pruneKeepDuration / time.Second),
+		"all":          pruneAll,
+	}
+	if pruneKeepStorage != "" {
+		keepBytes, err := units.RAMInBytes(pruneKeepStorage)
+		if err != nil {
+			return fmt.Errorf("invalid --keep-storage: %w", err)
+		}
+		vars["keepBytes"] = keepBytes
+	}
+
+	var res struct {
+		PruneEngineCache []engineCacheRecord
+	}
+	err := withEngineAndTUI(cmd.Context(), engine.Config{}, func(ctx context.Context, r *router.Router) error {
+		_, err := r.Do(ctx, `query Prune($filters: [String!], $keepDuration: Int, $keepBytes: Int, $all: Boolean) {
+			pruneEngineCache(filters: $filters, keepDuration: $keepDuration, keepBytes: $keepBytes, all: $all) {
+				description
+				recordType
+				size
+			}
+		}`, "Prune", vars, &res)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tSIZE\tDESCRIPTION")
+	var total int64
+	for _, rec := range res.PruneEngineCache {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", rec.RecordType, units.HumanSize(float64(rec.Size)), rec.Description)
+		total += rec.Size
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Total: %s\n", units.HumanSize(float64(total)))
+	return nil
+}
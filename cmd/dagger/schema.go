@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dagger/dagger/codegen/introspection"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/router"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspect the schema of the Dagger API",
+}
+
+var (
+	schemaExportFormat string
+	schemaExportOutput string
+)
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the schema of the Dagger API",
+	Long: `Export the schema of the Dagger API, either as the JSON result of the introspection query or in GraphQL SDL.
+
+The exported schema can be used to generate clients without an engine, e.g. with client-gen --schema.`,
+	Example: `  Export the result of the introspection query:
+    dagger schema export -o schema.json
+
+  Export the schema in SDL:
+    dagger schema export -o schema.graphql`,
+	Args: cobra.NoArgs,
+	RunE: SchemaExport,
+}
+
+func init() {
+	schemaExportCmd.Flags().StringVar(&schemaExportFormat, "format", "", "format to export in: json or graphql (defaults to the extension of the output file, or json)")
+	schemaExportCmd.Flags().StringVarP(&schemaExportOutput, "output", "o", "", "file to export to (defaults to stdout)")
+
+	schemaCmd.AddCommand(schemaExportCmd)
+}
+
+func SchemaExport(cmd *cobra.Command, args []string) error {
+	format := schemaExportFormat
+	if format == "" {
+		switch filepath.Ext(schemaExportOutput) {
+		case ".graphql", ".graphqls":
+			format = "graphql"
+		default:
+			format = "json"
+		}
+	}
+	if format != "json" && format != "graphql" {
+		return fmt.Errorf("unknown format %q: use json or graphql", format)
+	}
+
+	var exported []byte
+	err := withEngineAndTUI(cmd.Context(), engine.Config{}, func(ctx context.Context, r *router.Router) error {
+		if format == "graphql" {
+			exported = []byte(r.MergedSchemas())
+			return nil
+		}
+
+		result, err := r.Do(ctx, introspection.Query, "", nil, nil)
+		if err != nil {
+			return err
+		}
+		exported, err = json.MarshalIndent(result.Data, "", "  ")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if schemaExportOutput == "" || schemaExportOutput == "-" {
+		_, err := os.Stdout.Write(exported)
+		return err
+	}
+	return os.WriteFile(schemaExportOutput, exported, 0o600)
+}
@@ -21,7 +21,10 @@ import (
 	"github.com/vito/progrock/console"
 )
 
-var sessionLabels pipeline.Labels
+var (
+	sessionLabels pipeline.Labels
+	sessionListen string
+)
 
 func sessionCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -32,11 +35,13 @@ func sessionCmd() *cobra.Command {
 		SilenceUsage: true,
 	}
 	cmd.Flags().Var(&sessionLabels, "label", "label that identifies the source of this session (e.g, --label 'dagger.io/sdk.name:python' --label 'dagger.io/sdk.version:0.5.2' --label 'dagger.io/sdk.async:true')")
+	cmd.Flags().StringVar(&sessionListen, "listen", "127.0.0.1:0", "Listen on network address ADDR, or on a unix socket with unix:///path/to/sock")
 	return cmd
 }
 
 type connectParams struct {
 	Port         int    `json:"port"`
+	SocketPath   string `json:"socket_path,omitempty"`
 	SessionToken string `json:"session_token"`
 }
 
@@ -65,7 +70,7 @@ func EngineSession(cmd *cobra.Command, args []string) error {
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
 
-	l, err := net.Listen("tcp", "127.0.0.1:0")
+	l, err := sessionListener(sessionListen)
 	if err != nil {
 		return err
 	}
@@ -82,7 +87,15 @@ func EngineSession(cmd *cobra.Command, args []string) error {
 		l.Close()
 	}()
 
-	port := l.Addr().(*net.TCPAddr).Port
+	params := connectParams{
+		SessionToken: sessionToken.String(),
+	}
+	switch addr := l.Addr().(type) {
+	case *net.TCPAddr:
+		params.Port = addr.Port
+	case *net.UnixAddr:
+		params.SocketPath = addr.Name
+	}
 
 	return engine.Start(context.Background(), startOpts, func(ctx context.Context, r *router.Router) error {
 		srv := http.Server{
@@ -90,10 +103,7 @@ func EngineSession(cmd *cobra.Command, args []string) error {
 			ReadHeaderTimeout: 30 * time.Second,
 		}
 
-		paramBytes, err := json.Marshal(connectParams{
-			Port:         port,
-			SessionToken: sessionToken.String(),
-		})
+		paramBytes, err := json.Marshal(params)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/internal/tui"
+	"github.com/dagger/dagger/router"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/vito/progrock"
+)
+
+var watchCmd = &cobra.Command{
+	Use:                   "watch [command]",
+	Aliases:               []string{"w"},
+	DisableFlagsInUseLine: true,
+	Long: `Runs the specified command in a Dagger session, and runs it again whenever a watched host directory changes
+
+Host directories are watched when loaded with watch enabled, e.g. host.directory(path: ".", watch: true).
+The session is kept alive between runs, so only changed files are uploaded again.
+
+If a change is observed while the command is still running, the command is interrupted and started again.
+
+DAGGER_SESSION_PORT and DAGGER_SESSION_TOKEN will be convieniently injected automatically.`,
+	Short: "Runs a command in a Dagger session whenever host directories change",
+	Example: `  Re-run a Dagger pipeline written in Go on every change:
+    dagger watch go run main.go
+
+  Wait a little longer for changes to settle before re-running:
+    dagger watch --debounce 2s -- node index.mjs`,
+	Run:          Watch,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+}
+
+var watchDebounce time.Duration
+
+func init() {
+	// don't require -- to disambiguate subcommand flags
+	watchCmd.Flags().SetInterspersed(false)
+
+	watchCmd.Flags().DurationVar(
+		&waitDelay,
+		"cleanup-timeout",
+		10*time.Second,
+		"max duration to wait between SIGTERM and SIGKILL on interrupt",
+	)
+
+	watchCmd.Flags().DurationVar(
+		&watchDebounce,
+		"debounce",
+		200*time.Millisecond,
+		"how long to wait for changes to settle before running the command again",
+	)
+}
+
+func Watch(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	err := watch(ctx, args)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "watch canceled")
+			os.Exit(2)
+			return
+		}
+
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+		return
+	}
+}
+
+func watch(ctx context.Context, args []string) error {
+	u, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("generate uuid: %w", err)
+	}
+
+	sessionToken := u.String()
+
+	// buffer a single change; any more are redundant until the next run starts
+	changes := make(chan struct{}, 1)
+
+	return withEngineAndTUI(ctx, engine.Config{
+		SessionToken: sessionToken,
+		HostChangeCallback: func(string) {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		},
+	}, func(ctx context.Context, api *router.Router) error {
+		sessionL, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return fmt.Errorf("session listen: %w", err)
+		}
+		defer sessionL.Close()
+
+		sessionPort := fmt.Sprintf("%d", sessionL.Addr().(*net.TCPAddr).Port)
+		os.Setenv("DAGGER_SESSION_PORT", sessionPort)
+		os.Setenv("DAGGER_SESSION_TOKEN", sessionToken)
+
+		go http.Serve(sessionL, api) // nolint:gosec
+
+		for runs := 1; ; runs++ {
+			runCtx, cancel := context.WithCancel(ctx)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				// errors are shown by the TUI; keep watching regardless
+				_ = watchRun(runCtx, args, runs)
+			}()
+
+			select {
+			case <-ctx.Done():
+				cancel()
+				<-done
+				return ctx.Err()
+			case <-changes:
+			}
+
+			// wait for the changes to settle, e.g. while a branch is checked out
+			if err := debounce(ctx, changes, watchDebounce); err != nil {
+				cancel()
+				<-done
+				return err
+			}
+
+			// interrupt the command if it's still running
+			cancel()
+			<-done
+		}
+	})
+}
+
+// watchRun runs the command once, labeling its output with the number of the
+// run.
+func watchRun(ctx context.Context, args []string, runs int) error {
+	subCmd := exec.CommandContext(ctx, args[0], args[1:]...) // #nosec
+
+	// NB: go run lets its child process roam free when you interrupt it, so
+	// make sure they all get signalled. (you don't normally notice this in a
+	// shell because Ctrl+C sends to the process group.)
+	ensureChildProcessesAreKilled(subCmd)
+
+	if silent {
+		subCmd.Stdout = os.Stdout
+		subCmd.Stderr = os.Stderr
+		return subCmd.Run()
+	}
+
+	rec := progrock.RecorderFromContext(ctx)
+
+	cmdline := strings.Join(subCmd.Args, " ")
+	cmdVtx := rec.Vertex(tui.RootVertex, fmt.Sprintf("%s (run %d)", cmdline, runs))
+
+	if stdoutIsTTY {
+		subCmd.Stdout = cmdVtx.Stdout()
+	} else {
+		subCmd.Stdout = os.Stdout
+	}
+
+	if stderrIsTTY {
+		subCmd.Stderr = cmdVtx.Stderr()
+	} else {
+		subCmd.Stderr = os.Stderr
+	}
+
+	cmdErr := subCmd.Run()
+	cmdVtx.Done(cmdErr)
+	return cmdErr
+}
+
+// debounce waits until no changes have been observed for the given duration.
+func debounce(ctx context.Context, changes <-chan struct{}, quiet time.Duration) error {
+	timer := time.NewTimer(quiet)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changes:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(quiet)
+		case <-timer.C:
+			return nil
+		}
+	}
+}
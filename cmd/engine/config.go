@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/cmd/buildkitd/config"
+	"github.com/pelletier/go-toml"
 )
 
 // engineDefaultStateDir is the directory that we map to a volume by default.
@@ -44,3 +49,60 @@ func setNetworkDefaults(cfg *config.NetworkConfig, cniConfigPath string) {
 		cfg.CNIPoolSize = 16
 	}
 }
+
+// gcClassConfig is the retention of one class of cache records.
+type gcClassConfig struct {
+	// KeepDuration is how long records of the class are kept after they were
+	// last used.
+	KeepDuration config.Duration `toml:"keepDuration"`
+	// KeepBytes is how much space records of the class may take up.
+	KeepBytes config.DiskSpace `toml:"keepBytes"`
+}
+
+// gcClassesConfig is the retention of each class of cache records, which is
+// configured in the [gc] section of the engine config, next to the buildkitd
+// config. Records of a configured class are only garbage collected by the
+// class's own policy, rather than the policies in the buildkitd config.
+type gcClassesConfig struct {
+	// CacheMount is the retention of cache volumes, i.e. withMountedCache.
+	CacheMount *gcClassConfig `toml:"cachemount"`
+	// Source is the retention of host directories uploaded to the engine.
+	Source *gcClassConfig `toml:"source"`
+	// Exec is the retention of exec results, along with the other regular
+	// snapshots like pulled image layers.
+	Exec *gcClassConfig `toml:"exec"`
+}
+
+// recordTypes returns the configured classes by the type of cache record they
+// apply to.
+func (cfg gcClassesConfig) recordTypes() map[client.UsageRecordType]*gcClassConfig {
+	types := map[client.UsageRecordType]*gcClassConfig{}
+	if cfg.CacheMount != nil {
+		types[client.UsageRecordTypeCacheMount] = cfg.CacheMount
+	}
+	if cfg.Source != nil {
+		types[client.UsageRecordTypeLocalSource] = cfg.Source
+	}
+	if cfg.Exec != nil {
+		types[client.UsageRecordTypeRegular] = cfg.Exec
+	}
+	return types
+}
+
+// loadGCClasses loads the [gc] section of the engine config file.
+func loadGCClasses(path string) (gcClassesConfig, error) {
+	var cfg struct {
+		GC gcClassesConfig `toml:"gc"`
+	}
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return gcClassesConfig{}, nil
+		}
+		return gcClassesConfig{}, fmt.Errorf("failed to load gc config from %s: %w", path, err)
+	}
+	if err := tree.Unmarshal(&cfg); err != nil {
+		return gcClassesConfig{}, fmt.Errorf("failed to parse gc config from %s: %w", path, err)
+	}
+	return cfg.GC, nil
+}
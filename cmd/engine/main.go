@@ -97,6 +97,7 @@ var propagators = propagation.NewCompositeTextMapPropagator(propagation.TraceCon
 
 type workerInitializerOpt struct {
 	config         *config.Config
+	gcClasses      gcClassesConfig
 	sessionManager *session.Manager
 	traceSocket    string
 }
@@ -723,8 +724,14 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*co
 		}
 	}
 
+	gcClasses, err := loadGCClasses(c.GlobalString("config"))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	wc, err := newWorkerController(c, workerInitializerOpt{
 		config:         cfg,
+		gcClasses:      gcClasses,
 		sessionManager: sessionManager,
 		traceSocket:    traceSocket,
 	})
@@ -752,6 +759,13 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*co
 
 	cacheServiceURL := os.Getenv("_EXPERIMENTAL_DAGGER_CACHESERVICE_URL")
 	cacheServiceToken := os.Getenv("_EXPERIMENTAL_DAGGER_CACHESERVICE_TOKEN")
+	var cacheBackend *cache.BackendConfig
+	if v := os.Getenv("_EXPERIMENTAL_DAGGER_CACHE_BACKEND"); v != "" {
+		cacheBackend, err = cache.ParseBackendConfig(v)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	cacheManager, err := cache.NewManager(ctx, cache.ManagerConfig{
 		KeyStore:     cacheStorage,
 		ResultStore:  worker.NewCacheResultStorage(wc),
@@ -760,6 +774,7 @@ func newController(ctx context.Context, c *cli.Context, cfg *config.Config) (*co
 		ServiceURL:   cacheServiceURL,
 		Token:        cacheServiceToken,
 		EngineID:     w.Labels()[engine.EngineNameLabel],
+		Backend:      cacheBackend,
 	})
 	if err != nil {
 		return nil, nil, err
@@ -877,17 +892,41 @@ func parsePlatforms(platformsStr []string) ([]ocispecs.Platform, error) {
 	return out, nil
 }
 
-func getGCPolicy(cfg config.GCConfig, root string) []client.PruneInfo {
+func getGCPolicy(cfg config.GCConfig, classes gcClassesConfig, root string) []client.PruneInfo {
 	if cfg.GC != nil && !*cfg.GC {
 		return nil
 	}
 	if len(cfg.GCPolicy) == 0 {
 		cfg.GCPolicy = config.DefaultGCPolicy(cfg.GCKeepStorage)
 	}
-	out := make([]client.PruneInfo, 0, len(cfg.GCPolicy))
+
+	// each configured class gets its own policy, and is excluded from the
+	// others so that they don't prune it sooner than configured
+	classTypes := classes.recordTypes()
+	recordTypes := make([]string, 0, len(classTypes))
+	for recordType := range classTypes {
+		recordTypes = append(recordTypes, string(recordType))
+	}
+	sort.Strings(recordTypes)
+
+	out := make([]client.PruneInfo, 0, len(recordTypes)+len(cfg.GCPolicy))
+	var exclusions []string
+	for _, recordType := range recordTypes {
+		class := classTypes[client.UsageRecordType(recordType)]
+		out = append(out, client.PruneInfo{
+			Filter:       []string{"type==" + recordType},
+			KeepBytes:    class.KeepBytes.AsBytes(root),
+			KeepDuration: class.KeepDuration.Duration,
+		})
+		exclusions = append(exclusions, "type!="+recordType)
+	}
 	for _, rule := range cfg.GCPolicy {
+		filters := rule.Filters
+		if len(exclusions) > 0 {
+			filters = excludeFromFilters(rule.Filters, exclusions)
+		}
 		out = append(out, client.PruneInfo{
-			Filter:       rule.Filters,
+			Filter:       filters,
 			All:          rule.All,
 			KeepBytes:    rule.KeepBytes.AsBytes(root),
 			KeepDuration: rule.KeepDuration.Duration,
@@ -896,6 +935,20 @@ func getGCPolicy(cfg config.GCConfig, root string) []client.PruneInfo {
 	return out
 }
 
+// excludeFromFilters adds the exclusions to each of the filters. The
+// conditions within a filter must all match, while a record only has to match
+// one of the filters, so they're added to every filter.
+func excludeFromFilters(filters []string, exclusions []string) []string {
+	if len(filters) == 0 {
+		return []string{strings.Join(exclusions, ",")}
+	}
+	out := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		out = append(out, strings.Join(append([]string{filter}, exclusions...), ","))
+	}
+	return out
+}
+
 func getBuildkitVersion() client.BuildkitVersion {
 	return client.BuildkitVersion{
 		Package:  version.Package,
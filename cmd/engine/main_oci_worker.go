@@ -346,7 +346,7 @@ func ociWorkerInitializer(c *cli.Context, common workerInitializerOpt) ([]worker
 	if err != nil {
 		return nil, err
 	}
-	opt.GCPolicy = getGCPolicy(cfg.GCConfig, common.config.Root)
+	opt.GCPolicy = getGCPolicy(cfg.GCConfig, common.gcClasses, common.config.Root)
 	opt.BuildkitVersion = getBuildkitVersion()
 	opt.RegistryHosts = hosts
 
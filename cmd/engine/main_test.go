@@ -2,9 +2,12 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/cmd/buildkitd/config"
 	"github.com/moby/buildkit/session"
 	"github.com/stretchr/testify/require"
@@ -89,3 +92,67 @@ func TestEngineNameLabel(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestGCPolicyClasses(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "engine.toml")
+	err := os.WriteFile(configPath, []byte(`
+debug = true
+
+[worker.oci]
+  gcpolicy = [
+    { keepBytes = "1GB", filters = ["type==source.local"] },
+    { all = true, keepBytes = "10GB" },
+  ]
+
+[gc.cachemount]
+  keepDuration = "720h"
+  keepBytes = "20GB"
+
+[gc.exec]
+  keepDuration = "48h"
+`), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := config.LoadFile(configPath)
+	require.NoError(t, err)
+	classes, err := loadGCClasses(configPath)
+	require.NoError(t, err)
+	require.Nil(t, classes.Source)
+
+	require.Equal(t, []client.PruneInfo{
+		{
+			Filter:       []string{"type==exec.cachemount"},
+			KeepDuration: 720 * time.Hour,
+			KeepBytes:    20 << 30,
+		},
+		{
+			Filter:       []string{"type==regular"},
+			KeepDuration: 48 * time.Hour,
+		},
+		{
+			Filter:    []string{"type==source.local,type!=exec.cachemount,type!=regular"},
+			KeepBytes: 1 << 30,
+		},
+		{
+			Filter:    []string{"type!=exec.cachemount,type!=regular"},
+			All:       true,
+			KeepBytes: 10 << 30,
+		},
+	}, getGCPolicy(cfg.Workers.OCI.GCConfig, classes, t.TempDir()))
+
+	// without any classes the buildkitd policy is used as is
+	require.Equal(t, []client.PruneInfo{
+		{
+			Filter:    []string{"type==source.local"},
+			KeepBytes: 1 << 30,
+		},
+		{
+			All:       true,
+			KeepBytes: 10 << 30,
+		},
+	}, getGCPolicy(cfg.Workers.OCI.GCConfig, gcClassesConfig{}, t.TempDir()))
+
+	classes, err = loadGCClasses(filepath.Join(t.TempDir(), "missing.toml"))
+	require.NoError(t, err)
+	require.Equal(t, gcClassesConfig{}, classes)
+}
@@ -2,11 +2,16 @@ package generator
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/dagger/dagger/codegen/introspection"
 	"github.com/dagger/dagger/router"
+	"github.com/dagger/graphql/language/ast"
+	"github.com/dagger/graphql/language/parser"
 )
 
 var ErrUnknownSDKLang = errors.New("unknown sdk language")
@@ -24,6 +29,9 @@ type Config struct {
 	// Package is the target package that is generated.
 	// Not used for the SDKLangNodeJS.
 	Package string
+	// Sync generates a client for synchronous code.
+	// Only used for the SDKLangPython.
+	Sync bool
 }
 
 type Generator interface {
@@ -46,7 +54,81 @@ func Introspect(ctx context.Context, r *router.Router) (*introspection.Schema, e
 	if err != nil {
 		return nil, fmt.Errorf("error querying the API: %w", err)
 	}
-	return response.Schema, nil
+	schema := response.Schema
+	removeSubscriptions(schema)
+	return schema, nil
+}
+
+// IntrospectFile gets the Dagger Schema from a file, as exported by `dagger
+// schema export`: the schema in SDL format if the file's extension is
+// .graphql or .graphqls, or else the JSON result of the introspection query,
+// optionally wrapped in the response's "data".
+//
+// Unlike Introspect, it doesn't need an engine.
+func IntrospectFile(ctx context.Context, path string) (*introspection.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(path) {
+	case ".graphql", ".graphqls":
+		resolvers, err := scalarResolvers(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		r := router.New("", nil, router.Limits{}, nil)
+		if err := r.Add(router.StaticSchema(router.StaticSchemaParams{
+			Name:      filepath.Base(path),
+			Schema:    string(data),
+			Resolvers: resolvers,
+		})); err != nil {
+			return nil, fmt.Errorf("load %s: %w", path, err)
+		}
+		return Introspect(ctx, r)
+	}
+
+	var response struct {
+		introspection.Response
+		Data *introspection.Response `json:"data"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	schema := response.Schema
+	if response.Data != nil {
+		schema = response.Data.Schema
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("parse %s: no __schema found", path)
+	}
+	removeSubscriptions(schema)
+	return schema, nil
+}
+
+// scalarResolvers returns resolvers for the custom scalars of the schema,
+// which it can't be compiled without. Nothing is resolved, so they only pass
+// values through.
+func scalarResolvers(sdl []byte) (router.Resolvers, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: string(sdl)})
+	if err != nil {
+		return nil, err
+	}
+
+	passthrough := func(v any) any { return v }
+	resolvers := router.Resolvers{}
+	for _, def := range doc.Definitions {
+		if scalar, ok := def.(*ast.ScalarDefinition); ok {
+			resolvers[scalar.Name.Value] = router.ScalarResolver{
+				Serialize:  passthrough,
+				ParseValue: passthrough,
+				ParseLiteral: func(valueAST ast.Value) any {
+					return valueAST.GetValue()
+				},
+			}
+		}
+	}
+	return resolvers, nil
 }
 
 // IntrospectAndGenerate generate the Dagger API with the router r.
@@ -58,3 +140,58 @@ func IntrospectAndGenerate(ctx context.Context, r *router.Router, generator Gene
 
 	return generator.Generate(ctx, schema)
 }
+
+// removeSubscriptions removes the Subscription type from the schema, along
+// with the types only it refers to. Subscriptions are only served over
+// WebSockets, which the SDKs don't use.
+func removeSubscriptions(schema *introspection.Schema) {
+	subscription := schema.Subscription()
+	if subscription == nil {
+		return
+	}
+
+	used := map[string]bool{}
+	for _, root := range []*introspection.Type{schema.Query(), schema.Mutation()} {
+		markUsedTypes(schema, root, used)
+	}
+
+	subscribed := map[string]bool{}
+	markUsedTypes(schema, subscription, subscribed)
+
+	types := introspection.Types{}
+	for _, t := range schema.Types {
+		if subscribed[t.Name] && !used[t.Name] {
+			continue
+		}
+		types = append(types, t)
+	}
+	schema.Types = types
+	schema.SubscriptionType.Name = ""
+}
+
+// markUsedTypes marks the types referred to by t's fields and their
+// arguments, recursively.
+func markUsedTypes(schema *introspection.Schema, t *introspection.Type, used map[string]bool) {
+	if t == nil || used[t.Name] {
+		return
+	}
+	used[t.Name] = true
+
+	var mark func(ref *introspection.TypeRef)
+	mark = func(ref *introspection.TypeRef) {
+		for ; ref != nil; ref = ref.OfType {
+			if ref.Name != "" {
+				markUsedTypes(schema, schema.Types.Get(ref.Name), used)
+			}
+		}
+	}
+	for _, f := range t.Fields {
+		mark(f.TypeRef)
+		for _, arg := range f.Args {
+			mark(arg.TypeRef)
+		}
+	}
+	for _, f := range t.InputFields {
+		mark(f.TypeRef)
+	}
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dagger/dagger/codegen/introspection"
+	"github.com/dagger/dagger/router"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectFile(t *testing.T) {
+	ctx := context.Background()
+
+	schema, err := IntrospectFile(ctx, "testdata/schema.graphql")
+	require.NoError(t, err)
+
+	require.Equal(t, "SCALAR", string(schema.Types.Get("ContainerID").Kind))
+	container := schema.Types.Get("Container")
+	require.NotNil(t, container)
+	for _, f := range container.Fields {
+		if f.Name == "exec" {
+			require.True(t, f.IsDeprecated)
+			require.Equal(t, "Replaced by `withExec`.", f.DeprecationReason)
+		}
+	}
+
+	// subscriptions are removed, as with Introspect
+	require.Nil(t, schema.Subscription())
+	require.Nil(t, schema.Types.Get("Log"))
+
+	// the JSON result of the introspection query gives the same schema,
+	// whether it's wrapped in the response's data or not
+	sdl, err := os.ReadFile("testdata/schema.graphql")
+	require.NoError(t, err)
+	resolvers, err := scalarResolvers(sdl)
+	require.NoError(t, err)
+	r := router.New("", nil, router.Limits{}, nil)
+	require.NoError(t, r.Add(router.StaticSchema(router.StaticSchemaParams{
+		Name:      "test",
+		Schema:    string(sdl),
+		Resolvers: resolvers,
+	})))
+	result, err := r.Do(ctx, introspection.Query, "", nil, nil)
+	require.NoError(t, err)
+
+	for name, data := range map[string]any{
+		"schema.json":   result.Data,
+		"response.json": map[string]any{"data": result.Data},
+	} {
+		payload, err := json.Marshal(data)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(path, payload, 0o600))
+
+		fromJSON, err := IntrospectFile(ctx, path)
+		require.NoError(t, err)
+		// types are listed in no particular order
+		require.ElementsMatch(t, schema.Types, fromJSON.Types)
+		require.Equal(t, schema.QueryType, fromJSON.QueryType)
+	}
+}
@@ -122,6 +122,11 @@ func getArrayField(f *introspection.Field) []*introspection.Field {
 	// Only include scalar fields for now
 	// TODO: include subtype too
 	for _, typeField := range schemaType.Fields {
+		// skip fields like sync which are run for their side effects
+		if commonFunc.ConvertID(*typeField) {
+			continue
+		}
+
 		if typeField.TypeRef.IsScalar() {
 			fields = append(fields, typeField)
 		}
@@ -0,0 +1,97 @@
+package pythongenerator
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/dagger/dagger/codegen/generator"
+	"github.com/dagger/dagger/codegen/generator/python/templates"
+	"github.com/dagger/dagger/codegen/introspection"
+)
+
+type PythonGenerator struct {
+	Config generator.Config
+}
+
+// Generate will generate the Python SDK code and might modify the schema to
+// reorder types in a alphanumeric fashion.
+//
+// Types are defined in the order custom scalars, enums, inputs and objects
+// are, so that only objects refer to types which aren't defined yet.
+func (g *PythonGenerator) Generate(_ context.Context, schema *introspection.Schema) ([]byte, error) {
+	generator.SetSchema(schema)
+
+	sort.SliceStable(schema.Types, func(i, j int) bool {
+		return schema.Types[i].Name < schema.Types[j].Name
+	})
+	for _, v := range schema.Types {
+		sort.SliceStable(v.Fields, func(i, j int) bool {
+			return v.Fields[i].Name < v.Fields[j].Name
+		})
+		sort.SliceStable(v.InputFields, func(i, j int) bool {
+			return v.InputFields[i].Name < v.InputFields[j].Name
+		})
+	}
+
+	tmpl := templates.New()
+
+	var header bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&header, "header", nil); err != nil {
+		return nil, err
+	}
+	render := []string{header.String()}
+
+	var defined []string
+	define := func(name string, data any) error {
+		var out bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&out, name, data); err != nil {
+			return err
+		}
+		render = append(render, out.String())
+		return nil
+	}
+
+	sequence := []struct {
+		Template string
+		Filter   func(t *introspection.Type) bool
+	}{
+		{Template: "scalar", Filter: templates.IsCustomScalar},
+		{Template: "enum", Filter: func(t *introspection.Type) bool {
+			return t.Kind == introspection.TypeKindEnum
+		}},
+		{Template: "input", Filter: func(t *introspection.Type) bool {
+			return t.Kind == introspection.TypeKindInputObject
+		}},
+		{Template: "object", Filter: func(t *introspection.Type) bool {
+			return t.Kind == introspection.TypeKindObject
+		}},
+	}
+	for _, s := range sequence {
+		for _, t := range schema.Types {
+			// internal GraphQL types
+			if strings.HasPrefix(t.Name, "_") || !s.Filter(t) {
+				continue
+			}
+			data := struct {
+				*introspection.Type
+				Sync bool
+			}{t, g.Config.Sync}
+			if err := define(s.Template, data); err != nil {
+				return nil, err
+			}
+			name := t.Name
+			if name == generator.QueryStructName {
+				name = generator.QueryStructClientName
+			}
+			defined = append(defined, name)
+		}
+	}
+
+	if err := define("all", defined); err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.Join(render, "\n\n\n") + "\n"), nil
+}
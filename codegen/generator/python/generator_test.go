@@ -0,0 +1,59 @@
+package pythongenerator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dagger/dagger/codegen/generator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+
+	generate := func(sync bool) string {
+		schema, err := generator.IntrospectFile(ctx, "../testdata/schema.graphql")
+		require.NoError(t, err)
+		generator.SetSchemaParents(schema)
+
+		g := &PythonGenerator{Config: generator.Config{Lang: generator.SDKLangPython, Sync: sync}}
+		generated, err := g.Generate(ctx, schema)
+		require.NoError(t, err)
+		return string(generated)
+	}
+
+	async := generate(false)
+	require.Contains(t, async, `class ContainerID(Scalar):
+    """A unique container identifier."""`)
+	require.Contains(t, async, `    async def id(self) -> ContainerID:`)
+	require.Contains(t, async, `        return await _ctx.execute(ContainerID)`)
+	// the object isn't defined yet in its own methods
+	require.Contains(t, async, `    def with_exec(self, args: Sequence[str]) -> "Container":`)
+	require.Contains(t, async, `            Arg("args", args),`)
+	require.Contains(t, async, `        warnings.warn(
+            "Method \"exec\" is deprecated: Replaced by \"with_exec\".",
+            DeprecationWarning,
+            stacklevel=4,
+        )`)
+	require.Contains(t, async, `        """.. deprecated::
+            Replaced by :py:meth:`+"`with_exec`"+`.
+        """`)
+	// loading by ID keeps the ID rather than the object it refers to
+	require.Contains(t, async, `class Client(Root):
+
+    @typecheck
+    def container(self, id: Optional[ContainerID] = None) -> Container:
+        """Loads a container from its ID.`)
+	require.Contains(t, async, `__all__ = [
+    "ContainerID",
+    "Container",
+    "Client",
+]
+`)
+	require.NotContains(t, async, "Log")
+
+	sync := generate(true)
+	require.Contains(t, sync, `    def id(self) -> ContainerID:`)
+	require.Contains(t, sync, `        return _ctx.execute_sync(ContainerID)`)
+	require.NotContains(t, sync, "async def")
+}
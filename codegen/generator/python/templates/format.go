@@ -0,0 +1,70 @@
+package templates
+
+import (
+	"github.com/dagger/dagger/codegen/generator"
+)
+
+// pythonScalars maps the GraphQL scalars to their Python types, besides
+// the ones of generator.FormatTypeFuncs.
+var pythonScalars = map[string]string{
+	"ID":       "str",
+	"Date":     "date",
+	"DateTime": "datetime",
+	"Time":     "time",
+	"Decimal":  "Decimal",
+}
+
+// FormatTypeFunc is an implementation of generator.FormatTypeFuncs interface
+// to format GraphQL type into Python.
+type FormatTypeFunc struct{}
+
+func (f *FormatTypeFunc) FormatKindList(representation string) string {
+	return "list[" + representation + "]"
+}
+
+func (f *FormatTypeFunc) FormatKindScalarString(representation string) string {
+	representation += "str"
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindScalarInt(representation string) string {
+	representation += "int"
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindScalarFloat(representation string) string {
+	representation += "float"
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindScalarBoolean(representation string) string {
+	representation += "bool"
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindScalarDefault(representation string, refName string, input bool) string {
+	if alias, ok := generator.CustomScalar[refName]; ok && input {
+		representation += alias
+	} else if scalar, ok := pythonScalars[refName]; ok {
+		representation += scalar
+	} else {
+		representation += refName
+	}
+
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindObject(representation string, refName string) string {
+	representation += formatTypeName(refName)
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindInputObject(representation string, refName string) string {
+	representation += refName
+	return representation
+}
+
+func (f *FormatTypeFunc) FormatKindEnum(representation string, refName string) string {
+	representation += refName
+	return representation
+}
@@ -0,0 +1,522 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/dagger/graphql/language/ast"
+	"github.com/dagger/graphql/language/parser"
+
+	"github.com/dagger/dagger/codegen/generator"
+	"github.com/dagger/dagger/codegen/introspection"
+)
+
+// indentUnit is the indentation of a Python block.
+const indentUnit = "    "
+
+var (
+	commonFunc = generator.NewCommonFunctions(&FormatTypeFunc{})
+	funcMap    = template.FuncMap{
+		"ClassDoc":           classDoc,
+		"ConvertID":          commonFunc.ConvertID,
+		"DeprecationWarning": deprecationWarning,
+		"Docstring":          docstring,
+		"FormatArg":          formatArg,
+		"FormatInputField":   formatInputField,
+		"FormatName":         formatName,
+		"FormatOutputType":   formatOutputType,
+		"FormatTypeName":     formatTypeName,
+		"Indent":             indent,
+		"IsLeaf":             isLeaf,
+		"MethodDoc":          methodDoc,
+		"Signature":          signature,
+		"SortArgs":           sortArgs,
+		"SortEnumFields":     sortEnumFields,
+	}
+)
+
+// pythonKeywords are the reserved words which can't be used as names.
+var pythonKeywords = map[string]struct{}{
+	"False": {}, "None": {}, "True": {}, "and": {}, "as": {}, "assert": {},
+	"async": {}, "await": {}, "break": {}, "class": {}, "continue": {},
+	"def": {}, "del": {}, "elif": {}, "else": {}, "except": {}, "finally": {},
+	"for": {}, "from": {}, "global": {}, "if": {}, "import": {}, "in": {},
+	"is": {}, "lambda": {}, "nonlocal": {}, "not": {}, "or": {}, "pass": {},
+	"raise": {}, "return": {}, "try": {}, "while": {}, "with": {}, "yield": {},
+}
+
+// deprecationRefRe matches the references to other fields in deprecation
+// reasons.
+var deprecationRefRe = regexp.MustCompile("`[a-zA-Z0-9_]+`")
+
+const raisesDoc = `Raises
+------
+ExecuteTimeoutError
+    If the time to execute the query exceeds the configured timeout.
+QueryError
+    If the API returns an error.`
+
+// IsCustomScalar checks if the type is a scalar without a Python
+// equivalent, which is then defined in the generated code.
+func IsCustomScalar(t *introspection.Type) bool {
+	switch introspection.Scalar(t.Name) {
+	case introspection.ScalarString, introspection.ScalarInt, introspection.ScalarFloat, introspection.ScalarBoolean:
+		return false
+	}
+	_, ok := pythonScalars[t.Name]
+	return t.Kind == introspection.TypeKindScalar && !ok
+}
+
+// formatName formats a GraphQL name (e.g. field, arg) into a Python equivalent
+// Example: `withRootfsID` -> `with_rootfs_id`
+func formatName(s string) string {
+	s = snakeCase(titleAcronyms(s))
+	if _, ok := pythonKeywords[s]; ok {
+		s += "_"
+	}
+	return s
+}
+
+// formatTypeName formats a GraphQL type name into the name of its class.
+func formatTypeName(s string) string {
+	if s == generator.QueryStructName {
+		return generator.QueryStructClientName
+	}
+	return s
+}
+
+// titleAcronyms rewrites acronyms, initialisms and abbreviations as words.
+// Example: `withRootfsID` -> `withRootfsId`
+func titleAcronyms(s string) string {
+	isUpper := func(r rune) bool {
+		return (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		end := i
+		for end < len(runes) && isUpper(runes[end]) {
+			end++
+		}
+		// the acronym is followed by a word, which starts with its last
+		// letter, unless it ends the name
+		if end < len(runes) {
+			end--
+		}
+		if end <= i {
+			i++
+			continue
+		}
+		word := true
+		for j := i; j < end; j++ {
+			if unicode.IsLetter(runes[j]) {
+				if !word {
+					runes[j] = unicode.ToLower(runes[j])
+				}
+				word = false
+			} else {
+				word = true
+			}
+		}
+		i = end
+	}
+	return string(runes)
+}
+
+// snakeCase converts a camelCase name into snake_case.
+// Example: `withRootfsId` -> `with_rootfs_id`
+func snakeCase(s string) string {
+	isUpper := func(r rune) bool {
+		return r >= 'A' && r <= 'Z'
+	}
+	isUpperOrDigit := func(r rune) bool {
+		return isUpper(r) || (r >= '0' && r <= '9')
+	}
+
+	runes := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if runes[i] >= 'a' && runes[i] <= 'z' {
+			b.WriteRune(runes[i])
+			if i+1 < len(runes) && isUpper(runes[i+1]) {
+				b.WriteRune('_')
+			}
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isUpperOrDigit(runes[end]) {
+			end++
+		}
+		// split before the last uppercase letter of the run
+		split := -1
+		for j := end - 1; j > i; j-- {
+			if isUpper(runes[j]) {
+				split = j
+				break
+			}
+		}
+		if split < 0 {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		b.WriteString(string(runes[i:split]))
+		b.WriteRune('_')
+		i = split
+	}
+	return strings.ToLower(b.String())
+}
+
+// docstring wraps a string in docstring quotes.
+func docstring(s string) string {
+	if strings.Contains(s, "\n") {
+		s += "\n"
+	}
+	return `"""` + s + `"""`
+}
+
+// classDoc formats a type description into the docstring of its class.
+func classDoc(s string) string {
+	return strings.Join(wrap(docstring(s), lineWidth, "", ""), "\n")
+}
+
+// methodDoc formats the docstring of the method of a field, in the numpydoc
+// style.
+func methodDoc(f introspection.Field) string {
+	var sections []string
+
+	if f.Description != "" {
+		lines := splitLines(f.Description)
+		for i, l := range lines {
+			lines[i] = fill(l)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if f.DeprecationReason != "" {
+		lines := []string{".. deprecated::"}
+		lines = append(lines, wrapIndent(formatDeprecation(f.DeprecationReason, ":py:meth:`", "`"))...)
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if f.Name == "id" {
+		sections = append(sections, "Note\n----\nThis is lazyly evaluated, no operation is actually run.")
+	}
+
+	if argsHaveDescription(f.Args) {
+		lines := []string{"Parameters", "----------"}
+		for _, arg := range sortArgs(f) {
+			lines = append(lines, formatName(arg.Name)+":")
+			for _, l := range strings.Split(arg.Description, "\n") {
+				lines = append(lines, wrapIndent(l)...)
+			}
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if isLeaf(f.TypeRef) {
+		if returnDoc := typeDescription(f.TypeRef); !commonFunc.ConvertID(f) && returnDoc != "" {
+			lines := []string{"Returns", "-------", formatOutputType(f)}
+			lines = append(lines, wrapIndent(returnDoc)...)
+			sections = append(sections, strings.Join(lines, "\n"))
+		}
+		sections = append(sections, raisesDoc)
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+// typeDescription returns the description of the type referred to by r.
+func typeDescription(r *introspection.TypeRef) string {
+	t := generator.GetSchema().Types.Get(namedType(r).Name)
+	if t == nil {
+		return ""
+	}
+	return t.Description
+}
+
+// formatDeprecation formats the deprecation reason of a field, wrapping the
+// names of the fields it refers to.
+// Example: "Replaced by `withExec`." -> `Replaced by "with_exec".`
+func formatDeprecation(s, prefix, suffix string) string {
+	return deprecationRefRe.ReplaceAllStringFunc(s, func(match string) string {
+		return prefix + formatName(strings.Trim(match, "`")) + suffix
+	})
+}
+
+// deprecationWarning formats the message of the warning emitted when calling
+// the method of a deprecated field, escaped in a string literal.
+func deprecationWarning(f introspection.Field) string {
+	msg := fmt.Sprintf("Method %q is deprecated: %s", formatName(f.Name), formatDeprecation(f.DeprecationReason, `"`, `"`))
+	return strings.ReplaceAll(msg, `"`, `\"`)
+}
+
+// namedType unwraps the lists and non-null types around a type reference.
+func namedType(r *introspection.TypeRef) *introspection.TypeRef {
+	for r.OfType != nil {
+		r = r.OfType
+	}
+	return r
+}
+
+// isLeaf checks if the type is a scalar or an enum, or a list of them, which
+// is then executed rather than selected from.
+func isLeaf(r *introspection.TypeRef) bool {
+	kind := namedType(r).Kind
+	return kind == introspection.TypeKindScalar || kind == introspection.TypeKindEnum
+}
+
+// isCustomScalarRef checks if the type is a custom scalar, or a list of them.
+func isCustomScalarRef(r *introspection.TypeRef) bool {
+	named := namedType(r)
+	return IsCustomScalar(&introspection.Type{Kind: named.Kind, Name: named.Name})
+}
+
+// formatType formats a GraphQL type into a Python type hint, optional unless
+// it's non-null.
+// Example: `[String!]` -> `Optional[list[str]]`
+func formatType(r *introspection.TypeRef, input bool) string {
+	if r.Kind == introspection.TypeKindNonNull {
+		return formatNonNullType(r.OfType, input)
+	}
+	return "Optional[" + formatNonNullType(r, input) + "]"
+}
+
+func formatNonNullType(r *introspection.TypeRef, input bool) string {
+	if r.Kind == introspection.TypeKindList {
+		return "list[" + formatType(r.OfType, input) + "]"
+	}
+	if input {
+		return commonFunc.FormatInputType(r)
+	}
+	return commonFunc.FormatOutputType(r)
+}
+
+// formatOutputType formats the type returned by the method of a field.
+//
+// Objects are never optional nor lists, so that the query can always be
+// chained, and the IDs that are converted return their object.
+func formatOutputType(f introspection.Field) string {
+	if commonFunc.ConvertID(f) {
+		return formatTypeName(f.ParentObject.Name)
+	}
+	if isLeaf(f.TypeRef) {
+		return formatType(f.TypeRef, false)
+	}
+	return commonFunc.FormatOutputType(namedType(f.TypeRef))
+}
+
+// formatArgType formats the type of a field argument, which accepts objects
+// in place of their IDs, except for the `id` of the object which the field
+// returns (e.g., `container(id: ContainerID): Container!`).
+func formatArgType(f introspection.Field, arg introspection.InputValue) string {
+	input := true
+	if arg.Name == "id" && isCustomScalarRef(arg.TypeRef) {
+		alias, ok := generator.CustomScalar[namedType(arg.TypeRef).Name]
+		input = !ok || alias != namedType(f.TypeRef).Name
+	}
+	return formatType(arg.TypeRef, input)
+}
+
+// hasDefault checks if an argument may be omitted.
+func hasDefault(arg introspection.InputValue) bool {
+	_, ok := defaultValue(arg)
+	return ok || arg.TypeRef.IsOptional()
+}
+
+// formatDefault formats the default value of an argument into a Python
+// literal, None unless it has one.
+func formatDefault(arg introspection.InputValue) string {
+	if v, ok := defaultValue(arg); ok {
+		return formatValue(v)
+	}
+	return "None"
+}
+
+// defaultValue parses the default value of an argument, if it has one which
+// is valid for its type. Invalid ones, such as the strings introspection
+// returns for some enums, are ignored.
+func defaultValue(arg introspection.InputValue) (ast.Value, bool) {
+	if arg.DefaultValue == nil {
+		return nil, false
+	}
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: fmt.Sprintf("{f(v: %s)}", *arg.DefaultValue),
+	})
+	if err != nil {
+		return nil, false
+	}
+	op := doc.Definitions[0].(*ast.OperationDefinition)
+	v := op.SelectionSet.Selections[0].(*ast.Field).Arguments[0].Value
+	return v, isValidValue(arg.TypeRef, v)
+}
+
+// isValidValue checks if a GraphQL value is valid for the type.
+func isValidValue(r *introspection.TypeRef, v ast.Value) bool {
+	switch r.Kind {
+	case introspection.TypeKindNonNull:
+		return isValidValue(r.OfType, v)
+	case introspection.TypeKindList:
+		list, ok := v.(*ast.ListValue)
+		if !ok {
+			// a single value is coerced into a list
+			return isValidValue(r.OfType, v)
+		}
+		for _, item := range list.Values {
+			if !isValidValue(r.OfType, item) {
+				return false
+			}
+		}
+		return true
+	case introspection.TypeKindEnum:
+		_, ok := v.(*ast.EnumValue)
+		return ok
+	case introspection.TypeKindInputObject:
+		_, ok := v.(*ast.ObjectValue)
+		return ok
+	}
+
+	switch introspection.Scalar(r.Name) {
+	case introspection.ScalarString:
+		_, ok := v.(*ast.StringValue)
+		return ok
+	case introspection.ScalarInt:
+		_, ok := v.(*ast.IntValue)
+		return ok
+	case introspection.ScalarFloat:
+		switch v.(type) {
+		case *ast.IntValue, *ast.FloatValue:
+			return true
+		}
+		return false
+	case introspection.ScalarBoolean:
+		_, ok := v.(*ast.BooleanValue)
+		return ok
+	}
+	return true
+}
+
+// formatValue formats a GraphQL value into a Python literal.
+func formatValue(v ast.Value) string {
+	switch v := v.(type) {
+	case *ast.BooleanValue:
+		if v.Value {
+			return "True"
+		}
+		return "False"
+	case *ast.StringValue:
+		return fmt.Sprintf("%q", v.Value)
+	case *ast.EnumValue:
+		return fmt.Sprintf("%q", v.Value)
+	case *ast.ListValue:
+		values := make([]string, 0, len(v.Values))
+		for _, value := range v.Values {
+			values = append(values, formatValue(value))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case *ast.ObjectValue:
+		fields := make([]string, 0, len(v.Fields))
+		for _, field := range v.Fields {
+			fields = append(fields, fmt.Sprintf("%q: %s", field.Name.Value, formatValue(field.Value)))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return fmt.Sprint(v.GetValue())
+	}
+}
+
+// formatParam formats an argument or an input field as a parameter of a
+// function signature, broadening lists to sequences.
+func formatParam(arg introspection.InputValue, typ string) string {
+	param := formatName(arg.Name) + ": " + strings.ReplaceAll(typ, "list[", "Sequence[")
+	if hasDefault(arg) {
+		param += " = " + formatDefault(arg)
+	}
+	return param
+}
+
+// formatInputField formats a field of an input object.
+func formatInputField(field introspection.InputValue) string {
+	return formatParam(field, formatType(field.TypeRef, true))
+}
+
+// formatArg formats an argument for the query builder.
+func formatArg(arg introspection.InputValue) string {
+	params := []string{fmt.Sprintf("%q", arg.Name), formatName(arg.Name)}
+	if hasDefault(arg) {
+		params = append(params, formatDefault(arg))
+	}
+	return fmt.Sprintf("Arg(%s),", strings.Join(params, ", "))
+}
+
+// signature formats the signature of the method of a field.
+func signature(f introspection.Field, sync bool) string {
+	params := []string{"self"}
+	for _, arg := range sortArgs(f) {
+		params = append(params, formatParam(arg, formatArgType(f, arg)))
+	}
+	joined := strings.Join(params, ", ")
+	// arbitrary heuristic to force a trailing comma in long signatures, so
+	// that they get a line per parameter once formatted
+	if len(joined) > 40 {
+		joined += ","
+	}
+
+	prefix := ""
+	if !sync && isLeaf(f.TypeRef) {
+		prefix = "async "
+	}
+	sig := fmt.Sprintf("%sdef %s(%s) -> %s:", prefix, formatName(f.Name), joined, formatOutputType(f))
+	return quoteUndefined(f.ParentObject, sig)
+}
+
+// quoteUndefined quotes the references to the objects which aren't defined
+// yet when defining parent, including itself, as objects are defined in
+// alphabetical order.
+func quoteUndefined(parent *introspection.Type, s string) string {
+	var undefined []string
+	for _, t := range generator.GetSchema().Types {
+		if t.Kind != introspection.TypeKindObject || strings.HasPrefix(t.Name, "_") || t.Name < parent.Name {
+			continue
+		}
+		undefined = append(undefined, regexp.QuoteMeta(formatTypeName(t.Name)))
+	}
+	if len(undefined) == 0 {
+		return s
+	}
+	re := regexp.MustCompile(`\b(` + strings.Join(undefined, "|") + `)\b`)
+	return re.ReplaceAllString(s, `"$1"`)
+}
+
+// sortArgs returns the arguments of a field, the ones that may be omitted
+// last.
+func sortArgs(f introspection.Field) introspection.InputValues {
+	args := make(introspection.InputValues, len(f.Args))
+	copy(args, f.Args)
+	sort.SliceStable(args, func(i, j int) bool {
+		return !hasDefault(args[i]) && hasDefault(args[j])
+	})
+	return args
+}
+
+func argsHaveDescription(values introspection.InputValues) bool {
+	for _, v := range values {
+		if v.Description != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func sortEnumFields(s []introspection.EnumValue) []introspection.EnumValue {
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Name < s[j].Name
+	})
+	return s
+}
@@ -0,0 +1,139 @@
+package templates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dagger/dagger/codegen/generator"
+	"github.com/dagger/dagger/codegen/introspection"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"withExec":                      "with_exec",
+		"withRootfsID":                  "with_rootfs_id",
+		"experimentalPrivilegedNesting": "experimental_privileged_nesting",
+		"HTTPServer":                    "http_server",
+		"withHTTPProxy":                 "with_http_proxy",
+		"sha256Sum":                     "sha256_sum",
+		"import":                        "import_",
+		"from":                          "from_",
+	} {
+		require.Equal(t, expected, formatName(name), name)
+	}
+}
+
+func TestFormatType(t *testing.T) {
+	nonNull := func(r *introspection.TypeRef) *introspection.TypeRef {
+		return &introspection.TypeRef{Kind: introspection.TypeKindNonNull, OfType: r}
+	}
+	list := func(r *introspection.TypeRef) *introspection.TypeRef {
+		return &introspection.TypeRef{Kind: introspection.TypeKindList, OfType: r}
+	}
+	scalar := func(name string) *introspection.TypeRef {
+		return &introspection.TypeRef{Kind: introspection.TypeKindScalar, Name: name}
+	}
+
+	require.Equal(t, "str", formatType(nonNull(scalar("String")), false))
+	require.Equal(t, "Optional[int]", formatType(scalar("Int"), false))
+	require.Equal(t, "Optional[list[str]]", formatType(list(nonNull(scalar("ID"))), false))
+	require.Equal(t, "list[Optional[bool]]", formatType(nonNull(list(scalar("Boolean"))), false))
+	// IDs are accepted as their objects in inputs
+	require.Equal(t, "ContainerID", formatType(nonNull(scalar("ContainerID")), false))
+	require.Equal(t, "Container", formatType(nonNull(scalar("ContainerID")), true))
+}
+
+func TestWrap(t *testing.T) {
+	require.Equal(t, []string{
+		`    The String type is most often used by GraphQL to represent free-`,
+		`    form human-readable text.`,
+	}, wrapIndent("The String type is most often used by GraphQL to represent free-form human-readable text."))
+
+	// em-dashes break words too
+	require.Equal(t, []string{
+		`Pass flags to the command, such as "--`,
+		`no-cache" to disable the cache.`,
+	}, wrap(`Pass flags to the command, such as "--no-cache" to disable the cache.`, 38, "", ""))
+
+	require.Equal(t, "line one\n\nline two", indent(0, "line one\n\nline two"))
+	require.Equal(t, "    line one\n\n    line two", indent(1, "line one\n\nline two"))
+}
+
+// testField returns a field of the test schema, which becomes the schema
+// used by the template functions.
+func testField(t *testing.T, typeName, name string) introspection.Field {
+	t.Helper()
+
+	schema, err := generator.IntrospectFile(context.Background(), "../../testdata/schema.graphql")
+	require.NoError(t, err)
+	generator.SetSchemaParents(schema)
+	generator.SetSchema(schema)
+
+	for _, f := range schema.Types.Get(typeName).Fields {
+		if f.Name == name {
+			return *f
+		}
+	}
+	t.Fatalf("no field %s.%s", typeName, name)
+	return introspection.Field{}
+}
+
+func TestSignature(t *testing.T) {
+	id := testField(t, "Container", "id")
+	require.Equal(t, `async def id(self) -> ContainerID:`, signature(id, false))
+	require.Equal(t, `def id(self) -> ContainerID:`, signature(id, true))
+
+	// the object isn't defined yet in its own methods
+	require.Equal(t,
+		`def with_exec(self, args: Sequence[str]) -> "Container":`,
+		signature(testField(t, "Container", "withExec"), false))
+
+	// long signatures get a trailing comma, so black gives them a line per
+	// parameter
+	require.Equal(t,
+		`def with_env_variable(self, name: str, value: str, expand: Optional[bool] = None,) -> "Container":`,
+		signature(testField(t, "Container", "withEnvVariable"), false))
+
+	require.Equal(t,
+		`def container(self, id: Optional[ContainerID] = None) -> Container:`,
+		signature(testField(t, "Query", "container"), false))
+}
+
+func TestDeprecationWarning(t *testing.T) {
+	require.Equal(t,
+		`Method \"exec\" is deprecated: Replaced by \"with_exec\".`,
+		deprecationWarning(testField(t, "Container", "exec")))
+}
+
+func TestMethodDoc(t *testing.T) {
+	require.Equal(t, `.. deprecated::
+    Replaced by :py:meth:`+"`with_exec`"+`.`, methodDoc(testField(t, "Container", "exec")))
+
+	require.Equal(t, `Retrieves this container plus the given environment variable.
+
+Parameters
+----------
+name:
+    The name of the environment variable (e.g., "HOST").
+value:
+    The value of the environment variable.
+expand:
+    Replace ${VAR} or $VAR in the value.`, methodDoc(testField(t, "Container", "withEnvVariable")))
+
+	require.Equal(t, `Note
+----
+This is lazyly evaluated, no operation is actually run.
+
+Returns
+-------
+ContainerID
+    A unique container identifier.
+
+`+raisesDoc, methodDoc(testField(t, "Container", "id")))
+}
+
+func TestDocstring(t *testing.T) {
+	require.Equal(t, `"""A unique container identifier."""`, classDoc("A unique container identifier."))
+	require.Equal(t, "\"\"\"Line one.\n\nLine two.\n\"\"\"", docstring("Line one.\n\nLine two."))
+}
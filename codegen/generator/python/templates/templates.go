@@ -0,0 +1,26 @@
+package templates
+
+import (
+	"embed"
+	"fmt"
+	"text/template"
+)
+
+//go:embed src
+var srcs embed.FS
+
+// New creates a new template with all the templates of the Python
+// definitions set up.
+func New() *template.Template {
+	templateDeps := []string{
+		"header", "scalar", "enum", "input", "object", "all",
+	}
+
+	fileNames := make([]string, 0, len(templateDeps))
+	for _, tmpl := range templateDeps {
+		fileNames = append(fileNames, fmt.Sprintf("src/%s.py.gtpl", tmpl))
+	}
+
+	tmpl := template.Must(template.New("api").Funcs(funcMap).ParseFS(srcs, fileNames...))
+	return tmpl
+}
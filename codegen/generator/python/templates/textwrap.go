@@ -0,0 +1,218 @@
+package templates
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lineWidth is the default width of Python's textwrap, which docstrings have
+// always been wrapped to.
+const lineWidth = 70
+
+// wrap wraps text into lines of at most width characters, the same way as
+// Python's textwrap.wrap with its default options, so that docstrings don't
+// change between generators.
+func wrap(text string, width int, initialIndent, subsequentIndent string) []string {
+	chunks := splitChunks(replaceWhitespace(expandTabs(text)))
+
+	var lines []string
+	for len(chunks) > 0 {
+		var cur []string
+		curLen := 0
+
+		indent := initialIndent
+		if len(lines) > 0 {
+			indent = subsequentIndent
+		}
+		lineWidth := width - runeLen(indent)
+
+		// leading whitespace is only kept on the first line
+		if isBlank(chunks[0]) && len(lines) > 0 {
+			chunks = chunks[1:]
+		}
+
+		for len(chunks) > 0 {
+			l := runeLen(chunks[0])
+			if curLen+l > lineWidth {
+				break
+			}
+			cur = append(cur, chunks[0])
+			curLen += l
+			chunks = chunks[1:]
+		}
+
+		if len(chunks) > 0 && runeLen(chunks[0]) > lineWidth {
+			spaceLeft := lineWidth - curLen
+			if lineWidth < 1 {
+				spaceLeft = 1
+			}
+			chunk := []rune(chunks[0])
+			end := spaceLeft
+			// break long words after a hyphen, if there's one that fits
+			if hyphen := lastIndexRune(chunk[:spaceLeft], '-'); hyphen > 0 && strings.Trim(string(chunk[:hyphen]), "-") != "" {
+				end = hyphen + 1
+			}
+			cur = append(cur, string(chunk[:end]))
+			chunks[0] = string(chunk[end:])
+		}
+
+		if len(cur) > 0 && isBlank(cur[len(cur)-1]) {
+			cur = cur[:len(cur)-1]
+		}
+		if len(cur) > 0 {
+			lines = append(lines, indent+strings.Join(cur, ""))
+		}
+	}
+	return lines
+}
+
+// fill wraps text the same way as Python's textwrap.fill.
+func fill(text string) string {
+	return strings.Join(wrap(text, lineWidth, "", ""), "\n")
+}
+
+// wrapIndent wraps text into lines indented once.
+func wrapIndent(text string) []string {
+	return wrap(text, lineWidth, indentUnit, indentUnit)
+}
+
+// splitChunks splits text into runs of spaces and words, breaking
+// hyphenated words after their hyphens.
+func splitChunks(text string) []string {
+	var chunks []string
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		if runes[start] == ' ' {
+			for end < len(runes) && runes[end] == ' ' {
+				end++
+			}
+		} else {
+			for end < len(runes) && runes[end] != ' ' {
+				end++
+			}
+			chunks = append(chunks, splitHyphenated(runes[start:end])...)
+			start = end
+			continue
+		}
+		chunks = append(chunks, string(runes[start:end]))
+		start = end
+	}
+	return chunks
+}
+
+// splitHyphenated splits a word after the hyphens that are both preceded
+// and followed by letters, e.g. "free-form" into "free-" and "form", and
+// around em-dashes, e.g. "this--that" into "this", "--" and "that".
+func splitHyphenated(word []rune) []string {
+	isLetter := func(i int) bool {
+		return i >= 0 && i < len(word) && (word[i] == '_' || unicode.IsLetter(word[i]))
+	}
+	isWord := func(i int) bool {
+		return i >= 0 && i < len(word) && (word[i] == '_' || unicode.IsLetter(word[i]) || unicode.IsDigit(word[i]))
+	}
+	isWordPunct := func(i int) bool {
+		return isWord(i) || (i >= 0 && i < len(word) && strings.ContainsRune(`!"'&.,?`, word[i]))
+	}
+
+	var chunks []string
+	start := 0
+	for i := start + 1; i < len(word); i++ {
+		if word[i] != '-' {
+			continue
+		}
+		precededByLetters := (isLetter(i-2) && isLetter(i-1)) ||
+			(isLetter(i-3) && word[i-2] == '-' && isLetter(i-1))
+		followedByLetters := isLetter(i+1) &&
+			(isLetter(i+2) || (i+2 < len(word) && word[i+2] == '-' && isLetter(i+3)))
+		if i > start && precededByLetters && followedByLetters {
+			chunks = append(chunks, string(word[start:i+1]))
+			start = i + 1
+			continue
+		}
+
+		dashes := i
+		for dashes < len(word) && word[dashes] == '-' {
+			dashes++
+		}
+		if dashes-i >= 2 && isWordPunct(i-1) && isWord(dashes) {
+			if i > start {
+				chunks = append(chunks, string(word[start:i]))
+			}
+			chunks = append(chunks, string(word[i:dashes]))
+			start = dashes
+			i = dashes - 1
+		}
+	}
+	return append(chunks, string(word[start:]))
+}
+
+// expandTabs replaces tabs by spaces up to the next multiple of 8 columns.
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		switch r {
+		case '\t':
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n', '\r':
+			b.WriteRune(r)
+			col = 0
+		default:
+			b.WriteRune(r)
+			col++
+		}
+	}
+	return b.String()
+}
+
+// replaceWhitespace replaces each whitespace character by a space.
+func replaceWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("\t\n\v\f\r", r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// indent prefixes each line that isn't blank with depth indentation units,
+// like Python's textwrap.indent.
+func indent(depth int, s string) string {
+	prefix := strings.Repeat(indentUnit, depth)
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		if !isBlank(l) {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// splitLines splits s into lines, without a trailing empty line, like
+// Python's str.splitlines.
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
+
+func lastIndexRune(s []rune, r rune) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,15 +1,42 @@
 package core
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/dagger/dagger/core/pipeline"
+	"github.com/dagger/dagger/router"
+	bkclient "github.com/moby/buildkit/client"
+	"github.com/moby/buildkit/client/llb"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
 
 // CacheVolume is a persistent volume with a globally scoped identifier.
 type CacheVolume struct {
 	Keys []string `json:"keys"`
+
+	// Checksum identifies a volume which was listed from the engine, whose
+	// keys aren't known.
+	Checksum string `json:"checksum,omitempty"`
+
+	// Source is the directory the volume starts from when it's mounted
+	// without a source of its own, see WithContents.
+	Source *Directory `json:"source,omitempty"`
+
+	// Pipeline is the pipeline of the directories retrieved from the volume.
+	Pipeline pipeline.Path `json:"pipeline,omitempty"`
+
+	// usage is the disk usage of a listed volume, computed along with the
+	// listing so it isn't queried again for each volume.
+	usage *CacheVolumeUsage
 }
 
 var ErrInvalidCacheID = errors.New("invalid cache ID; create one using cacheVolume")
@@ -21,9 +48,22 @@ func NewCache(keys ...string) *CacheVolume {
 func (cache *CacheVolume) Clone() *CacheVolume {
 	cp := *cache
 	cp.Keys = cloneSlice(cp.Keys)
+	cp.Pipeline = cloneSlice(cp.Pipeline)
+	// the usage of a listed volume doesn't apply to the volumes derived
+	// from it
+	cp.usage = nil
+	if cp.Source != nil {
+		cp.Source = cp.Source.Clone()
+	}
 	return &cp
 }
 
+var _ router.Pipelineable = (*CacheVolume)(nil)
+
+func (cache *CacheVolume) PipelinePath() pipeline.Path {
+	return cache.Pipeline
+}
+
 // CacheID is an arbitrary string typically derived from a set of token
 // strings acting as the cache's "key" or "scope".
 type CacheID string
@@ -34,7 +74,7 @@ func (id CacheID) ToCacheVolume() (*CacheVolume, error) {
 		return nil, ErrInvalidCacheID
 	}
 
-	if len(cache.Keys) == 0 {
+	if len(cache.Keys) == 0 && cache.Checksum == "" {
 		return nil, ErrInvalidCacheID
 	}
 
@@ -43,6 +83,10 @@ func (id CacheID) ToCacheVolume() (*CacheVolume, error) {
 
 // Sum returns a checksum of the cache tokens suitable for use as a cache key.
 func (cache *CacheVolume) Sum() string {
+	if cache.Checksum != "" {
+		return cache.Checksum
+	}
+
 	hash := sha256.New()
 	for _, tok := range cache.Keys {
 		_, _ = hash.Write([]byte(tok + "\x00"))
@@ -65,8 +109,190 @@ const (
 	CacheSharingModeLocked  CacheSharingMode = "LOCKED"
 )
 
+// String returns the volume's keys, or its checksum if they aren't known.
+func (cache *CacheVolume) String() string {
+	if len(cache.Keys) == 0 {
+		return cache.Checksum
+	}
+
+	return strings.Join(cache.Keys, ", ")
+}
+
 func (cache *CacheVolume) WithKey(key string) *CacheVolume {
 	cache = cache.Clone()
 	cache.Keys = append(cache.Keys, key)
 	return cache
 }
+
+// cacheMountIDRegexp matches the ID in the description buildkit gives to
+// cache mount records, e.g.:
+//
+//	cached mount /cache from exec sh -c ... with id "..."
+var cacheMountIDRegexp = regexp.MustCompile(` with id ("(?:[^"\\]|\\.)*")$`)
+
+// cacheMountRecords returns the records of cache mounts in the engine, keyed
+// by cache ID. A volume may have many records, e.g. when mounted with a
+// source directory or with PRIVATE sharing.
+func cacheMountRecords(ctx context.Context, bk *bkclient.Client) (map[string][]*bkclient.UsageInfo, error) {
+	usage, err := bk.DiskUsage(ctx, bkclient.WithFilter([]string{
+		"type==" + string(bkclient.UsageRecordTypeCacheMount),
+	}))
+	if err != nil {
+		return nil, errors.Wrap(err, "disk usage")
+	}
+
+	records := map[string][]*bkclient.UsageInfo{}
+	for _, info := range usage {
+		match := cacheMountIDRegexp.FindStringSubmatch(info.Description)
+		if match == nil {
+			// not a cache volume, e.g. a Dockerfile cache mount without an id
+			continue
+		}
+
+		id, err := strconv.Unquote(match[1])
+		if err != nil {
+			continue
+		}
+
+		records[id] = append(records[id], info)
+	}
+
+	return records, nil
+}
+
+// CacheVolumes lists the cache volumes which exist in the engine, or only
+// the ones with the given checksums if any.
+func CacheVolumes(ctx context.Context, bk *bkclient.Client, checksums []string) ([]*CacheVolume, error) {
+	records, err := cacheMountRecords(ctx, bk)
+	if err != nil {
+		return nil, err
+	}
+
+	if checksums != nil {
+		filtered := map[string][]*bkclient.UsageInfo{}
+		for _, sum := range checksums {
+			if infos, ok := records[sum]; ok {
+				filtered[sum] = infos
+			}
+		}
+		records = filtered
+	}
+
+	volumes := make([]*CacheVolume, 0, len(records))
+	for sum, infos := range records {
+		usage := recordsUsage(infos)
+		volumes = append(volumes, &CacheVolume{Checksum: sum, usage: &usage})
+	}
+
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Checksum < volumes[j].Checksum
+	})
+
+	return volumes, nil
+}
+
+// CacheVolumeUsage is the disk usage of a cache volume.
+type CacheVolumeUsage struct {
+	// Size is the total size of the volume's records in bytes.
+	Size int64
+
+	// LastUsed is when the volume was last used, or nil if it doesn't exist
+	// yet.
+	LastUsed *time.Time
+}
+
+// Usage returns the disk usage of the volume in the engine.
+func (cache *CacheVolume) Usage(ctx context.Context, bk *bkclient.Client) (CacheVolumeUsage, error) {
+	if cache.usage != nil {
+		return *cache.usage, nil
+	}
+
+	records, err := cacheMountRecords(ctx, bk)
+	if err != nil {
+		return CacheVolumeUsage{}, err
+	}
+
+	return recordsUsage(records[cache.Sum()]), nil
+}
+
+func recordsUsage(infos []*bkclient.UsageInfo) CacheVolumeUsage {
+	var usage CacheVolumeUsage
+	for _, info := range infos {
+		usage.Size += info.Size
+
+		if info.LastUsedAt != nil && (usage.LastUsed == nil || info.LastUsedAt.After(*usage.LastUsed)) {
+			usage.LastUsed = info.LastUsedAt
+		}
+	}
+
+	return usage
+}
+
+// Prune deletes the volume's contents from the engine. It fails if the volume
+// is mounted by a running container.
+func (cache *CacheVolume) Prune(ctx context.Context, bk *bkclient.Client) error {
+	records, err := cacheMountRecords(ctx, bk)
+	if err != nil {
+		return err
+	}
+
+	filters := []string{}
+	for _, info := range records[cache.Sum()] {
+		if info.InUse {
+			return errors.New("cache volume is in use")
+		}
+
+		filters = append(filters, "id=="+info.ID)
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return bk.Prune(ctx, nil, bkclient.WithFilter(filters))
+}
+
+// cacheVolumeDir is where the volume is mounted in the cache image.
+const cacheVolumeDir = "/cache"
+
+// Snapshot returns a directory with the volume's current contents.
+func (cache *CacheVolume) Snapshot(ctx context.Context, gw bkgw.Client, platform specs.Platform) (*Directory, error) {
+	mountSt := llb.Scratch()
+	mountOpts := []llb.MountOption{llb.AsPersistentCacheDir(cache.Sum(), llb.CacheMountShared)}
+	var services ServiceBindings
+	if cache.Source != nil {
+		var err error
+		mountSt, err = cache.Source.State()
+		if err != nil {
+			return nil, err
+		}
+		mountOpts = append(mountOpts, llb.SourcePath(cache.Source.Dir))
+		services = cache.Source.Services
+	}
+
+	st := toolsImage(gw).Run(
+		llb.Args([]string{"cp", "-a", cacheVolumeDir + "/.", "/out"}),
+		llb.AddMount(cacheVolumeDir, mountSt, mountOpts...),
+		llb.WithCustomNamef("snapshot cache volume %s", cache),
+		// the contents can change at any time
+		llb.IgnoreCache,
+	).AddMount("/out", llb.Scratch())
+
+	def, err := st.Marshal(ctx, llb.Platform(platform))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDirectory(ctx, def.ToPB(), "", cache.Pipeline, platform, services), nil
+}
+
+// WithContents returns the volume starting from the contents of the
+// directory, like mounting it with the directory as its source: nothing is
+// copied until the volume is mounted. Buildkit keys a cache mount with a
+// source by both its ID and the source, so each different directory gets a
+// volume of its own, seeded with that directory's contents.
+func (cache *CacheVolume) WithContents(dir *Directory) *CacheVolume {
+	cache = cache.Clone()
+	cache.Source = dir
+	return cache
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheMountIDRegexp(t *testing.T) {
+	t.Parallel()
+
+	vol := NewCache("go-mod")
+
+	match := cacheMountIDRegexp.FindStringSubmatch(
+		`cached mount /go/pkg/mod from exec sh -c "echo \"with id\"" with id "` + vol.Sum() + `"`,
+	)
+	require.Len(t, match, 2)
+	require.Equal(t, `"`+vol.Sum()+`"`, match[1])
+
+	// cache mounts without an explicit id are keyed by their path
+	match = cacheMountIDRegexp.FindStringSubmatch(`cached mount /root/.cache from exec go build`)
+	require.Nil(t, match)
+}
+
+func TestCacheVolumeChecksum(t *testing.T) {
+	t.Parallel()
+
+	vol := NewCache("go-mod")
+
+	listed := &CacheVolume{Checksum: vol.Sum()}
+	require.Equal(t, vol.Sum(), listed.Sum())
+
+	id, err := listed.ID()
+	require.NoError(t, err)
+
+	decoded, err := id.ToCacheVolume()
+	require.NoError(t, err)
+	require.Equal(t, vol.Sum(), decoded.Sum())
+}
+
+func TestCacheVolumeWithContents(t *testing.T) {
+	t.Parallel()
+
+	vol := NewCache("go-mod")
+	seeded := vol.WithContents(&Directory{Dir: "/src"})
+	require.Nil(t, vol.Source, "original volume should be unchanged")
+	require.Equal(t, vol.Sum(), seeded.Sum())
+
+	id, err := seeded.ID()
+	require.NoError(t, err)
+
+	decoded, err := id.ToCacheVolume()
+	require.NoError(t, err)
+	require.NotNil(t, decoded.Source)
+	require.Equal(t, "/src", decoded.Source.Dir)
+}
+
+func TestCacheVolumeListedUsage(t *testing.T) {
+	t.Parallel()
+
+	usage := CacheVolumeUsage{Size: 42}
+	listed := &CacheVolume{Checksum: NewCache("go-mod").Sum(), usage: &usage}
+
+	// the usage computed with the listing is used without querying the
+	// engine again
+	got, err := listed.Usage(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, usage, got)
+
+	require.Nil(t, listed.WithKey("other").usage)
+}
@@ -0,0 +1,369 @@
+package core
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha1" // nolint:gosec
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/pmezard/go-difflib/difflib"
+	fstypes "github.com/tonistiigi/fsutil/types"
+)
+
+// ChangeKind is a string deriving from the ChangeKind enum
+// it can take values: ADDED, MODIFIED, DELETED
+type ChangeKind string
+
+const (
+	ChangeKindAdded    ChangeKind = "ADDED"
+	ChangeKindModified ChangeKind = "MODIFIED"
+	ChangeKindDeleted  ChangeKind = "DELETED"
+)
+
+// Change describes a path that differs between two filesystem trees.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Path string     `json:"path"`
+
+	// Sizes of the path before and after the change, if it was a regular file.
+	OldSize *int `json:"oldSize,omitempty"`
+	NewSize *int `json:"newSize,omitempty"`
+}
+
+// treeReader reads the contents of a file in a filesystem tree given its path
+// relative to the root of the tree.
+type treeReader func(string) ([]byte, error)
+
+// walkRef recursively lists everything beneath root in the given reference,
+// keyed by slash-separated paths relative to root.
+func walkRef(ctx context.Context, ref bkgw.Reference, root string) (map[string]*fstypes.Stat, error) {
+	stats := map[string]*fstypes.Stat{}
+
+	// empty directory, i.e. llb.Scratch()
+	if ref == nil {
+		return stats, nil
+	}
+
+	var walk func(string) error
+	walk = func(dir string) error {
+		entries, err := ref.ReadDir(ctx, bkgw.ReadDirRequest{
+			Path: path.Join(root, dir),
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			rel := path.Join(dir, entry.GetPath())
+			stats[rel] = entry
+
+			if fs.FileMode(entry.Mode).IsDir() {
+				if err := walk(rel); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// diffTrees compares two trees listed by walkRef and returns the changes from
+// oldTree to newTree, sorted by path.
+func diffTrees(oldTree, newTree map[string]*fstypes.Stat, readOld, readNew treeReader) ([]Change, error) {
+	changes := []Change{}
+
+	for p, newStat := range newTree {
+		oldStat, found := oldTree[p]
+		if !found {
+			changes = append(changes, Change{
+				Kind:    ChangeKindAdded,
+				Path:    p,
+				NewSize: statSize(newStat),
+			})
+			continue
+		}
+
+		modified, err := statModified(p, oldStat, newStat, readOld, readNew)
+		if err != nil {
+			return nil, err
+		}
+
+		if modified {
+			changes = append(changes, Change{
+				Kind:    ChangeKindModified,
+				Path:    p,
+				OldSize: statSize(oldStat),
+				NewSize: statSize(newStat),
+			})
+		}
+	}
+
+	for p, oldStat := range oldTree {
+		if _, found := newTree[p]; !found {
+			changes = append(changes, Change{
+				Kind:    ChangeKindDeleted,
+				Path:    p,
+				OldSize: statSize(oldStat),
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// statModified reports whether the path differs between the two trees. File
+// contents are only compared when their sizes match.
+func statModified(p string, oldStat, newStat *fstypes.Stat, readOld, readNew treeReader) (bool, error) {
+	oldMode := fs.FileMode(oldStat.Mode)
+	newMode := fs.FileMode(newStat.Mode)
+
+	if oldMode.Type() != newMode.Type() {
+		return true, nil
+	}
+
+	switch {
+	case newMode.IsRegular():
+		if oldMode.Perm() != newMode.Perm() || oldStat.Size_ != newStat.Size_ {
+			return true, nil
+		}
+
+		oldContent, err := readOld(p)
+		if err != nil {
+			return false, err
+		}
+
+		newContent, err := readNew(p)
+		if err != nil {
+			return false, err
+		}
+
+		return !bytes.Equal(oldContent, newContent), nil
+	case newMode&fs.ModeSymlink != 0:
+		return oldStat.Linkname != newStat.Linkname, nil
+	default:
+		return false, nil
+	}
+}
+
+// statSize returns the size of a regular file, or nil for anything else.
+func statSize(stat *fstypes.Stat) *int {
+	if !fs.FileMode(stat.Mode).IsRegular() {
+		return nil
+	}
+
+	size := int(stat.Size_)
+	return &size
+}
+
+// writePatch writes the changes as a unified diff, in the same format as `git
+// diff --binary`, including the extended headers of added and deleted files
+// and of mode changes. Only regular files are included.
+func writePatch(w io.Writer, changes []Change, oldTree, newTree map[string]*fstypes.Stat, readOld, readNew treeReader) error {
+	for _, change := range changes {
+		if change.OldSize == nil && change.NewSize == nil {
+			continue
+		}
+
+		fromFile := "a/" + change.Path
+		toFile := "b/" + change.Path
+
+		header := fmt.Sprintf("diff --git %s %s\n", fromFile, toFile)
+
+		var oldContent, newContent []byte
+		var err error
+
+		// the mode given on the index line of binary patches, when it's
+		// unchanged
+		indexMode := ""
+
+		switch {
+		case change.OldSize == nil:
+			header += fmt.Sprintf("new file mode %s\n", gitMode(newTree[change.Path]))
+		case change.NewSize == nil:
+			header += fmt.Sprintf("deleted file mode %s\n", gitMode(oldTree[change.Path]))
+		default:
+			if oldMode, newMode := gitMode(oldTree[change.Path]), gitMode(newTree[change.Path]); oldMode != newMode {
+				header += fmt.Sprintf("old mode %s\nnew mode %s\n", oldMode, newMode)
+			} else {
+				indexMode = " " + oldMode
+			}
+		}
+
+		if change.OldSize != nil {
+			oldContent, err = readOld(change.Path)
+			if err != nil {
+				return err
+			}
+		} else {
+			fromFile = "/dev/null"
+		}
+
+		if change.NewSize != nil {
+			newContent, err = readNew(change.Path)
+			if err != nil {
+				return err
+			}
+		} else {
+			toFile = "/dev/null"
+		}
+
+		if _, err := io.WriteString(w, header); err != nil {
+			return err
+		}
+
+		// like git, only give the headers of empty files and of files whose
+		// mode changed but not their contents
+		if bytes.Equal(oldContent, newContent) {
+			continue
+		}
+
+		if bytes.IndexByte(oldContent, 0) != -1 || bytes.IndexByte(newContent, 0) != -1 {
+			_, err := fmt.Fprintf(w, "index %s..%s%s\nGIT binary patch\n",
+				gitBlobHash(oldContent, change.OldSize != nil),
+				gitBlobHash(newContent, change.NewSize != nil),
+				indexMode)
+			if err != nil {
+				return err
+			}
+
+			// the reverse patch comes second, as with git
+			if err := writeBinaryLiteral(w, newContent); err != nil {
+				return err
+			}
+			if err := writeBinaryLiteral(w, oldContent); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+			A:        splitLines(oldContent),
+			B:        splitLines(newContent),
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gitBlobHash returns the full hash git gives to a blob with the content, or
+// the null hash if the file doesn't exist.
+func gitBlobHash(content []byte, exists bool) string {
+	if !exists {
+		return strings.Repeat("0", 40)
+	}
+
+	// git's object IDs are SHA-1 hashes
+	h := sha1.New() // nolint:gosec
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// gitBase85 is the alphabet of git's base85 encoding.
+const gitBase85 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// writeBinaryLiteral writes the content as a literal hunk of a git binary
+// patch: zlib compressed, then base85 encoded in lines of up to 52 bytes,
+// each prefixed by its length.
+func writeBinaryLiteral(w io.Writer, content []byte) error {
+	compressed := new(bytes.Buffer)
+	zw := zlib.NewWriter(compressed)
+	if _, err := zw.Write(content); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	out := new(bytes.Buffer)
+	fmt.Fprintf(out, "literal %d\n", len(content))
+
+	data := compressed.Bytes()
+	for len(data) > 0 {
+		n := len(data)
+		if n > 52 {
+			n = 52
+		}
+
+		if n <= 26 {
+			out.WriteByte(byte('A' + n - 1))
+		} else {
+			out.WriteByte(byte('a' + n - 27))
+		}
+
+		for i := 0; i < n; i += 4 {
+			var group uint32
+			for j := 0; j < 4; j++ {
+				group <<= 8
+				if i+j < n {
+					group |= uint32(data[i+j])
+				}
+			}
+
+			var digits [5]byte
+			for j := 4; j >= 0; j-- {
+				digits[j] = gitBase85[group%85]
+				group /= 85
+			}
+			out.Write(digits[:])
+		}
+		out.WriteByte('\n')
+
+		data = data[n:]
+	}
+	out.WriteByte('\n')
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// gitMode returns the mode git records for a regular file, which only keeps
+// whether it's executable.
+func gitMode(stat *fstypes.Stat) string {
+	if stat != nil && fs.FileMode(stat.Mode).Perm()&0o111 != 0 {
+		return "100755"
+	}
+
+	return "100644"
+}
+
+// noNewline marks a last line without a trailing newline in a unified diff.
+const noNewline = "\\ No newline at end of file\n"
+
+// splitLines splits content into lines, keeping their trailing newlines.
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	// terminate the last line so that hunks stay well-formed, and mark it so
+	// that it differs from the same line with a newline
+	lines[len(lines)-1] += "\n" + noNewline
+	return lines
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	fstypes "github.com/tonistiigi/fsutil/types"
+)
+
+type testTree map[string]string
+
+func (tree testTree) stats() map[string]*fstypes.Stat {
+	stats := map[string]*fstypes.Stat{}
+	for p, content := range tree {
+		stats[p] = &fstypes.Stat{
+			Path:  p,
+			Mode:  0o644,
+			Size_: int64(len(content)),
+		}
+	}
+	return stats
+}
+
+func (tree testTree) read(p string) ([]byte, error) {
+	return []byte(tree[p]), nil
+}
+
+func TestDiffTrees(t *testing.T) {
+	t.Parallel()
+
+	oldTree := testTree{
+		"same":     "same\n",
+		"modified": "old\n",
+		"resized":  "old\n",
+		"deleted":  "deleted\n",
+	}
+	newTree := testTree{
+		"same":     "same\n",
+		"modified": "new\n",
+		"resized":  "longer\n",
+		"added":    "added\n",
+	}
+
+	oldStats := oldTree.stats()
+	newStats := newTree.stats()
+	newStats["dir"] = &fstypes.Stat{Path: "dir", Mode: uint32(fs.ModeDir | 0o755)}
+
+	changes, err := diffTrees(oldStats, newStats, oldTree.read, newTree.read)
+	require.NoError(t, err)
+
+	size := func(n int) *int { return &n }
+	require.Equal(t, []Change{
+		{Kind: ChangeKindAdded, Path: "added", NewSize: size(6)},
+		{Kind: ChangeKindDeleted, Path: "deleted", OldSize: size(8)},
+		{Kind: ChangeKindAdded, Path: "dir"},
+		{Kind: ChangeKindModified, Path: "modified", OldSize: size(4), NewSize: size(4)},
+		{Kind: ChangeKindModified, Path: "resized", OldSize: size(4), NewSize: size(7)},
+	}, changes)
+}
+
+func TestWritePatch(t *testing.T) {
+	t.Parallel()
+
+	oldTree := testTree{
+		"modified":   "a\nb\nc\n",
+		"deleted":    "gone\n",
+		"binary":     "\x00old",
+		"empty-gone": "",
+		"chmod":      "same\n",
+		"chmod-edit": "old\n",
+		"newline":    "no newline",
+	}
+	newTree := testTree{
+		"modified":   "a\nB\nc\n",
+		"added":      "no newline",
+		"binary":     "\x00new",
+		"empty":      "",
+		"chmod":      "same\n",
+		"chmod-edit": "new\n",
+		"newline":    "no newline\n",
+	}
+
+	oldStats := oldTree.stats()
+	newStats := newTree.stats()
+	newStats["chmod"].Mode = 0o755
+	newStats["chmod-edit"].Mode = 0o755
+
+	changes, err := diffTrees(oldStats, newStats, oldTree.read, newTree.read)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, writePatch(buf, changes, oldStats, newStats, oldTree.read, newTree.read))
+	require.Equal(t, `diff --git a/added b/added
+new file mode 100644
+--- /dev/null
++++ b/added
+@@ -0,0 +1 @@
++no newline
+\ No newline at end of file
+diff --git a/binary b/binary
+index e7be1ea5722fddec13dbce5018dc1056c0dd3fdb..f9e371ff2657e5d2bd4389e3b323fe0550e3a860 100644
+GIT binary patch
+literal 4
+Qc$@$P0Q>&{Ze@1^00NHzO8@`+"`"+`>
+
+literal 4
+Qc$@$P0Q>&{Z){`+"`"+`(00NBxKmY&$
+
+diff --git a/chmod b/chmod
+old mode 100644
+new mode 100755
+diff --git a/chmod-edit b/chmod-edit
+old mode 100644
+new mode 100755
+--- a/chmod-edit
++++ b/chmod-edit
+@@ -1 +1 @@
+-old
++new
+diff --git a/deleted b/deleted
+deleted file mode 100644
+--- a/deleted
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/empty b/empty
+new file mode 100644
+diff --git a/empty-gone b/empty-gone
+deleted file mode 100644
+diff --git a/modified b/modified
+--- a/modified
++++ b/modified
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+diff --git a/newline b/newline
+--- a/newline
++++ b/newline
+@@ -1 +1 @@
+-no newline
+\ No newline at end of file
++no newline
+`, buf.String())
+}
@@ -254,6 +254,12 @@ type ContainerMount struct {
 
 	// Configure the mount as a tmpfs.
 	Tmpfs bool `json:"tmpfs,omitempty"`
+
+	// The maximum size of the tmpfs in bytes, or 0 for no limit.
+	TmpfsSize int `json:"tmpfs_size,omitempty"`
+
+	// Configure the mount as read-only.
+	Readonly bool `json:"readonly,omitempty"`
 }
 
 // SourceState returns the state of the source of the mount.
@@ -413,12 +419,12 @@ func (container *Container) buildUncached(
 			"contextsubdir": context.Dir,
 		}
 
-		if dockerfile != "" {
-			opts["filename"] = path.Join(context.Dir, dockerfile)
-		} else {
-			opts["filename"] = path.Join(context.Dir, defaultDockerfileName)
+		if dockerfile == "" {
+			dockerfile = defaultDockerfileName
 		}
 
+		opts["filename"] = path.Join(context.Dir, dockerfile)
+
 		if target != "" {
 			opts["target"] = target
 		}
@@ -427,8 +433,41 @@ func (container *Container) buildUncached(
 			opts["build-arg:"+buildArg.Name] = buildArg.Value
 		}
 
+		// the frontend only applies .dockerignore to local contexts, so apply it
+		// to the context input ourselves
+		excludes, err := loadDockerignore(ctx, gw, context, dockerfile)
+		if err != nil {
+			return nil, err
+		}
+
+		contextDef := context.LLB
+		if len(excludes) > 0 {
+			contextSt, err := context.State()
+			if err != nil {
+				return nil, err
+			}
+
+			contextDir := context.Dir
+			if contextDir == "" {
+				contextDir = "/"
+			}
+
+			contextSt = llb.Scratch().File(llb.Copy(contextSt, contextDir, contextDir, &llb.CopyInfo{
+				CopyDirContentsOnly: true,
+				CreateDestPath:      true,
+				ExcludePatterns:     excludes,
+			}))
+
+			def, err := contextSt.Marshal(ctx, llb.Platform(platform))
+			if err != nil {
+				return nil, err
+			}
+
+			contextDef = def.ToPB()
+		}
+
 		inputs := map[string]*pb.Definition{
-			dockerui.DefaultLocalNameContext:    context.LLB,
+			dockerui.DefaultLocalNameContext:    contextDef,
 			dockerui.DefaultLocalNameDockerfile: context.LLB,
 		}
 
@@ -554,19 +593,19 @@ func (container *Container) WithNewFile(ctx context.Context, gw bkgw.Client, des
 	})
 }
 
-func (container *Container) WithMountedDirectory(ctx context.Context, gw bkgw.Client, target string, dir *Directory, owner string) (*Container, error) {
+func (container *Container) WithMountedDirectory(ctx context.Context, gw bkgw.Client, target string, dir *Directory, owner string, readonly bool) (*Container, error) {
 	container = container.Clone()
 
-	return container.withMounted(ctx, gw, target, dir.LLB, dir.Dir, dir.Services, owner)
+	return container.withMounted(ctx, gw, target, dir.LLB, dir.Dir, dir.Services, owner, readonly)
 }
 
-func (container *Container) WithMountedFile(ctx context.Context, gw bkgw.Client, target string, file *File, owner string) (*Container, error) {
+func (container *Container) WithMountedFile(ctx context.Context, gw bkgw.Client, target string, file *File, owner string, readonly bool) (*Container, error) {
 	container = container.Clone()
 
-	return container.withMounted(ctx, gw, target, file.LLB, file.File, file.Services, owner)
+	return container.withMounted(ctx, gw, target, file.LLB, file.File, file.Services, owner, readonly)
 }
 
-func (container *Container) WithMountedCache(ctx context.Context, gw bkgw.Client, target string, cache *CacheVolume, source *Directory, concurrency CacheSharingMode, owner string) (*Container, error) {
+func (container *Container) WithMountedCache(ctx context.Context, gw bkgw.Client, target string, cache *CacheVolume, source *Directory, concurrency CacheSharingMode, owner string, readonly bool) (*Container, error) {
 	container = container.Clone()
 
 	target = absPath(container.Config.WorkingDir, target)
@@ -585,11 +624,18 @@ func (container *Container) WithMountedCache(ctx context.Context, gw bkgw.Client
 		Target:           target,
 		CacheID:          cache.Sum(),
 		CacheSharingMode: cacheSharingMode,
+		Readonly:         readonly,
+	}
+
+	if source == nil {
+		// the volume's own contents, see CacheVolume.WithContents
+		source = cache.Source
 	}
 
 	if source != nil {
 		mount.Source = source.LLB
 		mount.SourcePath = source.Dir
+		container.Services.Merge(source.Services)
 	}
 
 	if owner != "" {
@@ -615,14 +661,19 @@ func (container *Container) WithMountedCache(ctx context.Context, gw bkgw.Client
 	return container, nil
 }
 
-func (container *Container) WithMountedTemp(ctx context.Context, target string) (*Container, error) {
+func (container *Container) WithMountedTemp(ctx context.Context, target string, size int) (*Container, error) {
 	container = container.Clone()
 
+	if size < 0 {
+		return nil, fmt.Errorf("invalid tmpfs size %d", size)
+	}
+
 	target = absPath(container.Config.WorkingDir, target)
 
 	container.Mounts = container.Mounts.With(ContainerMount{
-		Target: target,
-		Tmpfs:  true,
+		Target:    target,
+		Tmpfs:     true,
+		TmpfsSize: size,
 	})
 
 	// set image ref to empty string
@@ -869,6 +920,7 @@ func (container *Container) withMounted(
 	srcPath string,
 	svcs ServiceBindings,
 	owner string,
+	readonly bool,
 ) (*Container, error) {
 	target = absPath(container.Config.WorkingDir, target)
 
@@ -884,6 +936,7 @@ func (container *Container) withMounted(
 		Source:     srcDef,
 		SourcePath: srcPath,
 		Target:     target,
+		Readonly:   readonly,
 	})
 
 	container.Services.Merge(svcs)
@@ -996,7 +1049,7 @@ func (container *Container) writeToPath(ctx context.Context, gw bkgw.Client, sub
 		return container.WithRootFS(ctx, root)
 	}
 
-	return container.withMounted(ctx, gw, mount.Target, dir.LLB, mount.SourcePath, nil, "")
+	return container.withMounted(ctx, gw, mount.Target, dir.LLB, mount.SourcePath, nil, "", mount.Readonly)
 }
 
 func (container *Container) ImageConfig(ctx context.Context) (specs.ImageConfig, error) {
@@ -1214,7 +1267,16 @@ func (container *Container) WithExec(ctx context.Context, gw bkgw.Client, progSo
 		}
 
 		if mnt.Tmpfs {
-			mountOpts = append(mountOpts, llb.Tmpfs())
+			var tmpfsOpts []llb.TmpfsOption
+			if mnt.TmpfsSize > 0 {
+				tmpfsOpts = append(tmpfsOpts, llb.TmpfsSize(int64(mnt.TmpfsSize)))
+			}
+
+			mountOpts = append(mountOpts, llb.Tmpfs(tmpfsOpts...))
+		}
+
+		if mnt.Readonly {
+			mountOpts = append(mountOpts, llb.Readonly)
 		}
 
 		runOpts = append(runOpts, llb.AddMount(mnt.Target, srcSt, mountOpts...))
@@ -1261,7 +1323,8 @@ func (container *Container) WithExec(ctx context.Context, gw bkgw.Client, progSo
 	container.Meta = metaDef.ToPB()
 
 	for i, mnt := range mounts {
-		if mnt.Tmpfs || mnt.CacheID != "" {
+		if mnt.Tmpfs || mnt.CacheID != "" || mnt.Readonly {
+			// read-only mounts can't have changed
 			continue
 		}
 
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/fs"
@@ -116,6 +117,14 @@ func (dir *Directory) Digest() (digest.Digest, error) {
 	return id.Digest()
 }
 
+// Directory is volatile when it's read from the host, whose files may change
+// while its ID doesn't.
+var _ router.Volatile = (*Directory)(nil)
+
+func (dir *Directory) Volatile() bool {
+	return hasLocalSource(dir.LLB)
+}
+
 func (dir *Directory) State() (llb.State, error) {
 	if dir.LLB == nil {
 		return llb.Scratch(), nil
@@ -504,6 +513,7 @@ func (dir *Directory) Export(
 	ctx context.Context,
 	host *Host,
 	dest string,
+	wipe bool,
 	bkClient *bkclient.Client,
 	solveOpts bkclient.SolveOpt,
 	solveCh chan<- *bkclient.SolveStatus,
@@ -513,7 +523,10 @@ func (dir *Directory) Export(
 		return err
 	}
 
-	return host.Export(ctx, bkclient.ExportEntry{
+	// contents of the exported directory, used for wiping stale paths
+	var tree map[string]*fstypes.Stat
+
+	err = host.Export(ctx, bkclient.ExportEntry{
 		Type:      bkclient.ExporterLocal,
 		OutputDir: dest,
 	}, bkClient, solveOpts, solveCh, func(ctx context.Context, gw bkgw.Client) (*bkgw.Result, error) {
@@ -539,12 +552,144 @@ func (dir *Directory) Export(
 				defPB = dir.LLB
 			}
 
-			return gw.Solve(ctx, bkgw.SolveRequest{
+			res, err := gw.Solve(ctx, bkgw.SolveRequest{
 				Evaluate:   true,
 				Definition: defPB,
 			})
+			if err != nil {
+				return nil, err
+			}
+
+			if wipe {
+				ref, err := res.SingleRef()
+				if err != nil {
+					return nil, err
+				}
+
+				tree, err = walkRef(ctx, ref, "")
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return res, nil
 		})
 	})
+	if err != nil {
+		return err
+	}
+
+	if wipe {
+		return host.wipe(dest, tree)
+	}
+
+	return nil
+}
+
+// ExportChanges returns the changes that exporting the directory to dest on
+// the host would make, without writing anything.
+func (dir *Directory) ExportChanges(ctx context.Context, gw bkgw.Client, host *Host, dest string, wipe bool) ([]Change, error) {
+	dest, err := host.NormalizeDest(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return WithServices(ctx, gw, dir.Services, func() ([]Change, error) {
+		tree, readFile, err := dir.tree(ctx, gw)
+		if err != nil {
+			return nil, err
+		}
+
+		return host.exportChanges(dest, tree, wipe, readFile)
+	})
+}
+
+// Changes returns the paths which differ between the directory and other,
+// treating other as the newer of the two.
+func (dir *Directory) Changes(ctx context.Context, gw bkgw.Client, other *Directory) ([]Change, error) {
+	svcs := ServiceBindings{}
+	svcs.Merge(dir.Services)
+	svcs.Merge(other.Services)
+
+	return WithServices(ctx, gw, svcs, func() ([]Change, error) {
+		oldTree, readOld, err := dir.tree(ctx, gw)
+		if err != nil {
+			return nil, err
+		}
+
+		newTree, readNew, err := other.tree(ctx, gw)
+		if err != nil {
+			return nil, err
+		}
+
+		return diffTrees(oldTree, newTree, readOld, readNew)
+	})
+}
+
+// Patch returns a file containing a unified diff of the changes from the
+// directory to other.
+func (dir *Directory) Patch(ctx context.Context, gw bkgw.Client, other *Directory) (*File, error) {
+	svcs := ServiceBindings{}
+	svcs.Merge(dir.Services)
+	svcs.Merge(other.Services)
+
+	patch, err := WithServices(ctx, gw, svcs, func() ([]byte, error) {
+		oldTree, readOld, err := dir.tree(ctx, gw)
+		if err != nil {
+			return nil, err
+		}
+
+		newTree, readNew, err := other.tree(ctx, gw)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := diffTrees(oldTree, newTree, readOld, readNew)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := new(bytes.Buffer)
+		if err := writePatch(buf, changes, oldTree, newTree, readOld, readNew); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	st := llb.Scratch().File(llb.Mkfile("changes.patch", 0o644, patch))
+
+	return NewFileSt(ctx, st, "changes.patch", dir.Pipeline, dir.Platform, nil)
+}
+
+// tree solves the directory and recursively lists its contents, returning
+// them along with a function for reading files within it.
+func (dir *Directory) tree(ctx context.Context, gw bkgw.Client) (map[string]*fstypes.Stat, treeReader, error) {
+	res, err := gw.Solve(ctx, bkgw.SolveRequest{
+		Definition: dir.LLB,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ref, err := res.SingleRef()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := walkRef(ctx, ref, dir.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tree, func(p string) ([]byte, error) {
+		return ref.ReadFile(ctx, bkgw.ReadRequest{
+			Filename: path.Join(dir.Dir, p),
+		})
+	}, nil
 }
 
 // Root removes any relative path from the directory.
@@ -109,6 +109,14 @@ func (file *File) Digest() (digest.Digest, error) {
 	return id.Digest()
 }
 
+// File is volatile when it's read from the host, whose files may change
+// while its ID doesn't.
+var _ router.Volatile = (*File)(nil)
+
+func (file *File) Volatile() bool {
+	return hasLocalSource(file.LLB)
+}
+
 func (file *File) State() (llb.State, error) {
 	return defToState(file.LLB)
 }
@@ -0,0 +1,396 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dagger/dagger/core/pipeline"
+	"github.com/moby/buildkit/client/llb"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/util/sshutil"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// GitFetchOpts configures how a git ref is fetched.
+type GitFetchOpts struct {
+	KeepGitDir bool
+
+	// Depth is the number of commits of history to fetch, along with any
+	// tags. If zero, only the commit itself is fetched.
+	Depth int
+
+	// SkipSubmodules leaves submodules uninitialized, i.e. as empty
+	// directories.
+	SkipSubmodules bool
+
+	// AuthToken and AuthHeader authenticate fetches over HTTPS, either with a
+	// token or with the value of a complete Authorization header.
+	AuthToken  SecretID
+	AuthHeader SecretID
+
+	// SSHKey is a private key used to authenticate fetches over SSH, as an
+	// alternative to an SSH agent's socket.
+	SSHKey SecretID
+
+	SSHKnownHosts string
+	SSHAuthSocket SocketID
+}
+
+// gitSHA matches a full commit SHA, which is safe to cache forever.
+var gitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// IsGitCommitSHA reports whether the ref is a full commit SHA, rather than the
+// name of a ref.
+func IsGitCommitSHA(ref string) bool {
+	return gitSHA.MatchString(ref)
+}
+
+// gitCheckoutDir is where the repository is checked out in the git image.
+const gitCheckoutDir = "/src"
+
+// gitSSHSockPath is where the SSH auth socket is mounted in the git image.
+const gitSSHSockPath = "/tmp/ssh-agent.sock"
+
+// gitSSHKeyPath is where the SSH private key is mounted in the git image.
+const gitSSHKeyPath = "/tmp/ssh-key"
+
+// GitFetch fetches the ref from the repository at the given URL. An empty ref
+// fetches the repository's default branch.
+//
+// Buildkit's git source is used when possible. It always fetches submodules,
+// only fetches a single commit of history and can't use an SSH private key, so
+// otherwise the repository is fetched with git in a container instead.
+func GitFetch(gw bkgw.Client, url, ref string, opts GitFetchOpts) llb.State {
+	if opts.Depth == 0 && !opts.SkipSubmodules && opts.SSHKey == "" {
+		gitOpts := []llb.GitOption{}
+		if opts.KeepGitDir {
+			gitOpts = append(gitOpts, llb.KeepGitDir())
+		}
+		if opts.AuthToken != "" {
+			gitOpts = append(gitOpts, llb.AuthTokenSecret(opts.AuthToken.String()))
+		}
+		if opts.AuthHeader != "" {
+			gitOpts = append(gitOpts, llb.AuthHeaderSecret(opts.AuthHeader.String()))
+		}
+		if opts.SSHKnownHosts != "" {
+			gitOpts = append(gitOpts, llb.KnownSSHHosts(opts.SSHKnownHosts))
+		}
+		if opts.SSHAuthSocket != "" {
+			gitOpts = append(gitOpts, llb.MountSSHSock(opts.SSHAuthSocket.LLBID()))
+		}
+		return llb.Git(url, ref, gitOpts...)
+	}
+
+	runOpts := gitRunOpts(url, opts)
+	runOpts = append(runOpts,
+		llb.Args([]string{"sh", "-c", gitFetchScript(opts)}),
+		llb.AddEnv("GIT_REF", ref),
+		llb.Dir(gitCheckoutDir),
+		llb.WithCustomNamef("git fetch %s %s", url, ref),
+	)
+
+	// branches and tags can move, so fetch them again in every session
+	if !gitSHA.MatchString(ref) {
+		runOpts = append(runOpts, llb.IgnoreCache)
+	}
+
+	return toolsBase(gw).Run(runOpts...).
+		AddMount(gitCheckoutDir, llb.Scratch())
+}
+
+// gitRunOpts returns the options for running git against the repository at
+// the given URL, with any credentials in opts.
+func gitRunOpts(url string, opts GitFetchOpts) []llb.RunOption {
+	runOpts := []llb.RunOption{
+		llb.AddEnv("GIT_URL", gitRemoteURL(url)),
+		llb.AddEnv("GIT_SSH_KNOWN_HOSTS", opts.SSHKnownHosts),
+	}
+
+	if opts.SSHAuthSocket != "" {
+		runOpts = append(runOpts,
+			llb.AddSSHSocket(
+				llb.SSHID(opts.SSHAuthSocket.LLBID()),
+				llb.SSHSocketTarget(gitSSHSockPath),
+			),
+			llb.AddEnv("SSH_AUTH_SOCK", gitSSHSockPath),
+		)
+	}
+
+	if opts.AuthToken != "" {
+		runOpts = append(runOpts, llb.AddSecret("GIT_AUTH_TOKEN",
+			llb.SecretID(opts.AuthToken.String()),
+			llb.SecretAsEnv(true),
+		))
+	}
+
+	if opts.AuthHeader != "" {
+		runOpts = append(runOpts, llb.AddSecret("GIT_AUTH_HEADER",
+			llb.SecretID(opts.AuthHeader.String()),
+			llb.SecretAsEnv(true),
+		))
+	}
+
+	if opts.SSHKey != "" {
+		runOpts = append(runOpts, llb.AddSecret(gitSSHKeyPath,
+			llb.SecretID(opts.SSHKey.String()),
+			llb.SecretFileOpt(0, 0, 0o400),
+		))
+	}
+
+	return runOpts
+}
+
+// gitFetchScript returns a script which fetches $GIT_REF from $GIT_URL into
+// the working directory.
+func gitFetchScript(opts GitFetchOpts) string {
+	depth := 1
+	fetchFlags := "--depth=1"
+	if opts.Depth > 0 {
+		depth = opts.Depth
+
+		// also fetch tags, for tools like `git describe`
+		fetchFlags = fmt.Sprintf("--tags --depth=%d", depth)
+	}
+
+	script := gitAuthScript(opts)
+
+	script += `git init -q
+git remote add origin "$GIT_URL"
+git fetch -q ` + fetchFlags + ` origin "${GIT_REF:-HEAD}"
+git checkout -q FETCH_HEAD
+`
+
+	if !opts.SkipSubmodules {
+		script += fmt.Sprintf("git submodule -q update --init --recursive --depth=%d\n", depth)
+	}
+
+	if !opts.KeepGitDir {
+		script += "rm -rf .git\n"
+	}
+
+	return script
+}
+
+// gitAuthScript returns a script which configures git to use the credentials
+// in opts, as set up by gitRunOpts.
+func gitAuthScript(opts GitFetchOpts) string {
+	sshCommand := "ssh -o StrictHostKeyChecking=no"
+	if opts.SSHKnownHosts != "" {
+		sshCommand = "ssh -o UserKnownHostsFile=/tmp/known_hosts"
+	}
+	if opts.SSHKey != "" {
+		sshCommand += " -o IdentitiesOnly=yes -i " + gitSSHKeyPath
+	}
+
+	script := `set -e
+printf '%s\n' "$GIT_SSH_KNOWN_HOSTS" > /tmp/known_hosts
+export GIT_SSH_COMMAND="` + sshCommand + `"
+`
+
+	// pass the Authorization header through the environment, rather than
+	// config, so that it's never written to .git/config
+	switch {
+	case opts.AuthHeader != "":
+		script += `export GIT_CONFIG_COUNT=1 GIT_CONFIG_KEY_0="http.$GIT_URL.extraHeader" GIT_CONFIG_VALUE_0="Authorization: $GIT_AUTH_HEADER"
+`
+	case opts.AuthToken != "":
+		script += `export GIT_CONFIG_COUNT=1 GIT_CONFIG_KEY_0="http.$GIT_URL.extraHeader" GIT_CONFIG_VALUE_0="Authorization: basic $(printf 'x-access-token:%s' "$GIT_AUTH_TOKEN" | base64 | tr -d '\n')"
+`
+	}
+
+	return script
+}
+
+// gitRemoteURL defaults the URL to HTTPS if it doesn't specify a transport, in
+// the same way as buildkit's git source.
+func gitRemoteURL(url string) string {
+	for _, prefix := range []string{"http://", "https://", "git://", "ssh://"} {
+		if strings.HasPrefix(url, prefix) {
+			return url
+		}
+	}
+
+	if sshutil.IsImplicitSSHTransport(url) {
+		return url
+	}
+
+	return "https://" + url
+}
+
+// GitRemoteRef is a ref advertised by a remote repository.
+type GitRemoteRef struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha"`
+}
+
+// GitCommit describes a commit in a repository's history.
+type GitCommit struct {
+	SHA     string `json:"sha"`
+	Author  string `json:"author"`
+	Message string `json:"message"`
+
+	// Timestamp is the author date, in seconds since the Unix epoch.
+	Timestamp int `json:"timestamp"`
+}
+
+// gitOutputDir is where the output of a git command is written in the git
+// image.
+const gitOutputDir = "/out"
+
+// GitLsRemote lists the refs in the remote repository matching any of the
+// patterns, or every ref if there are none, in the same way as `git
+// ls-remote`. Annotated tags are resolved to the commit they point to.
+func GitLsRemote(ctx context.Context, gw bkgw.Client, url string, patterns []string, opts GitFetchOpts, p pipeline.Path, platform specs.Platform, svcs ServiceBindings) ([]GitRemoteRef, error) {
+	// refs can move, so always list them again
+	out, err := gitOutput(ctx, gw, url, opts,
+		`git ls-remote "$GIT_URL" "$@" > `+gitOutputDir+`/output`,
+		patterns,
+		false,
+		fmt.Sprintf("git ls-remote %s %s", url, strings.Join(patterns, " ")),
+		p, platform, svcs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLsRemote(out)
+}
+
+// GitLog lists up to limit commits of history from the ref, or the entire
+// history if limit is zero, newest first. An empty ref lists the history of
+// the repository's default branch.
+func GitLog(ctx context.Context, gw bkgw.Client, url, ref string, limit int, opts GitFetchOpts, p pipeline.Path, platform specs.Platform, svcs ServiceBindings) ([]GitCommit, error) {
+	fetchFlags := ""
+	logFlags := ""
+	if limit > 0 {
+		fetchFlags = fmt.Sprintf("--depth=%d", limit)
+		logFlags = fmt.Sprintf("-n %d", limit)
+	}
+
+	out, err := gitOutput(ctx, gw, url, opts,
+		`cd "$(mktemp -d)"
+git init -q --bare
+git fetch -q `+fetchFlags+` "$GIT_URL" "${1:-HEAD}"
+git log -z `+logFlags+` --format='%H%n%an <%ae>%n%at%n%B' FETCH_HEAD > `+gitOutputDir+`/output`,
+		[]string{ref},
+		// the history of a commit never changes
+		gitSHA.MatchString(ref),
+		fmt.Sprintf("git log %s %s", url, ref),
+		p, platform, svcs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitLog(out)
+}
+
+// gitOutput runs the script with the given arguments in the git image and
+// returns what it writes to the output file.
+func gitOutput(
+	ctx context.Context,
+	gw bkgw.Client,
+	url string,
+	opts GitFetchOpts,
+	script string,
+	args []string,
+	cache bool,
+	name string,
+	p pipeline.Path,
+	platform specs.Platform,
+	svcs ServiceBindings,
+) ([]byte, error) {
+	runOpts := gitRunOpts(url, opts)
+	runOpts = append(runOpts,
+		llb.Args(append([]string{"sh", "-c", gitAuthScript(opts) + script, "sh"}, args...)),
+		llb.WithCustomName(name),
+	)
+
+	if !cache {
+		runOpts = append(runOpts, llb.IgnoreCache)
+	}
+
+	st := toolsBase(gw).Run(runOpts...).
+		AddMount(gitOutputDir, llb.Scratch())
+
+	file, err := NewFileSt(ctx, st, "output", p, platform, svcs)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.Contents(ctx, gw)
+}
+
+// parseLsRemote parses the output of `git ls-remote`, using the peeled SHA
+// of annotated tags.
+func parseLsRemote(out []byte) ([]GitRemoteRef, error) {
+	refs := []GitRemoteRef{}
+	indices := map[string]int{}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+
+		sha, name, found := strings.Cut(line, "\t")
+		if !found {
+			return nil, fmt.Errorf("invalid ls-remote output: %q", line)
+		}
+
+		if tag, peeled := strings.CutSuffix(name, "^{}"); peeled {
+			if i, found := indices[tag]; found {
+				refs[i].SHA = sha
+				continue
+			}
+
+			name = tag
+		}
+
+		indices[name] = len(refs)
+		refs = append(refs, GitRemoteRef{
+			Name: name,
+			SHA:  sha,
+		})
+	}
+
+	return refs, nil
+}
+
+// parseGitLog parses the output of `git log -z` with the format used by
+// GitLog.
+func parseGitLog(out []byte) ([]GitCommit, error) {
+	commits := []GitCommit{}
+
+	for _, record := range strings.Split(string(out), "\x00") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(strings.TrimPrefix(record, "\n"), "\n", 4)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid log output: %q", record)
+		}
+
+		timestamp, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit timestamp %q: %w", fields[2], err)
+		}
+
+		var message string
+		if len(fields) == 4 {
+			message = strings.TrimSuffix(fields[3], "\n")
+		}
+
+		commits = append(commits, GitCommit{
+			SHA:       fields[0],
+			Author:    fields[1],
+			Message:   message,
+			Timestamp: timestamp,
+		})
+	}
+
+	return commits, nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLsRemote(t *testing.T) {
+	t.Parallel()
+
+	refs, err := parseLsRemote([]byte(`406e0c96f0ca4c4ad854821cbde0e36b723711a8	HEAD
+406e0c96f0ca4c4ad854821cbde0e36b723711a8	refs/heads/main
+28376ad298ec24e1914d9f4a8bfcaa1c9a27c8a6	refs/tags/v0.1.0
+b6315d8f2810962c601af73f86831f6866ea798b	refs/tags/v0.2.0
+a2c141a10fed2ccb25d1691a2ee1b5f7bf406602	refs/tags/v0.2.0^{}
+`))
+	require.NoError(t, err)
+	require.Equal(t, []GitRemoteRef{
+		{Name: "HEAD", SHA: "406e0c96f0ca4c4ad854821cbde0e36b723711a8"},
+		{Name: "refs/heads/main", SHA: "406e0c96f0ca4c4ad854821cbde0e36b723711a8"},
+		{Name: "refs/tags/v0.1.0", SHA: "28376ad298ec24e1914d9f4a8bfcaa1c9a27c8a6"},
+		{Name: "refs/tags/v0.2.0", SHA: "a2c141a10fed2ccb25d1691a2ee1b5f7bf406602"},
+	}, refs)
+
+	refs, err = parseLsRemote([]byte(""))
+	require.NoError(t, err)
+	require.Empty(t, refs)
+}
+
+func TestParseGitLog(t *testing.T) {
+	t.Parallel()
+
+	commits, err := parseGitLog([]byte("406e0c96f0ca4c4ad854821cbde0e36b723711a8\nA <a@example.com>\n1792361099\nsubject\n\nbody\n\x00" +
+		"a2c141a10fed2ccb25d1691a2ee1b5f7bf406602\nB <b@example.com>\n1792361000\ninit\n\x00"))
+	require.NoError(t, err)
+	require.Equal(t, []GitCommit{
+		{
+			SHA:       "406e0c96f0ca4c4ad854821cbde0e36b723711a8",
+			Author:    "A <a@example.com>",
+			Message:   "subject\n\nbody",
+			Timestamp: 1792361099,
+		},
+		{
+			SHA:       "a2c141a10fed2ccb25d1691a2ee1b5f7bf406602",
+			Author:    "B <b@example.com>",
+			Message:   "init",
+			Timestamp: 1792361000,
+		},
+	}, commits)
+}
+
+func TestGitRemoteURL(t *testing.T) {
+	t.Parallel()
+
+	for url, expected := range map[string]string{
+		"github.com/dagger/dagger":         "https://github.com/dagger/dagger",
+		"https://github.com/dagger/dagger": "https://github.com/dagger/dagger",
+		"git://example.com/repo":           "git://example.com/repo",
+		"ssh://git@example.com/repo":       "ssh://git@example.com/repo",
+		"git@github.com:dagger/dagger.git": "git@github.com:dagger/dagger.git",
+	} {
+		require.Equal(t, expected, gitRemoteURL(url), url)
+	}
+}
@@ -2,27 +2,40 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/dagger/dagger/core/pipeline"
 	bkclient "github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/client/llb"
 	bkgw "github.com/moby/buildkit/frontend/gateway/client"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	fstypes "github.com/tonistiigi/fsutil/types"
 	"github.com/vito/progrock"
 )
 
 type Host struct {
 	Workdir   string
 	DisableRW bool
+
+	// onChange is called with the path of a directory loaded with watch
+	// enabled whenever anything beneath it changes.
+	onChange func(string)
+
+	watcher  *hostWatcher
+	watcherL sync.Mutex
 }
 
-func NewHost(workdir string, disableRW bool) *Host {
+func NewHost(workdir string, disableRW bool, onChange func(string)) *Host {
 	return &Host{
 		Workdir:   workdir,
 		DisableRW: disableRW,
+		onChange:  onChange,
 	}
 }
 
@@ -35,7 +48,7 @@ type CopyFilter struct {
 	Include []string
 }
 
-func (host *Host) Directory(ctx context.Context, dirPath string, p pipeline.Path, platform specs.Platform, filter CopyFilter) (*Directory, error) {
+func (host *Host) Directory(ctx context.Context, dirPath string, p pipeline.Path, platform specs.Platform, filter CopyFilter, gitignore, watch bool) (*Directory, error) {
 	if host.DisableRW {
 		return nil, ErrHostRWDisabled
 	}
@@ -63,21 +76,46 @@ func (host *Host) Directory(ctx context.Context, dirPath string, p pipeline.Path
 
 	localID := fmt.Sprintf("host:%s", absPath)
 
+	excludes := filter.Exclude
+	if gitignore {
+		ignored, err := loadGitignore(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("load .gitignore: %w", err)
+		}
+
+		excludes = append(ignored, excludes...)
+	}
+
+	uniqueID := localID
+	if watch {
+		generation, err := host.watch(absPath, CopyFilter{Include: filter.Include, Exclude: excludes})
+		if err != nil {
+			return nil, fmt.Errorf("watch: %w", err)
+		}
+
+		// re-sync once the directory has changed, rather than reusing the result
+		// from earlier in the session
+		if generation > 0 {
+			uniqueID = fmt.Sprintf("%s@%d", localID, generation)
+		}
+	}
+
 	localOpts := []llb.LocalOption{
 		// Custom name
 		llb.WithCustomNamef("upload %s", absPath),
 
-		// synchronize concurrent filesyncs for the same path
+		// synchronize concurrent filesyncs for the same path, and only transfer
+		// the differences from the last sync
 		llb.SharedKeyHint(localID),
 
 		// make the LLB stable so we can test invariants like:
 		//
 		//   workdir == directory(".")
-		llb.LocalUniqueID(localID),
+		llb.LocalUniqueID(uniqueID),
 	}
 
-	if len(filter.Exclude) > 0 {
-		localOpts = append(localOpts, llb.ExcludePatterns(filter.Exclude))
+	if len(excludes) > 0 {
+		localOpts = append(localOpts, llb.ExcludePatterns(excludes))
 	}
 
 	if len(filter.Include) > 0 {
@@ -106,6 +144,38 @@ func (host *Host) Directory(ctx context.Context, dirPath string, p pipeline.Path
 	return NewDirectory(ctx, defPB, "", p, platform, nil), nil
 }
 
+// watch starts watching the paths of the directory matched by the filter for
+// changes, returning the number of changes seen beneath it so far.
+func (host *Host) watch(absPath string, filter CopyFilter) (int, error) {
+	host.watcherL.Lock()
+	if host.watcher == nil {
+		watcher, err := newHostWatcher(host.onChange)
+		if err != nil {
+			host.watcherL.Unlock()
+			return 0, err
+		}
+
+		host.watcher = watcher
+	}
+	watcher := host.watcher
+	host.watcherL.Unlock()
+
+	return watcher.Watch(absPath, filter)
+}
+
+// Close stops watching the host's directories, at the end of the session.
+func (host *Host) Close() error {
+	host.watcherL.Lock()
+	defer host.watcherL.Unlock()
+
+	if host.watcher == nil {
+		return nil
+	}
+	err := host.watcher.Close()
+	host.watcher = nil
+	return err
+}
+
 func (host *Host) File(ctx context.Context, path string, p pipeline.Path, platform specs.Platform) (*File, error) {
 	if host.DisableRW {
 		return nil, ErrHostRWDisabled
@@ -210,6 +280,153 @@ func (host *Host) Export(
 	return err
 }
 
+// exportChanges compares tree, as listed by walkRef, with the contents of dest
+// on the host and returns the changes exporting it would make. Paths which
+// only exist on the host are reported as deleted only if wipe is set.
+func (host *Host) exportChanges(dest string, tree map[string]*fstypes.Stat, wipe bool, readFile treeReader) ([]Change, error) {
+	if host.DisableRW {
+		return nil, ErrHostRWDisabled
+	}
+
+	hostTree, err := walkHostDir(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diffTrees(hostTree, tree, func(p string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dest, filepath.FromSlash(p)))
+	}, readFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if wipe {
+		return changes, nil
+	}
+
+	// exporting without wipe leaves any other paths in place
+	kept := []Change{}
+	for _, change := range changes {
+		if change.Kind != ChangeKindDeleted {
+			kept = append(kept, change)
+		}
+	}
+
+	return kept, nil
+}
+
+// wipe removes everything beneath dest on the host that is not present in
+// tree.
+func (host *Host) wipe(dest string, tree map[string]*fstypes.Stat) error {
+	if host.DisableRW {
+		return ErrHostRWDisabled
+	}
+
+	stale, err := staleHostPaths(dest, tree)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range stale {
+		if err := os.RemoveAll(filepath.Join(dest, filepath.FromSlash(p))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// staleHostPaths returns the paths beneath dest on the host which are not
+// present in tree. Stale directories are returned without their contents.
+func staleHostPaths(dest string, tree map[string]*fstypes.Stat) ([]string, error) {
+	stale := []string{}
+	err := filepath.WalkDir(dest, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dest && errors.Is(err, os.ErrNotExist) {
+				// nothing exported yet
+				return nil
+			}
+			return err
+		}
+
+		if p == dest {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dest, p)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if _, found := tree[rel]; found {
+			return nil
+		}
+
+		stale = append(stale, rel)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+// walkHostDir recursively lists everything beneath dir on the host, in the
+// same form as walkRef.
+func walkHostDir(dir string) (map[string]*fstypes.Stat, error) {
+	stats := map[string]*fstypes.Stat{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && errors.Is(err, os.ErrNotExist) {
+				// treat a missing directory as empty
+				return nil
+			}
+			return err
+		}
+
+		if p == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		stat := &fstypes.Stat{
+			Path:  d.Name(),
+			Mode:  uint32(info.Mode()),
+			Size_: info.Size(),
+		}
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			stat.Linkname, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		stats[filepath.ToSlash(rel)] = stat
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 func (host *Host) NormalizeDest(dest string) (string, error) {
 	if filepath.IsAbs(dest) {
 		return dest, nil
@@ -0,0 +1,319 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dagger/dagger/core/pipeline"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/moby/buildkit/client/llb"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/vito/progrock"
+)
+
+// GitRepositoryPath returns the root of the working tree of the git
+// repository containing the given path on the host.
+func (host *Host) GitRepositoryPath(repoPath string) (string, error) {
+	if host.DisableRW {
+		return "", ErrHostRWDisabled
+	}
+
+	var absPath string
+	if filepath.IsAbs(repoPath) {
+		absPath = repoPath
+	} else {
+		absPath = filepath.Join(host.Workdir, repoPath)
+
+		if !strings.HasPrefix(absPath, host.Workdir) {
+			return "", fmt.Errorf("path %q escapes workdir; use an absolute path instead", repoPath)
+		}
+	}
+
+	absPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return "", fmt.Errorf("eval symlinks: %w", err)
+	}
+
+	repo, err := git.PlainOpenWithOptions(absPath, &git.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("open git repository %s: %w", absPath, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	return worktree.Filesystem.Root(), nil
+}
+
+// HostGitRefs lists the refs in the repository on the host matching any of
+// the patterns, or every ref if there are none, in the same way as GitLsRemote
+// does for remote repositories.
+func HostGitRefs(repoPath string, patterns []string) ([]GitRemoteRef, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	matchers := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		matchers[i] = gitTailMatcher(pattern)
+	}
+
+	iter, err := repo.Storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []plumbing.ReferenceName{plumbing.HEAD}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() != plumbing.HEAD {
+			names = append(names, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// like ls-remote, list HEAD first and the rest by name
+	sort.Slice(names[1:], func(i, j int) bool {
+		return names[1+i] < names[1+j]
+	})
+
+	refs := []GitRemoteRef{}
+	for _, name := range names {
+		if len(matchers) > 0 && !anyMatch(matchers, name.String()) {
+			continue
+		}
+
+		ref, err := storer.ResolveReference(repo.Storer, name)
+		if err != nil {
+			// e.g. HEAD of a repository with no commits yet
+			continue
+		}
+
+		sha := ref.Hash()
+		if tag, err := repo.TagObject(sha); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil, err
+			}
+			sha = commit.Hash
+		}
+
+		refs = append(refs, GitRemoteRef{
+			Name: name.String(),
+			SHA:  sha.String(),
+		})
+	}
+
+	return refs, nil
+}
+
+// gitTailMatcher returns a matcher for ref names which match the pattern in
+// the same way as `git ls-remote`, i.e. if the pattern matches the whole name
+// or its last path components. Wildcards match slashes too.
+func gitTailMatcher(pattern string) *regexp.Regexp {
+	expr := ""
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr += ".*"
+		case '?':
+			expr += "."
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr += regexp.QuoteMeta(pattern[i:])
+				i = len(pattern)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr += "[" + class + "]"
+			i += 1 + end
+		default:
+			expr += regexp.QuoteMeta(string(c))
+		}
+	}
+
+	re, err := regexp.Compile("^(.*/)?" + expr + "$")
+	if err != nil {
+		// an invalid class; match it literally instead
+		re = regexp.MustCompile("^(.*/)?" + regexp.QuoteMeta(pattern) + "$")
+	}
+	return re
+}
+
+func anyMatch(matchers []*regexp.Regexp, name string) bool {
+	for _, matcher := range matchers {
+		if matcher.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostGitResolve returns the SHA of the commit the ref points to in the
+// repository on the host. An empty ref resolves HEAD.
+func HostGitResolve(repoPath, ref string) (string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := resolveHostGitRef(repo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+func resolveHostGitRef(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolve ref %q: %w", ref, err)
+	}
+
+	return *hash, nil
+}
+
+// HostGitLog lists up to limit commits of history from the ref in the
+// repository on the host, or the entire history if limit is zero, newest
+// first, in the same way as GitLog does for remote repositories.
+func HostGitLog(repoPath, ref string, limit int) ([]GitCommit, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := resolveHostGitRef(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := repo.Log(&git.LogOptions{
+		From:  hash,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []GitCommit{}
+	err = iter.ForEach(func(commit *object.Commit) error {
+		if limit > 0 && len(commits) >= limit {
+			return storer.ErrStop
+		}
+		commits = append(commits, hostGitCommit(commit))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+func hostGitCommit(commit *object.Commit) GitCommit {
+	return GitCommit{
+		SHA:       commit.Hash.String(),
+		Author:    fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email),
+		Message:   strings.TrimSuffix(commit.Message, "\n"),
+		Timestamp: int(commit.Author.When.Unix()),
+	}
+}
+
+// HostGitDirty reports whether the working tree of the repository on the
+// host has any uncommitted changes, including untracked files which aren't
+// ignored.
+func HostGitDirty(repoPath string) (bool, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return false, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+
+	return !status.IsClean(), nil
+}
+
+// hostGitDirFiles are the parts of a .git directory which are needed to read
+// the repository's objects, leaving out e.g. the index, hooks and reflogs.
+var hostGitDirFiles = []string{"HEAD", "config", "packed-refs", "refs", "objects", "shallow"}
+
+// GitTree returns the files of the commit with the given SHA in the
+// repository on the host. The .git directory's objects are synced into the
+// engine, which only transfers what has changed since the last sync, and the
+// tree is extracted with `git archive`.
+func (host *Host) GitTree(ctx context.Context, gw bkgw.Client, repoPath, sha string, p pipeline.Path, platform specs.Platform) (*Directory, error) {
+	if host.DisableRW {
+		return nil, ErrHostRWDisabled
+	}
+
+	gitDir := filepath.Join(repoPath, ".git")
+	if fi, err := os.Stat(gitDir); err != nil || !fi.IsDir() {
+		return nil, errors.New("only repositories with a .git directory are supported; worktrees and submodules are not")
+	}
+
+	// Create a sub-pipeline to group llb.Local instructions
+	pipelineName := fmt.Sprintf("host.gitRepository %s", repoPath)
+	ctx, subRecorder := progrock.WithGroup(ctx, pipelineName, progrock.Weak())
+
+	localID := fmt.Sprintf("host:%s", gitDir)
+
+	gitDirSt := llb.Local(gitDir,
+		llb.WithCustomNamef("upload %s", gitDir),
+		llb.IncludePatterns(hostGitDirFiles),
+		llb.SharedKeyHint(localID),
+		llb.LocalUniqueID(localID),
+	)
+
+	// extract the committed tree without touching the repository's index
+	st := toolsBase(gw).Run(
+		llb.Args([]string{"sh", "-c", `git --git-dir=/git archive "$1" | tar -x -C ` + gitCheckoutDir, "sh", sha}),
+		llb.AddMount("/git", gitDirSt, llb.Readonly),
+		llb.WithCustomNamef("git archive %s %s", repoPath, sha),
+	).AddMount(gitCheckoutDir, llb.Scratch())
+
+	def, err := st.Marshal(ctx, llb.Platform(platform))
+	if err != nil {
+		return nil, err
+	}
+
+	defPB := def.ToPB()
+
+	// associate vertexes to the 'host.gitRepository' sub-pipeline
+	recordVertexes(subRecorder, defPB)
+
+	return NewDirectory(ctx, defPB, "", p, platform, nil), nil
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostGit(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(message string, when time.Time) plumbing.Hash {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(message), 0600))
+		_, err := worktree.Add("README.md")
+		require.NoError(t, err)
+		sha, err := worktree.Commit(message+"\n", &git.CommitOptions{
+			Author: &object.Signature{Name: "A", Email: "a@example.com", When: when},
+		})
+		require.NoError(t, err)
+		return sha
+	}
+
+	first := commit("first", time.Unix(1700000000, 0))
+	second := commit("second", time.Unix(1700000100, 0))
+
+	tag, err := repo.CreateTag("v0.1.0", first, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "A", Email: "a@example.com", When: time.Unix(1700000000, 0)},
+		Message: "v0.1.0",
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, first, tag.Hash())
+
+	t.Run("refs", func(t *testing.T) {
+		refs, err := HostGitRefs(dir, nil)
+		require.NoError(t, err)
+		require.Equal(t, []GitRemoteRef{
+			{Name: "HEAD", SHA: second.String()},
+			{Name: "refs/heads/master", SHA: second.String()},
+			// annotated tags are resolved to their commit
+			{Name: "refs/tags/v0.1.0", SHA: first.String()},
+		}, refs)
+
+		refs, err = HostGitRefs(dir, []string{"master"})
+		require.NoError(t, err)
+		require.Equal(t, []GitRemoteRef{
+			{Name: "refs/heads/master", SHA: second.String()},
+		}, refs)
+
+		refs, err = HostGitRefs(dir, []string{"refs/tags/v*"})
+		require.NoError(t, err)
+		require.Equal(t, []GitRemoteRef{
+			{Name: "refs/tags/v0.1.0", SHA: first.String()},
+		}, refs)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		for ref, sha := range map[string]plumbing.Hash{
+			"":                  second,
+			"master":            second,
+			"v0.1.0":            first,
+			"refs/tags/v0.1.0":  first,
+			first.String():      first,
+			first.String()[:12]: first,
+		} {
+			actual, err := HostGitResolve(dir, ref)
+			require.NoError(t, err, ref)
+			require.Equal(t, sha.String(), actual, ref)
+		}
+
+		_, err := HostGitResolve(dir, "missing")
+		require.Error(t, err)
+	})
+
+	t.Run("log", func(t *testing.T) {
+		commits, err := HostGitLog(dir, "", 0)
+		require.NoError(t, err)
+		require.Equal(t, []GitCommit{
+			{SHA: second.String(), Author: "A <a@example.com>", Message: "second", Timestamp: 1700000100},
+			{SHA: first.String(), Author: "A <a@example.com>", Message: "first", Timestamp: 1700000000},
+		}, commits)
+
+		commits, err = HostGitLog(dir, "master", 1)
+		require.NoError(t, err)
+		require.Len(t, commits, 1)
+		require.Equal(t, second.String(), commits[0].SHA)
+	})
+
+	t.Run("dirty", func(t *testing.T) {
+		dirty, err := HostGitDirty(dir)
+		require.NoError(t, err)
+		require.False(t, dirty)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("untracked"), 0600))
+
+		dirty, err = HostGitDirty(dir)
+		require.NoError(t, err)
+		require.True(t, dirty)
+	})
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/moby/buildkit/client/llb"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/opencontainers/go-digest"
+)
+
+// HTTPHeader is a header sent with an HTTP request.
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HTTPFetchOpts configures how a file is fetched over HTTP.
+type HTTPFetchOpts struct {
+	// Checksum is verified against the content of the file, if set.
+	Checksum digest.Digest
+
+	// Headers are sent with the request, along with an Authorization header
+	// with the value of AuthHeader if set.
+	Headers    []HTTPHeader
+	AuthHeader SecretID
+
+	// Permissions of the file, between 0 and 0777. Defaults to 0600.
+	Permissions int
+}
+
+// mode returns the file mode of the fetched file.
+func (opts HTTPFetchOpts) mode() os.FileMode {
+	if opts.Permissions == 0 {
+		return 0o600
+	}
+	return os.FileMode(opts.Permissions)
+}
+
+// httpOutputDir is where the file is downloaded to in the HTTP image.
+const httpOutputDir = "/out"
+
+// HTTPFetch fetches the URL into a file with the given name.
+//
+// Buildkit's HTTP source is used when possible. It can't send headers, so
+// otherwise the file is fetched with curl in a container instead.
+func HTTPFetch(gw bkgw.Client, url, filename string, opts HTTPFetchOpts) (llb.State, error) {
+	if opts.Checksum != "" {
+		if err := opts.Checksum.Validate(); err != nil {
+			return llb.State{}, fmt.Errorf("invalid checksum %q: %w", opts.Checksum, err)
+		}
+		// the curl image only has sha256sum and sha512sum, so reject the rest
+		// on both paths alike
+		switch opts.Checksum.Algorithm() {
+		case digest.SHA256, digest.SHA512:
+		default:
+			return llb.State{}, fmt.Errorf("invalid checksum %q: algorithm must be sha256 or sha512", opts.Checksum)
+		}
+	}
+
+	if len(opts.Headers) == 0 && opts.AuthHeader == "" {
+		httpOpts := []llb.HTTPOption{llb.Filename(filename)}
+		if opts.Checksum != "" {
+			httpOpts = append(httpOpts, llb.Checksum(opts.Checksum))
+		}
+		if opts.Permissions != 0 {
+			httpOpts = append(httpOpts, llb.Chmod(opts.mode()))
+		}
+		return llb.HTTP(url, httpOpts...), nil
+	}
+
+	args := []string{"sh", "-c", httpFetchScript(opts), "sh"}
+	for _, header := range opts.Headers {
+		args = append(args, "-H", header.Name+": "+header.Value)
+	}
+
+	runOpts := []llb.RunOption{
+		llb.Args(args),
+		llb.AddEnv("HTTP_URL", url),
+		llb.AddEnv("HTTP_OUTPUT", path.Join(httpOutputDir, filename)),
+		llb.WithCustomNamef("fetch %s", url),
+	}
+
+	if opts.AuthHeader != "" {
+		runOpts = append(runOpts, llb.AddSecret("HTTP_AUTH_HEADER",
+			llb.SecretID(opts.AuthHeader.String()),
+			llb.SecretAsEnv(true),
+		))
+	}
+
+	// the content can only be reused if it's verified
+	if opts.Checksum == "" {
+		runOpts = append(runOpts, llb.IgnoreCache)
+	}
+
+	return toolsBase(gw).Run(runOpts...).
+		AddMount(httpOutputDir, llb.Scratch()), nil
+}
+
+// httpFetchScript returns a script which downloads $HTTP_URL to $HTTP_OUTPUT,
+// passing its arguments to curl.
+func httpFetchScript(opts HTTPFetchOpts) string {
+	script := "set -e\n"
+
+	if opts.AuthHeader != "" {
+		script += `curl -fsSL -o "$HTTP_OUTPUT" -H "Authorization: $HTTP_AUTH_HEADER" "$@" "$HTTP_URL"` + "\n"
+	} else {
+		script += `curl -fsSL -o "$HTTP_OUTPUT" "$@" "$HTTP_URL"` + "\n"
+	}
+
+	if opts.Checksum != "" {
+		script += fmt.Sprintf(`echo "%s  $HTTP_OUTPUT" | %ssum -c -s -`+"\n", opts.Checksum.Encoded(), opts.Checksum.Algorithm())
+	}
+
+	script += fmt.Sprintf(`chmod %04o "$HTTP_OUTPUT"`+"\n", opts.mode())
+
+	return script
+}
@@ -0,0 +1,363 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/dockerignore"
+	bkgw "github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/patternmatcher"
+)
+
+const (
+	gitignoreName    = ".gitignore"
+	dockerignoreName = ".dockerignore"
+)
+
+// gitignorePattern is a single pattern parsed from a .gitignore file.
+type gitignorePattern struct {
+	// Pattern is relative to the directory containing the .gitignore file.
+	Pattern string
+
+	// Anchored patterns only match relative to the .gitignore file's directory,
+	// rather than at any depth beneath it.
+	Anchored bool
+
+	// DirOnly patterns (i.e. with a trailing slash) only match directories.
+	DirOnly bool
+
+	// Negate re-includes paths excluded by an earlier pattern.
+	Negate bool
+}
+
+// parseGitignore parses the contents of a .gitignore file.
+func parseGitignore(content []byte) []gitignorePattern {
+	patterns := []gitignorePattern{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pattern gitignorePattern
+		if strings.HasPrefix(line, "!") {
+			pattern.Negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			pattern.DirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// a slash anywhere but the end anchors the pattern to the .gitignore's
+		// directory
+		pattern.Anchored = strings.Contains(line, "/")
+		pattern.Pattern = strings.TrimPrefix(line, "/")
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
+
+// excludePattern returns the pattern in the syntax accepted by
+// llb.ExcludePatterns, relative to base.
+func (pattern gitignorePattern) excludePattern(base string) string {
+	p := pattern.Pattern
+	if !pattern.Anchored {
+		p = "**/" + p
+	}
+
+	if base != "" {
+		p = path.Join(base, p)
+	}
+
+	if pattern.Negate {
+		p = "!" + p
+	}
+
+	return p
+}
+
+// under returns the pattern relative to dir, a subdirectory of the
+// .gitignore file's directory given as its path components, or false if it
+// can't match anything beneath dir.
+func (pattern gitignorePattern) under(dir []string) (gitignorePattern, bool) {
+	if !pattern.Anchored {
+		return pattern, true
+	}
+
+	components := strings.Split(pattern.Pattern, "/")
+	for i, name := range dir {
+		if i == len(components) {
+			break
+		}
+
+		if components[i] == "**" {
+			// matches any number of directories, so it still applies beneath
+			// dir
+			pattern.Pattern = strings.Join(components[i:], "/")
+			return pattern, true
+		}
+
+		if ok, err := path.Match(components[i], name); err != nil || !ok {
+			return gitignorePattern{}, false
+		}
+	}
+
+	if len(components) <= len(dir) {
+		// matches dir or one of its parents, so everything beneath it
+		pattern.Pattern = "**"
+		pattern.DirOnly = false
+		return pattern, true
+	}
+
+	pattern.Pattern = strings.Join(components[len(dir):], "/")
+	return pattern, true
+}
+
+// gitignoreExcludes accumulates the exclude patterns for the patterns of
+// .gitignore files.
+//
+// Exclude patterns can't tell files from directories, so directory-only
+// patterns aren't excluded as such. Instead, each directory they match is
+// excluded by its exact path once found. Git never looks beneath an ignored
+// directory, so these are excluded after every other pattern, where nothing
+// can re-include paths within them.
+type gitignoreExcludes struct {
+	// files are the exclude patterns which match files and directories alike
+	files []string
+
+	// dirs are the exclude patterns which match directories, including the
+	// directory-only ones
+	dirs []string
+
+	// ignoredDirs are the directories only matched by directory-only patterns
+	ignoredDirs []string
+
+	fileMatcher *patternmatcher.PatternMatcher
+	dirMatcher  *patternmatcher.PatternMatcher
+}
+
+func (excludes *gitignoreExcludes) add(patterns []gitignorePattern, base string) error {
+	for _, pattern := range patterns {
+		p := pattern.excludePattern(base)
+		excludes.dirs = append(excludes.dirs, p)
+		if !pattern.DirOnly {
+			excludes.files = append(excludes.files, p)
+		}
+	}
+
+	var err error
+	excludes.fileMatcher, err = patternmatcher.New(excludes.files)
+	if err != nil {
+		return err
+	}
+	excludes.dirMatcher, err = patternmatcher.New(excludes.dirs)
+	return err
+}
+
+// ignoredDir reports whether the directory is ignored, recording it if it's
+// only ignored by directory-only patterns.
+func (excludes *gitignoreExcludes) ignoredDir(dir string) (bool, error) {
+	ignored, err := excludes.dirMatcher.MatchesOrParentMatches(dir)
+	if err != nil || !ignored {
+		return false, err
+	}
+
+	excluded, err := excludes.fileMatcher.MatchesOrParentMatches(dir)
+	if err != nil {
+		return false, err
+	}
+	if !excluded {
+		excludes.ignoredDirs = append(excludes.ignoredDirs, escapePattern(dir))
+	}
+
+	return true, nil
+}
+
+func (excludes *gitignoreExcludes) patterns() []string {
+	return append(append([]string{}, excludes.files...), excludes.ignoredDirs...)
+}
+
+// escapePattern escapes the special characters of exclude patterns in p, so
+// that it only matches itself.
+func escapePattern(p string) string {
+	var escaped strings.Builder
+	for _, c := range p {
+		if strings.ContainsRune(`*?[]\`, c) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
+
+// loadGitignore translates the patterns in any .gitignore files found within
+// root on the host into exclude patterns relative to root.
+//
+// Patterns in .gitignore files above root, up to the root of its git
+// repository, are included too, relative to their own directory.
+func loadGitignore(root string) ([]string, error) {
+	parentPatterns, err := loadParentGitignore(root)
+	if err != nil {
+		return nil, err
+	}
+
+	excludes := &gitignoreExcludes{}
+	if err := excludes.add(parentPatterns, ""); err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+
+		if rel != "" {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			// git doesn't look for .gitignore files in ignored directories
+			ignored, err := excludes.ignoredDir(rel)
+			if err != nil {
+				return err
+			}
+			if ignored {
+				return filepath.SkipDir
+			}
+		}
+
+		content, err := os.ReadFile(filepath.Join(p, gitignoreName))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return excludes.add(parseGitignore(content), rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return excludes.patterns(), nil
+}
+
+// loadParentGitignore returns the patterns in .gitignore files in the parent
+// directories of dir, up to the root of its git repository, relative to dir.
+func loadParentGitignore(dir string) ([]gitignorePattern, error) {
+	parents := []string{}
+	for cur := dir; ; {
+		if _, err := os.Stat(filepath.Join(cur, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			// not in a git repository
+			return []gitignorePattern{}, nil
+		}
+
+		parents = append(parents, parent)
+		cur = parent
+	}
+
+	patterns := []gitignorePattern{}
+
+	// outermost first, so that nested .gitignore files take precedence
+	for i := len(parents) - 1; i >= 0; i-- {
+		content, err := os.ReadFile(filepath.Join(parents[i], gitignoreName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := filepath.Rel(parents[i], dir)
+		if err != nil {
+			return nil, err
+		}
+		components := strings.Split(filepath.ToSlash(rel), "/")
+
+		for _, pattern := range parseGitignore(content) {
+			if pattern, ok := pattern.under(components); ok {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+
+	return patterns, nil
+}
+
+// loadDockerignore reads the exclude patterns for a Docker build context from
+// the directory, in the same way as `docker build`: a .dockerignore file next
+// to the Dockerfile named after it takes precedence over the .dockerignore
+// file at the root of the context.
+func loadDockerignore(ctx context.Context, gw bkgw.Client, context *Directory, dockerfile string) ([]string, error) {
+	res, err := gw.Solve(ctx, bkgw.SolveRequest{
+		Definition: context.LLB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := res.SingleRef()
+	if err != nil {
+		return nil, err
+	}
+
+	// empty directory, i.e. llb.Scratch()
+	if ref == nil {
+		return nil, nil
+	}
+
+	for _, name := range []string{
+		path.Join(context.Dir, dockerfile) + dockerignoreName,
+		path.Join(context.Dir, dockerignoreName),
+	} {
+		content, err := ref.ReadFile(ctx, bkgw.ReadRequest{
+			Filename: name,
+		})
+		if err != nil {
+			// not found; docker build ignores any error here too
+			continue
+		}
+
+		return dockerignore.ReadAll(bytes.NewReader(content))
+	}
+
+	return nil, nil
+}
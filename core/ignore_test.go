@@ -0,0 +1,123 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/patternmatcher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitignore(t *testing.T) {
+	t.Parallel()
+
+	patterns := parseGitignore([]byte(`
+# comment
+*.log
+node_modules/
+/build
+docs/_site
+!keep.log
+\#not-a-comment
+`))
+
+	excludes := []string{}
+	dirOnly := []string{}
+	for _, pattern := range patterns {
+		excludes = append(excludes, pattern.excludePattern("sub"))
+		if pattern.DirOnly {
+			dirOnly = append(dirOnly, pattern.Pattern)
+		}
+	}
+	require.Equal(t, []string{"node_modules"}, dirOnly)
+
+	require.Equal(t, []string{
+		"sub/**/*.log",
+		"sub/**/node_modules",
+		"sub/build",
+		"sub/docs/_site",
+		"!sub/**/keep.log",
+		"sub/**/#not-a-comment",
+	}, excludes)
+}
+
+func TestLoadGitignore(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	root := filepath.Join(repo, "app")
+
+	write := func(p, content string) {
+		p = filepath.Join(repo, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+	write(".gitignore", "*.log\n/app/anchored\n/other/anchored\napp/*/generated/\n")
+	write("app/.gitignore", "/dist\nbuild/\n")
+	write("app/src/.gitignore", "*.tmp\n")
+	write("app/dist/.gitignore", "!ignored-anyway\n")
+	write("app/build/out", "")
+	write("app/src/build", "a file, not a directory")
+	write("app/pkg/generated/out", "")
+	write("app/generated/out", "")
+
+	excludes, err := loadGitignore(root)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"**/*.log",
+		// anchored to the parent's directory
+		"anchored",
+		"dist",
+		"src/**/*.tmp",
+		// only directories match directory-only patterns
+		"build",
+		"pkg/generated",
+	}, excludes)
+
+	matcher, err := patternmatcher.New(excludes)
+	require.NoError(t, err)
+	for p, excluded := range map[string]bool{
+		"anchored":          true,
+		"other/anchored":    false,
+		"build/out":         true,
+		"src/build":         false,
+		"pkg/generated/out": true,
+		"generated/out":     false,
+	} {
+		actual, err := matcher.MatchesOrParentMatches(p)
+		require.NoError(t, err)
+		require.Equal(t, excluded, actual, p)
+	}
+}
+
+func TestGitignorePatternUnder(t *testing.T) {
+	t.Parallel()
+
+	for _, example := range []struct {
+		pattern  string
+		dir      []string
+		expected string
+		ok       bool
+	}{
+		{"*.log", []string{"app"}, "**/*.log", true},
+		{"/app/dist", []string{"app"}, "dist", true},
+		{"/*/dist", []string{"app"}, "dist", true},
+		{"/other/dist", []string{"app"}, "", false},
+		{"/app", []string{"app"}, "**", true},
+		{"/app", []string{"app", "sub"}, "**", true},
+		{"app/**/dist", []string{"app", "sub"}, "**/dist", true},
+		{"app/sub/dist", []string{"app"}, "sub/dist", true},
+	} {
+		patterns := parseGitignore([]byte(example.pattern))
+		require.Len(t, patterns, 1)
+
+		pattern, ok := patterns[0].under(example.dir)
+		require.Equal(t, example.ok, ok, example.pattern)
+		if ok {
+			require.Equal(t, example.expected, pattern.excludePattern(""), example.pattern)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package core
 import (
 	"testing"
 
+	"dagger.io/dagger"
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/internal/testutil"
+	"github.com/moby/buildkit/identity"
 	"github.com/stretchr/testify/require"
 )
 
@@ -65,3 +67,142 @@ func TestCacheVolume(t *testing.T) {
 		require.NotEqual(t, idOrig, idDiff)
 	})
 }
+
+func TestCacheVolumeManagement(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	cache := c.CacheVolume(identity.NewID())
+
+	size, err := cache.Size(ctx)
+	require.NoError(t, err)
+	require.Zero(t, size)
+
+	_, err = c.Container().
+		From("alpine:3.16.2").
+		WithMountedCache("/cache", cache, dagger.ContainerWithMountedCacheOpts{
+			Sharing: dagger.Private,
+		}).
+		WithExec([]string{"dd", "if=/dev/zero", "of=/cache/file", "bs=1M", "count=1"}).
+		Sync(ctx)
+	require.NoError(t, err)
+
+	checksum, err := cache.Checksum(ctx)
+	require.NoError(t, err)
+
+	t.Run("inspect", func(t *testing.T) {
+		size, err := cache.Size(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, size, 1024*1024)
+
+		lastUsed, err := cache.LastUsed(ctx)
+		require.NoError(t, err)
+		require.NotZero(t, lastUsed)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		volumes, err := c.CacheVolumes(ctx)
+		require.NoError(t, err)
+
+		var found bool
+		for _, vol := range volumes {
+			volChecksum, err := vol.Checksum(ctx)
+			require.NoError(t, err)
+
+			if volChecksum == checksum {
+				found = true
+
+				size, err := vol.Size(ctx)
+				require.NoError(t, err)
+				require.GreaterOrEqual(t, size, 1024*1024)
+			}
+		}
+		require.True(t, found)
+
+		volumes, err = c.CacheVolumes(ctx, dagger.CacheVolumesOpts{
+			Checksums: []string{checksum, "missing"},
+		})
+		require.NoError(t, err)
+		require.Len(t, volumes, 1)
+
+		volChecksum, err := volumes[0].Checksum(ctx)
+		require.NoError(t, err)
+		require.Equal(t, checksum, volChecksum)
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		_, err := cache.Prune(ctx)
+		require.NoError(t, err)
+
+		size, err := cache.Size(ctx)
+		require.NoError(t, err)
+		require.Zero(t, size)
+
+		volumes, err := c.CacheVolumes(ctx)
+		require.NoError(t, err)
+		for _, vol := range volumes {
+			volChecksum, err := vol.Checksum(ctx)
+			require.NoError(t, err)
+			require.NotEqual(t, checksum, volChecksum)
+		}
+	})
+}
+
+func TestCacheVolumeContents(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	cache := c.CacheVolume(identity.NewID()).
+		WithContents(c.Directory().
+			WithNewFile("seed", "seeded").
+			WithNewFile("sub/file", "sub-content"))
+
+	ctr := c.Container().
+		From("alpine:3.16.2").
+		WithMountedCache("/cache", cache)
+
+	t.Run("seeded from a directory", func(t *testing.T) {
+		out, err := ctr.WithExec([]string{"cat", "/cache/seed", "/cache/sub/file"}).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "seededsub-content", out)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		_, err := ctr.WithExec([]string{"sh", "-c", "echo -n written > /cache/written"}).Sync(ctx)
+		require.NoError(t, err)
+
+		snapshot := cache.Snapshot()
+
+		entries, err := snapshot.Entries(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"seed", "sub", "written"}, entries)
+
+		contents, err := snapshot.File("written").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "written", contents)
+	})
+
+	t.Run("mounted again", func(t *testing.T) {
+		// the same key and contents mount the same volume, so changes are kept
+		out, err := c.Container().
+			From("alpine:3.16.2").
+			WithMountedCache("/cache", cache).
+			WithExec([]string{"ls", "/cache"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "seed\nsub\nwritten\n", out)
+	})
+
+	t.Run("other contents", func(t *testing.T) {
+		// different contents get a volume of their own
+		other := cache.WithContents(c.Directory().WithNewFile("other", "other"))
+
+		entries, err := other.Snapshot().Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"other"}, entries)
+	})
+}
@@ -1664,6 +1664,63 @@ func TestContainerWithMountedTemp(t *testing.T) {
 	require.Contains(t, execRes.Container.From.WithMountedTemp.WithExec.Stdout, "tmpfs /mnt/tmp tmpfs")
 }
 
+func TestContainerWithMountedTempSize(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	ctr := c.Container().
+		From("alpine:3.16.2").
+		WithMountedTemp("/mnt/tmp", dagger.ContainerWithMountedTempOpts{
+			Size: 1024 * 1024,
+		})
+
+	out, err := ctr.WithExec([]string{"grep", "/mnt/tmp", "/proc/mounts"}).Stdout(ctx)
+	require.NoError(t, err)
+	require.Contains(t, out, "size=1024k")
+
+	_, err = ctr.
+		WithExec([]string{"dd", "if=/dev/zero", "of=/mnt/tmp/big", "bs=1M", "count=2"}).
+		Sync(ctx)
+	require.Error(t, err)
+}
+
+func TestContainerWithMountedReadOnly(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	dir := c.Directory().WithNewFile("some-file", "some-content")
+
+	ctr := c.Container().
+		From("alpine:3.16.2").
+		WithMountedDirectory("/mnt/dir", dir, dagger.ContainerWithMountedDirectoryOpts{
+			ReadOnly: true,
+		}).
+		WithMountedFile("/mnt/file", dir.File("some-file"), dagger.ContainerWithMountedFileOpts{
+			ReadOnly: true,
+		}).
+		WithMountedCache("/mnt/cache", c.CacheVolume(identity.NewID()), dagger.ContainerWithMountedCacheOpts{
+			ReadOnly: true,
+		})
+
+	out, err := ctr.WithExec([]string{"cat", "/mnt/dir/some-file", "/mnt/file"}).Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "some-contentsome-content", out)
+
+	for _, target := range []string{"/mnt/dir/new-file", "/mnt/file", "/mnt/cache/new-file"} {
+		_, err := ctr.WithExec([]string{"sh", "-c", "echo hi > " + target}).Sync(ctx)
+		require.Error(t, err, target)
+	}
+
+	// contents can still be read back from the mount
+	contents, err := ctr.File("/mnt/dir/some-file").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "some-content", contents)
+}
+
 func TestContainerWithDirectory(t *testing.T) {
 	t.Parallel()
 
@@ -2,6 +2,9 @@ package core
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 	"time"
@@ -572,6 +575,80 @@ func TestDirectoryDiff(t *testing.T) {
 	*/
 }
 
+func TestDirectoryChanges(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	before := c.Directory().
+		WithNewFile("same.txt", "same\n").
+		WithNewFile("modified.txt", "a\nb\nc\n").
+		WithNewFile("deleted.txt", "deleted\n")
+
+	after := c.Directory().
+		WithNewFile("same.txt", "same\n").
+		WithNewFile("modified.txt", "a\nB\nc\n").
+		WithNewFile("sub/added.txt", "added\n")
+
+	t.Run("changes", func(t *testing.T) {
+		changes, err := before.Changes(ctx, after)
+		require.NoError(t, err)
+
+		strs := []string{}
+		for _, change := range changes {
+			kind, err := change.Kind(ctx)
+			require.NoError(t, err)
+			path, err := change.Path(ctx)
+			require.NoError(t, err)
+			strs = append(strs, fmt.Sprintf("%s %s", kind, path))
+		}
+
+		require.Equal(t, []string{
+			"DELETED deleted.txt",
+			"MODIFIED modified.txt",
+			"ADDED sub",
+			"ADDED sub/added.txt",
+		}, strs)
+
+		newSize, err := changes[3].NewSize(ctx)
+		require.NoError(t, err)
+		require.Equal(t, len("added\n"), newSize)
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		changes, err := before.Changes(ctx, before)
+		require.NoError(t, err)
+		require.Empty(t, changes)
+	})
+
+	t.Run("as patch", func(t *testing.T) {
+		patch, err := before.AsPatch(after).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, `diff --git a/deleted.txt b/deleted.txt
+deleted file mode 100644
+--- a/deleted.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-deleted
+diff --git a/modified.txt b/modified.txt
+--- a/modified.txt
++++ b/modified.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+diff --git a/sub/added.txt b/sub/added.txt
+new file mode 100644
+--- /dev/null
++++ b/sub/added.txt
+@@ -0,0 +1 @@
++added
+`, patch)
+	})
+}
+
 func TestDirectoryExport(t *testing.T) {
 	t.Parallel()
 
@@ -611,6 +688,73 @@ func TestDirectoryExport(t *testing.T) {
 		require.Error(t, err)
 		require.False(t, ok)
 	})
+
+	t.Run("with wipe", func(t *testing.T) {
+		dest := t.TempDir()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dest, "stale.txt"), []byte("stale"), 0600))
+		require.NoError(t, os.MkdirAll(filepath.Join(dest, "stale-dir", "sub"), 0755))
+
+		ok, err := dir.Export(ctx, dest, dagger.DirectoryExportOpts{Wipe: true})
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		entries, err := ls(dest)
+		require.NoError(t, err)
+		require.Equal(t, []string{"README", "color_prompt.sh.disabled", "locale.sh"}, entries)
+	})
+}
+
+func TestDirectoryExportDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dest := t.TempDir()
+
+	c, err := dagger.Connect(ctx, dagger.WithWorkdir(dest))
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "modified.txt"), []byte("old"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "same.txt"), []byte("same"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "stale.txt"), []byte("stale"), 0644))
+
+	dir := c.Directory().
+		WithNewFile("added.txt", "added").
+		WithNewFile("modified.txt", "new").
+		WithNewFile("same.txt", "same")
+
+	changes := func(opts ...dagger.DirectoryExportDryRunOpts) []string {
+		res, err := dir.ExportDryRun(ctx, ".", opts...)
+		require.NoError(t, err)
+
+		strs := []string{}
+		for _, change := range res {
+			kind, err := change.Kind(ctx)
+			require.NoError(t, err)
+			path, err := change.Path(ctx)
+			require.NoError(t, err)
+			strs = append(strs, fmt.Sprintf("%s %s", kind, path))
+		}
+		return strs
+	}
+
+	require.Equal(t, []string{
+		"ADDED added.txt",
+		"MODIFIED modified.txt",
+	}, changes())
+
+	require.Equal(t, []string{
+		"ADDED added.txt",
+		"MODIFIED modified.txt",
+		"DELETED stale.txt",
+	}, changes(dagger.DirectoryExportDryRunOpts{Wipe: true}))
+
+	// nothing is written
+	entries, err := ls(dest)
+	require.NoError(t, err)
+	require.Equal(t, []string{"modified.txt", "same.txt", "stale.txt"}, entries)
 }
 
 func TestDirectoryDockerBuild(t *testing.T) {
@@ -772,6 +916,52 @@ CMD cat /secret
 	})
 }
 
+func TestDirectoryDockerBuildDockerignore(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	contextDir := c.Directory().
+		WithNewFile("kept.txt", "kept").
+		WithNewFile("ignored.txt", "ignored").
+		WithNewFile("node_modules/dep/index.js", "ignored").
+		WithNewFile("Dockerfile",
+			`FROM alpine:3.16.2
+WORKDIR /src
+COPY . .
+CMD find . -type f | sort
+`)
+
+	t.Run(".dockerignore", func(t *testing.T) {
+		src := contextDir.WithNewFile(".dockerignore", "ignored.txt\nnode_modules\n")
+
+		out, err := src.DockerBuild().Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "./.dockerignore\n./Dockerfile\n./kept.txt\n", out)
+	})
+
+	t.Run("Dockerfile-specific .dockerignore", func(t *testing.T) {
+		src := contextDir.
+			WithNewFile(".dockerignore", "node_modules\n").
+			WithNewFile("Dockerfile.dockerignore", "*.txt\nnode_modules\nDockerfile*\n")
+
+		out, err := src.DockerBuild().Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "./.dockerignore\n", out)
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		src := contextDir.WithNewFile(".dockerignore", "ignored.txt\nnode_modules\n")
+
+		sub := c.Directory().WithDirectory("subcontext", src).Directory("subcontext")
+
+		out, err := sub.DockerBuild().Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "./.dockerignore\n./Dockerfile\n./kept.txt\n", out)
+	})
+}
+
 func TestDirectoryWithNewFileExceedingLength(t *testing.T) {
 	t.Parallel()
 
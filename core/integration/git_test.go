@@ -71,6 +71,137 @@ func TestGitSSHAuthSock(t *testing.T) {
 	c, ctx := connect(t)
 	defer c.Close()
 
+	sshSvc, repoURL, knownHosts, userPrivateKey := gitSSHService(ctx, t, c)
+
+	key, err := ssh.ParseRawPrivateKey([]byte(userPrivateKey))
+	require.NoError(t, err)
+
+	sshAgent := agent.NewKeyring()
+	err = sshAgent.Add(agent.AddedKey{
+		PrivateKey: key,
+	})
+	require.NoError(t, err)
+
+	tmp := t.TempDir()
+	sock := filepath.Join(tmp, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					t.Logf("accept: %s", err)
+					panic(err)
+				}
+				break
+			}
+
+			t.Log("agent serving")
+
+			err = agent.ServeAgent(sshAgent, c)
+			if err != nil && !errors.Is(err, io.EOF) {
+				t.Logf("serve agent: %s", err)
+				panic(err)
+			}
+		}
+	}()
+
+	entries, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: sshSvc}).
+		Branch("main").
+		Tree(dagger.GitRefTreeOpts{
+			SSHKnownHosts: knownHosts,
+			SSHAuthSocket: c.Host().UnixSocket(sock),
+		}).
+		Entries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md"}, entries)
+
+	t.Run("repository auth", func(t *testing.T) {
+		testGitSSHRepository(ctx, t, c.Git(repoURL, dagger.GitOpts{
+			SSHKnownHosts:           knownHosts,
+			SSHAuthSocket:           c.Host().UnixSocket(sock),
+			ExperimentalServiceHost: sshSvc,
+		}))
+	})
+}
+
+func TestGitSSHKey(t *testing.T) {
+	t.Parallel()
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	sshSvc, repoURL, knownHosts, userPrivateKey := gitSSHService(ctx, t, c)
+
+	entries, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: sshSvc}).
+		Branch("main").
+		Tree(dagger.GitRefTreeOpts{
+			SSHKnownHosts: knownHosts,
+			SSHKey:        c.SetSecret("ssh-key", userPrivateKey),
+		}).
+		Entries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md"}, entries)
+
+	t.Run("repository auth", func(t *testing.T) {
+		testGitSSHRepository(ctx, t, c.Git(repoURL, dagger.GitOpts{
+			SSHKnownHosts:           knownHosts,
+			SSHKey:                  c.SetSecret("ssh-key", userPrivateKey),
+			ExperimentalServiceHost: sshSvc,
+		}))
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		_, err := c.Git(repoURL, dagger.GitOpts{
+			SSHKnownHosts:           "example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+			SSHKey:                  c.SetSecret("ssh-key", userPrivateKey),
+			ExperimentalServiceHost: sshSvc,
+		}).Branches(ctx)
+		require.Error(t, err)
+	})
+}
+
+// testGitSSHRepository checks that the repository's metadata and tree can be
+// fetched with the SSH auth given to the repository.
+func testGitSSHRepository(ctx context.Context, t *testing.T, repo *dagger.GitRepository) {
+	t.Helper()
+
+	branches, err := repo.Branches(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"main"}, branches)
+
+	refs, err := repo.Refs(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, refs)
+
+	main := repo.Branch("main")
+
+	digest, err := main.Digest(ctx)
+	require.NoError(t, err)
+
+	sha, err := main.Commit().Sha(ctx)
+	require.NoError(t, err)
+	require.Equal(t, digest, sha)
+
+	log, err := main.Log(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, log)
+
+	entries, err := main.Tree().Entries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"README.md"}, entries)
+}
+
+// gitSSHService returns a service serving a git repository over SSH, along
+// with the repository's URL, the known hosts entry for the service and the
+// private key authorized to access it.
+func gitSSHService(ctx context.Context, t *testing.T, c *dagger.Client) (*dagger.Container, string, string, string) {
+	t.Helper()
+
 	gitSSH := c.Container().
 		From("alpine:3.16.2").
 		WithExec([]string{"apk", "add", "git", "openssh"})
@@ -116,42 +247,6 @@ sleep infinity
 `).
 		File("setup.sh")
 
-	key, err := ssh.ParseRawPrivateKey([]byte(userPrivateKey))
-	require.NoError(t, err)
-
-	sshAgent := agent.NewKeyring()
-	err = sshAgent.Add(agent.AddedKey{
-		PrivateKey: key,
-	})
-	require.NoError(t, err)
-
-	tmp := t.TempDir()
-	sock := filepath.Join(tmp, "agent.sock")
-	l, err := net.Listen("unix", sock)
-	require.NoError(t, err)
-	defer l.Close()
-
-	go func() {
-		for {
-			c, err := l.Accept()
-			if err != nil {
-				if !errors.Is(err, net.ErrClosed) {
-					t.Logf("accept: %s", err)
-					panic(err)
-				}
-				break
-			}
-
-			t.Log("agent serving")
-
-			err = agent.ServeAgent(sshAgent, c)
-			if err != nil && !errors.Is(err, io.EOF) {
-				t.Logf("serve agent: %s", err)
-				panic(err)
-			}
-		}
-	}()
-
 	sshPort := 2222
 	sshSvc := hostKeyGen.
 		WithMountedFile("/root/start.sh", setupScript).
@@ -162,15 +257,191 @@ sleep infinity
 	require.NoError(t, err)
 
 	repoURL := fmt.Sprintf("ssh://root@%s:%d/root/repo", sshHost, sshPort)
-	entries, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: sshSvc}).
-		Branch("main").
-		Tree(dagger.GitRefTreeOpts{
-			SSHKnownHosts: fmt.Sprintf("[%s]:%d %s", sshHost, sshPort, strings.TrimSpace(hostPubKey)),
-			SSHAuthSocket: c.Host().UnixSocket(sock),
+	knownHosts := fmt.Sprintf("[%s]:%d %s", sshHost, sshPort, strings.TrimSpace(hostPubKey))
+
+	return sshSvc, repoURL, knownHosts, userPrivateKey
+}
+
+func TestGitHTTPAuth(t *testing.T) {
+	t.Parallel()
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	gitSvc, repoURL := gitHTTPService(ctx, t, c, "s3cr3t")
+
+	token := c.SetSecret("git-token", "s3cr3t")
+	header := c.SetSecret("git-header", "Bearer s3cr3t")
+
+	for _, tc := range []struct {
+		name string
+		opts dagger.GitOpts
+	}{
+		{"token", dagger.GitOpts{HTTPAuthToken: token}},
+		// fetching history uses git in a container rather than buildkit's
+		// git source
+		{"token with depth", dagger.GitOpts{HTTPAuthToken: token, Depth: 2}},
+		{"header", dagger.GitOpts{HTTPAuthHeader: header}},
+		{"header with depth", dagger.GitOpts{HTTPAuthHeader: header, Depth: 2}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.ExperimentalServiceHost = gitSvc
+			repo := c.Git(repoURL, tc.opts)
+
+			readme, err := repo.Branch("main").Tree().File("README.md").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "two\n", readme)
+
+			branches, err := repo.Branches(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"main"}, branches)
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitSvc}).
+			Branch("main").
+			Tree().
+			File("README.md").
+			Contents(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		_, err := c.Git(repoURL, dagger.GitOpts{
+			HTTPAuthToken:           c.SetSecret("wrong-git-token", "wrong"),
+			Depth:                   2,
+			ExperimentalServiceHost: gitSvc,
 		}).
-		Entries(ctx)
+			Branch("main").
+			Tree().
+			File("README.md").
+			Contents(ctx)
+		require.Error(t, err)
+	})
+}
+
+// gitHTTPService serves a repository over git's smart HTTP protocol, only to
+// clients which authenticate with the token, either as a bearer token or as
+// the basic auth password of x-access-token.
+func gitHTTPService(ctx context.Context, t *testing.T, c *dagger.Client, token string) (*dagger.Container, string) {
+	t.Helper()
+
+	setup := c.Directory().
+		WithNewFile("setup.sh", `#!/bin/sh
+
+set -e -u -x
+
+git config --global user.email "root@localhost"
+git config --global user.name "Test User"
+git config --global init.defaultBranch main
+
+mkdir -p /srv/git /root/repo
+
+cd /root/repo
+git init
+echo one > README.md
+git add README.md
+git commit -m "one"
+echo two > README.md
+git commit -am "two"
+
+git clone --bare /root/repo /srv/git/repo.git
+
+exec python /src/server.py
+`).
+		WithNewFile("server.py", `
+import base64
+import os
+import subprocess
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+TOKEN = os.environ["GIT_TOKEN"]
+
+
+def authorized(header):
+    scheme, _, value = header.partition(" ")
+    if scheme.lower() == "bearer":
+        return value == TOKEN
+    if scheme.lower() == "basic":
+        return base64.b64decode(value) == b"x-access-token:" + TOKEN.encode()
+    return False
+
+
+class Handler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.serve()
+
+    def do_POST(self):
+        self.serve()
+
+    def serve(self):
+        if not authorized(self.headers.get("Authorization", "")):
+            self.send_response(401)
+            self.send_header("WWW-Authenticate", 'Basic realm="git"')
+            self.end_headers()
+            return
+
+        path, _, query = self.path.partition("?")
+        env = dict(
+            os.environ,
+            GIT_PROJECT_ROOT="/srv/git",
+            GIT_HTTP_EXPORT_ALL="1",
+            REQUEST_METHOD=self.command,
+            PATH_INFO=path,
+            QUERY_STRING=query,
+            CONTENT_TYPE=self.headers.get("Content-Type", ""),
+            HTTP_CONTENT_ENCODING=self.headers.get("Content-Encoding", ""),
+            HTTP_GIT_PROTOCOL=self.headers.get("Git-Protocol", ""),
+            REMOTE_USER="git",
+            REMOTE_ADDR=self.client_address[0],
+        )
+        body = self.rfile.read(int(self.headers.get("Content-Length", 0)))
+        out = subprocess.run(
+            ["git", "http-backend"],
+            input=body,
+            env=env,
+            stdout=subprocess.PIPE,
+            check=True,
+        ).stdout
+
+        sep = b"\r\n\r\n" if b"\r\n\r\n" in out else b"\n\n"
+        head, _, content = out.partition(sep)
+        status = 200
+        headers = []
+        for line in head.decode().splitlines():
+            name, _, value = line.partition(":")
+            if name.lower() == "status":
+                status = int(value.split()[0])
+            else:
+                headers.append((name, value.strip()))
+
+        self.send_response(status)
+        for name, value in headers:
+            self.send_header(name, value)
+        self.end_headers()
+        self.wfile.write(content)
+
+
+HTTPServer(("", 8000), Handler).serve_forever()
+`)
+
+	gitSvc := c.Container().
+		From("python:3.11-alpine").
+		WithExec([]string{"apk", "add", "git"}).
+		WithMountedDirectory("/src", setup).
+		WithEnvVariable("GIT_TOKEN", token).
+		WithExposedPort(8000).
+		WithExec([]string{"sh", "/src/setup.sh"})
+
+	url, err := gitSvc.Endpoint(ctx, dagger.ContainerEndpointOpts{
+		Scheme: "http",
+	})
 	require.NoError(t, err)
-	require.Equal(t, []string{"README.md"}, entries)
+
+	return gitSvc, url + "/repo.git"
 }
 
 func TestGitKeepGitDir(t *testing.T) {
@@ -192,3 +463,211 @@ func TestGitKeepGitDir(t *testing.T) {
 		require.NotContains(t, ent, ".git")
 	})
 }
+
+func TestGitRefsDepthSubmodules(t *testing.T) {
+	t.Parallel()
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	gitSvc, repoURL := gitDaemonService(ctx, t, c)
+
+	t.Run("head", func(t *testing.T) {
+		readme, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitSvc}).
+			Head().
+			Tree().
+			File("README.md").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "two\n", readme)
+	})
+
+	t.Run("pull request ref", func(t *testing.T) {
+		readme, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitSvc}).
+			Ref("refs/pull/1/head").
+			Tree().
+			File("README.md").
+			Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "pr\n", readme)
+	})
+
+	t.Run("submodules", func(t *testing.T) {
+		entries, err := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitSvc}).
+			Branch("main").
+			Tree().
+			Entries(ctx, dagger.DirectoryEntriesOpts{Path: "sub"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"sub.txt"}, entries)
+	})
+
+	t.Run("skip submodules", func(t *testing.T) {
+		entries, err := c.Git(repoURL, dagger.GitOpts{
+			SkipSubmodules:          true,
+			ExperimentalServiceHost: gitSvc,
+		}).
+			Branch("main").
+			Tree().
+			Entries(ctx, dagger.DirectoryEntriesOpts{Path: "sub"})
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		tree := c.Git(repoURL, dagger.GitOpts{
+			KeepGitDir:              true,
+			Depth:                   3,
+			ExperimentalServiceHost: gitSvc,
+		}).
+			Branch("main").
+			Tree()
+
+		out, err := c.Container().
+			From("alpine/git:2.36.3").
+			WithMountedDirectory("/src", tree).
+			WithWorkdir("/src").
+			WithExec([]string{"describe", "--tags"}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, out, "v0.1.0-2-g")
+	})
+}
+
+func TestGitMetadata(t *testing.T) {
+	t.Parallel()
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	gitSvc, repoURL := gitDaemonService(ctx, t, c)
+	repo := c.Git(repoURL, dagger.GitOpts{ExperimentalServiceHost: gitSvc})
+
+	commits, err := repo.Branch("main").Log(ctx, dagger.GitRefLogOpts{Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+
+	messages := []string{}
+	shas := []string{}
+	for _, commit := range commits {
+		message, err := commit.Message(ctx)
+		require.NoError(t, err)
+		messages = append(messages, message)
+
+		sha, err := commit.Sha(ctx)
+		require.NoError(t, err)
+		shas = append(shas, sha)
+	}
+	require.Equal(t, []string{"add submodule", "two", "one"}, messages)
+
+	t.Run("commit", func(t *testing.T) {
+		commit := repo.Branch("main").Commit()
+
+		sha, err := commit.Sha(ctx)
+		require.NoError(t, err)
+		require.Equal(t, shas[0], sha)
+
+		author, err := commit.Author(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Test User <root@localhost>", author)
+
+		timestamp, err := commit.Timestamp(ctx)
+		require.NoError(t, err)
+		require.NotZero(t, timestamp)
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		digest, err := repo.Branch("main").Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, shas[0], digest)
+
+		digest, err = repo.Head().Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, shas[0], digest)
+	})
+
+	t.Run("branches and tags", func(t *testing.T) {
+		branches, err := repo.Branches(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"main"}, branches)
+
+		tags, err := repo.Tags(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v0.1.0"}, tags)
+	})
+
+	t.Run("refs", func(t *testing.T) {
+		refs, err := repo.Refs(ctx, dagger.GitRepositoryRefsOpts{Pattern: "refs/tags/v*"})
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+
+		name, err := refs[0].Name(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "refs/tags/v0.1.0", name)
+
+		// annotated tags resolve to their commit
+		sha, err := refs[0].Sha(ctx)
+		require.NoError(t, err)
+		require.Equal(t, shas[2], sha)
+	})
+}
+
+// gitDaemonService returns a service serving a git repository with a tag, a
+// submodule and a pull request ref over the git protocol, along with the
+// repository's URL.
+func gitDaemonService(ctx context.Context, t *testing.T, c *dagger.Client) (*dagger.Container, string) {
+	t.Helper()
+
+	setupScript := c.Directory().
+		WithNewFile("setup.sh", `#!/bin/sh
+
+set -e -u -x
+
+git config --global user.email "root@localhost"
+git config --global user.name "Test User"
+git config --global init.defaultBranch main
+git config --global protocol.file.allow always
+
+mkdir -p /srv/sub /srv/repo
+
+cd /srv/sub
+git init
+echo sub > sub.txt
+git add sub.txt
+git commit -m "sub"
+
+cd /srv/repo
+git init
+echo one > README.md
+git add README.md
+git commit -m "one"
+git tag -a v0.1.0 -m "release v0.1.0"
+echo two > README.md
+git commit -am "two"
+git submodule add ../sub sub
+git commit -m "add submodule"
+
+git checkout -b pr
+echo pr > README.md
+git commit -am "pr"
+git update-ref refs/pull/1/head HEAD
+git checkout main
+git branch -D pr
+
+git daemon --verbose --export-all --base-path=/srv --reuseaddr --port=9418
+`).
+		File("setup.sh")
+
+	gitSvc := c.Container().
+		From("alpine:3.16.2").
+		WithExec([]string{"apk", "add", "git", "git-daemon"}).
+		WithMountedFile("/root/start.sh", setupScript).
+		WithExposedPort(9418).
+		WithExec([]string{"sh", "/root/start.sh"})
+
+	gitHost, err := gitSvc.Hostname(ctx)
+	require.NoError(t, err)
+
+	return gitSvc, fmt.Sprintf("git://%s/repo", gitHost)
+}
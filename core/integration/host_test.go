@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"dagger.io/dagger"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
 )
 
@@ -238,6 +241,195 @@ func TestHostDirectoryExcludeInclude(t *testing.T) {
 	})
 }
 
+func TestHostDirectoryGitignore(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "app")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitignore"), []byte("*.log\n"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("node_modules/\n/out.txt\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node_modules", "dep", "index.js"), []byte("1"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), []byte("2"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "debug.log"), []byte("3"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("4"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "out.txt"), []byte("5"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "trace.log"), []byte("6"), 0600))
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	t.Run("gitignore", func(t *testing.T) {
+		hostDir := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+			Gitignore: true,
+		})
+
+		entries, err := hostDir.Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{".gitignore", "src"}, entries)
+
+		entries, err = hostDir.Entries(ctx, dagger.DirectoryEntriesOpts{Path: "src"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"main.go", "out.txt"}, entries)
+	})
+
+	t.Run("gitignore with exclude", func(t *testing.T) {
+		entries, err := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+			Gitignore: true,
+			Exclude:   []string{".gitignore"},
+		}).Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"src"}, entries)
+	})
+
+	t.Run("without gitignore", func(t *testing.T) {
+		entries, err := c.Host().Directory(dir).Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{".gitignore", "debug.log", "node_modules", "out.txt", "src"}, entries)
+	})
+}
+
+func TestHostDirectoryWatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte("1"), 0600))
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	contents, err := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+		Watch: true,
+	}).File("file").Contents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1", contents)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte("2"), 0600))
+
+	require.Eventually(t, func() bool {
+		contents, err := c.Host().Directory(dir, dagger.HostDirectoryOpts{
+			Watch: true,
+		}).File("file").Contents(ctx)
+		require.NoError(t, err)
+		return contents == "2"
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestHostGitRepository(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("committed"), 0600))
+	_, err = worktree.Add(".gitignore")
+	require.NoError(t, err)
+	_, err = worktree.Add("README.md")
+	require.NoError(t, err)
+	sha, err := worktree.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test User", Email: "root@localhost", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "debug.log"), []byte("ignored"), 0600))
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	hostRepo := c.Host().GitRepository(dir)
+
+	t.Run("clean", func(t *testing.T) {
+		actualSHA, err := hostRepo.Head().Digest(ctx)
+		require.NoError(t, err)
+		require.Equal(t, sha.String(), actualSHA)
+
+		branches, err := hostRepo.Branches(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"master"}, branches)
+
+		dirty, err := hostRepo.Dirty(ctx)
+		require.NoError(t, err)
+		require.False(t, dirty)
+	})
+
+	t.Run("refs", func(t *testing.T) {
+		refs, err := hostRepo.Refs(ctx)
+		require.NoError(t, err)
+		require.Len(t, refs, 2)
+
+		name, err := refs[0].Name(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "HEAD", name)
+		name, err = refs[1].Name(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "refs/heads/master", name)
+		refSHA, err := refs[1].Sha(ctx)
+		require.NoError(t, err)
+		require.Equal(t, sha.String(), refSHA)
+	})
+
+	t.Run("log", func(t *testing.T) {
+		commits, err := hostRepo.Branch("master").Log(ctx)
+		require.NoError(t, err)
+		require.Len(t, commits, 1)
+
+		author, err := commits[0].Author(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Test User <root@localhost>", author)
+		message, err := commits[0].Message(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "init", message)
+	})
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("uncommitted"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("untracked"), 0600))
+
+	t.Run("dirty", func(t *testing.T) {
+		dirty, err := c.Host().GitRepository(dir).Dirty(ctx)
+		require.NoError(t, err)
+		require.True(t, dirty)
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		tree := hostRepo.Head().Tree()
+
+		entries, err := tree.Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{".gitignore", "README.md"}, entries)
+
+		contents, err := tree.File("README.md").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "committed", contents)
+	})
+
+	t.Run("tree with uncommitted changes", func(t *testing.T) {
+		tree := hostRepo.Head().Tree(dagger.GitRefTreeOpts{
+			IncludeUncommitted: true,
+		})
+
+		entries, err := tree.Entries(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{".gitignore", "README.md", "new.txt"}, entries)
+
+		contents, err := tree.File("README.md").Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "uncommitted", contents)
+	})
+
+	t.Run("remote repositories have no working tree", func(t *testing.T) {
+		_, err := c.Git("github.com/dagger/dagger").Dirty(ctx)
+		require.ErrorContains(t, err, "no working tree")
+	})
+}
+
 func TestHostFile(t *testing.T) {
 	t.Parallel()
 
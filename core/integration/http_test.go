@@ -5,6 +5,7 @@ import (
 
 	"dagger.io/dagger"
 	"github.com/dagger/dagger/internal/engine"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -42,3 +43,138 @@ func TestHTTPService(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, contents, "Hello, world!")
 }
+
+func TestHTTPChecksum(t *testing.T) {
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	svc, url := httpService(ctx, t, c, "Hello, world!")
+
+	t.Run("matching checksum", func(t *testing.T) {
+		contents, err := c.HTTP(url, dagger.HTTPOpts{
+			Checksum:                digest.FromString("Hello, world!").String(),
+			ExperimentalServiceHost: svc,
+		}).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world!", contents)
+	})
+
+	t.Run("mismatched checksum", func(t *testing.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			Checksum:                digest.FromString("Goodbye, world!").String(),
+			ExperimentalServiceHost: svc,
+		}).Contents(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			Checksum:                digest.SHA384.FromString("Hello, world!").String(),
+			ExperimentalServiceHost: svc,
+		}).Contents(ctx)
+		require.ErrorContains(t, err, "algorithm must be sha256 or sha512")
+	})
+}
+
+func TestHTTPPermissions(t *testing.T) {
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	svc, url := httpService(ctx, t, c, "#!/bin/sh\necho hello\n")
+
+	out, err := c.Container().
+		From("alpine:3.16.2").
+		WithMountedFile("/usr/local/bin/hello", c.HTTP(url, dagger.HTTPOpts{
+			Permissions:             0o755,
+			Name:                    "hello",
+			ExperimentalServiceHost: svc,
+		})).
+		WithExec([]string{"hello"}).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", out)
+}
+
+func TestHTTPInvalidPermissions(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	for _, perm := range []int{-1, 0o1000, 0o4755} {
+		_, err := c.HTTP("https://dagger.io", dagger.HTTPOpts{Permissions: perm}).Contents(ctx)
+		require.ErrorContains(t, err, "invalid permissions", perm)
+	}
+}
+
+func TestHTTPInvalidName(t *testing.T) {
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	for _, name := range []string{".", "..", "/", "a/b", "../a"} {
+		_, err := c.HTTP("https://dagger.io", dagger.HTTPOpts{Name: name}).Contents(ctx)
+		require.ErrorContains(t, err, "must be a file name", name)
+	}
+}
+
+func TestHTTPHeaders(t *testing.T) {
+	checkNotDisabled(t, engine.ServicesDNSEnvName)
+
+	t.Parallel()
+
+	c, ctx := connect(t)
+	defer c.Close()
+
+	// only serves the content when the expected headers are sent
+	srv := c.Container().
+		From("python").
+		WithMountedFile("/srv/server.py", c.Directory().WithNewFile("server.py", `
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+class Handler(BaseHTTPRequestHandler):
+    def do_GET(self):
+        if self.headers.get("Authorization") != "Bearer s3cr3t" or self.headers.get("X-Custom") != "custom":
+            self.send_response(401)
+            self.end_headers()
+            return
+        self.send_response(200)
+        self.end_headers()
+        self.wfile.write(b"Hello, world!")
+
+HTTPServer(("", 8000), Handler).serve_forever()
+`).File("server.py")).
+		WithExposedPort(8000).
+		WithExec([]string{"python", "/srv/server.py"})
+
+	url, err := srv.Endpoint(ctx, dagger.ContainerEndpointOpts{
+		Scheme: "http",
+	})
+	require.NoError(t, err)
+
+	t.Run("with headers", func(t *testing.T) {
+		contents, err := c.HTTP(url, dagger.HTTPOpts{
+			Headers:                 []dagger.HTTPHeader{{Name: "X-Custom", Value: "custom"}},
+			AuthHeader:              c.SetSecret("auth-header", "Bearer s3cr3t"),
+			ExperimentalServiceHost: srv,
+		}).Contents(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world!", contents)
+	})
+
+	t.Run("without headers", func(t *testing.T) {
+		_, err := c.HTTP(url, dagger.HTTPOpts{
+			ExperimentalServiceHost: srv,
+		}).Contents(ctx)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dagger/dagger/engine"
+	internalengine "github.com/dagger/dagger/internal/engine"
+	"github.com/dagger/dagger/router"
+	"github.com/gorilla/websocket"
+	"github.com/moby/buildkit/identity"
+	"github.com/stretchr/testify/require"
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// withSessionServer starts a session and serves its API over HTTP, so that
+// subscriptions can be made over its WebSocket transport.
+func withSessionServer(t *testing.T, fn func(ctx context.Context, url string)) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	err := engine.Start(ctx, engine.Config{
+		RunnerHost: internalengine.RunnerHost(),
+	}, func(ctx context.Context, r *router.Router) error {
+		srv := httptest.NewServer(r)
+		defer srv.Close()
+		fn(ctx, srv.URL)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func postQuery(ctx context.Context, url, query string, vars map[string]any, res any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/query", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   json.RawMessage
+		Errors []json.RawMessage
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("query failed: %s", result.Errors[0])
+	}
+	return json.Unmarshal(result.Data, res)
+}
+
+func TestExecLogs(t *testing.T) {
+	t.Parallel()
+
+	withSessionServer(t, func(ctx context.Context, url string) {
+		// a unique command, so that it isn't cached and runs while subscribed;
+		// it waits a little before writing, since subscribing isn't
+		// acknowledged
+		var created struct {
+			Container struct {
+				From struct {
+					WithExec struct {
+						ID string
+					}
+				}
+			}
+		}
+		err := postQuery(ctx, url, `query($cmd: String!) {
+			container {
+				from(address: "alpine:3.16.2") {
+					withExec(args: ["sh", "-c", $cmd]) {
+						id
+					}
+				}
+			}
+		}`, map[string]any{
+			"cmd": "sleep 1; echo hello " + identity.NewID() + "; echo world",
+		}, &created)
+		require.NoError(t, err)
+		id := created.Container.From.WithExec.ID
+
+		dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+		conn, _, err := dialer.DialContext(ctx, "ws"+strings.TrimPrefix(url, "http")+"/query", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(3 * time.Minute))
+
+		require.NoError(t, conn.WriteJSON(wsMessage{Type: "connection_init"}))
+		var msg wsMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		require.Equal(t, "connection_ack", msg.Type)
+
+		payload, err := json.Marshal(map[string]any{
+			"query":     `subscription($id: ContainerID!) { execLogs(container: $id) { stream data } }`,
+			"variables": map[string]any{"id": id},
+		})
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteJSON(wsMessage{ID: "1", Type: "subscribe", Payload: payload}))
+
+		// the subscription doesn't run the command, so evaluate it once
+		// subscribed
+		stdout := make(chan string, 1)
+		stdoutErr := make(chan error, 1)
+		go func() {
+			var res struct {
+				Container struct {
+					Stdout string
+				}
+			}
+			err := postQuery(ctx, url, `query($id: ContainerID!) {
+				container(id: $id) {
+					stdout
+				}
+			}`, map[string]any{"id": id}, &res)
+			stdout <- res.Container.Stdout
+			stdoutErr <- err
+		}()
+
+		logs := ""
+		for {
+			var msg wsMessage
+			require.NoError(t, conn.ReadJSON(&msg))
+			if msg.Type == "complete" {
+				break
+			}
+			require.Equal(t, "next", msg.Type, string(msg.Payload))
+
+			var result struct {
+				Data struct {
+					ExecLogs struct {
+						Stream string
+						Data   string
+					}
+				}
+			}
+			require.NoError(t, json.Unmarshal(msg.Payload, &result))
+			if result.Data.ExecLogs.Stream == "STDOUT" {
+				logs += result.Data.ExecLogs.Data
+			}
+		}
+
+		out := <-stdout
+		require.NoError(t, <-stdoutErr)
+		require.Contains(t, out, "world\n")
+		require.Equal(t, out, logs)
+	})
+}
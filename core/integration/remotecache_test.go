@@ -13,6 +13,10 @@ import (
 )
 
 func getDevEngineForRemoteCache(ctx context.Context, c *dagger.Client, cache *dagger.Container, cacheName, cacheEnv string, index uint8) (devEngine *dagger.Container, endpoint string, err error) {
+	return getDevEngineWithCacheEnv(ctx, c, cache, cacheName, "_EXPERIMENTAL_DAGGER_CACHE_CONFIG", cacheEnv, index)
+}
+
+func getDevEngineWithCacheEnv(ctx context.Context, c *dagger.Client, cache *dagger.Container, cacheName, cacheEnvName, cacheEnv string, index uint8) (devEngine *dagger.Container, endpoint string, err error) {
 	id := identity.NewID()
 	networkCIDR := fmt.Sprintf("10.%d.0.0/16", 100+index)
 	devEngine = devEngineContainer(c)
@@ -30,7 +34,7 @@ func getDevEngineForRemoteCache(ctx context.Context, c *dagger.Client, cache *da
 	devEngine = devEngine.
 		WithServiceBinding(cacheName, cache).
 		WithExposedPort(1234, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
-		WithEnvVariable("_EXPERIMENTAL_DAGGER_CACHE_CONFIG", cacheEnv).
+		WithEnvVariable(cacheEnvName, cacheEnv).
 		WithEnvVariable("ENGINE_ID", id).
 		WithMountedCache("/var/lib/dagger", c.CacheVolume("dagger-dev-engine-state-"+identity.NewID())).
 		WithExec(nil, dagger.ContainerWithExecOpts{
@@ -197,4 +201,96 @@ func TestRemoteCacheS3(t *testing.T) {
 
 		require.Equal(t, shaA, shaB)
 	})
+
+	t.Run("engine-wide s3 cache backend", func(t *testing.T) {
+		c, ctx := connect(t)
+		defer c.Close()
+
+		bucket := "dagger-test-cache-backend-s3-" + identity.NewID()
+
+		s3 := c.Pipeline("s3").Container().From("minio/minio").
+			WithMountedCache("/data", c.CacheVolume("minio-cache")).
+			WithExposedPort(9000, dagger.ContainerWithExposedPortOpts{Protocol: dagger.Tcp}).
+			WithExec([]string{"server", "/data"})
+
+		s3Endpoint, err := s3.Endpoint(ctx, dagger.ContainerEndpointOpts{Port: 9000, Scheme: "http"})
+		require.NoError(t, err)
+
+		mc := c.Container().From("minio/mc").
+			WithServiceBinding("s3", s3).
+			WithEntrypoint([]string{"sh"})
+
+		minioStdout, err := mc.
+			WithExec([]string{"-c", "mc alias set minio http://s3:9000 minioadmin minioadmin && mc mb minio/" + bucket}).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, minioStdout, "Bucket created successfully")
+
+		backendEnv := "type=s3,endpoint_url=" + s3Endpoint + ",access_key_id=minioadmin,secret_access_key=minioadmin,region=mars,use_path_style=true,bucket=" + bucket + ",prefix=engines,export_period=5s"
+
+		devEngineA, endpointA, err := getDevEngineWithCacheEnv(ctx, c, s3, "s3", "_EXPERIMENTAL_DAGGER_CACHE_BACKEND", backendEnv, 0)
+		require.NoError(t, err)
+
+		cliBinPath := "/.dagger-cli"
+		// This loads the dagger-cli binary from the host into the container, that was set up by
+		// internal/mage/engine.go:test. This is used to communicate with the dev engine.
+		daggerCli := c.Host().Directory("/dagger-dev/", dagger.HostDirectoryOpts{Include: []string{"dagger"}}).File("dagger")
+
+		query := `{
+				container {
+					from(address: "alpine:3.17") {
+						withExec(args: ["sh", "-c", "head -c 128 /dev/random | sha256sum"]) {
+							stdout
+						}
+					}
+				}
+			}`
+
+		outputA, err := c.Container().From("alpine:3.17").
+			WithServiceBinding("dev-engine", devEngineA).
+			WithMountedFile(cliBinPath, daggerCli).
+			WithEnvVariable("_EXPERIMENTAL_DAGGER_CLI_BIN", cliBinPath).
+			WithEnvVariable("_EXPERIMENTAL_DAGGER_RUNNER_HOST", endpointA).
+			WithNewFile("/.dagger-query.txt", dagger.ContainerWithNewFileOpts{
+				Contents: query,
+			}).
+			WithExec([]string{
+				"sh", "-c", cliBinPath + " query --doc .dagger-query.txt",
+			}).Stdout(ctx)
+		require.NoError(t, err)
+		shaA := strings.TrimSpace(gjson.Get(outputA, "container.from.withExec.stdout").String())
+		require.NotEmpty(t, shaA, "shaA is empty")
+
+		// wait for engine A's periodic export to write the index
+		_, err = mc.
+			WithEnvVariable("CACHEBUSTER", identity.NewID()).
+			WithExec([]string{"-c", `mc alias set minio http://s3:9000 minioadmin minioadmin
+				for i in $(seq 60); do
+					mc stat minio/` + bucket + `/engines/index.json && exit 0
+					sleep 1
+				done
+				exit 1`}).
+			Sync(ctx)
+		require.NoError(t, err)
+
+		devEngineB, endpointB, err := getDevEngineWithCacheEnv(ctx, c, s3, "s3", "_EXPERIMENTAL_DAGGER_CACHE_BACKEND", backendEnv, 1)
+		require.NoError(t, err)
+
+		outputB, err := c.Container().From("alpine:3.17").
+			WithServiceBinding("dev-engine", devEngineB).
+			WithMountedFile(cliBinPath, daggerCli).
+			WithEnvVariable("_EXPERIMENTAL_DAGGER_CLI_BIN", cliBinPath).
+			WithEnvVariable("_EXPERIMENTAL_DAGGER_RUNNER_HOST", endpointB).
+			WithNewFile("/.dagger-query.txt", dagger.ContainerWithNewFileOpts{
+				Contents: query,
+			}).
+			WithExec([]string{
+				"sh", "-c", cliBinPath + " query --doc .dagger-query.txt",
+			}).Stdout(ctx)
+		require.NoError(t, err)
+		shaB := strings.TrimSpace(gjson.Get(outputB, "container.from.withExec.stdout").String())
+		require.NotEmpty(t, shaB, "shaB is empty")
+
+		require.Equal(t, shaA, shaB)
+	})
 }
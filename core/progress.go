@@ -0,0 +1,254 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/vito/progrock"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// progressSubscriberBuffer is how many updates a subscriber may fall behind
+// before it gets dropped.
+const progressSubscriberBuffer = 1000
+
+// ProgressBroker is a progrock.Writer which fans out the progress of a
+// session to any number of subscribers.
+type ProgressBroker struct {
+	subs   map[chan *progrock.StatusUpdate]struct{}
+	closed bool
+
+	// completed holds the vertexes which have completed so far, so that
+	// subscribers know not to wait for them.
+	completed map[string]struct{}
+
+	l sync.Mutex
+}
+
+var _ progrock.Writer = &ProgressBroker{}
+
+func NewProgressBroker() *ProgressBroker {
+	return &ProgressBroker{
+		subs:      map[chan *progrock.StatusUpdate]struct{}{},
+		completed: map[string]struct{}{},
+	}
+}
+
+// Subscribe returns a channel receiving every update written from now on. The
+// channel is closed once ctx is done, once the broker is closed, or if the
+// subscriber falls too far behind, rather than blocking the whole session.
+func (b *ProgressBroker) Subscribe(ctx context.Context) <-chan *progrock.StatusUpdate {
+	ch := make(chan *progrock.StatusUpdate, progressSubscriberBuffer)
+
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(ch)
+	}()
+
+	return ch
+}
+
+func (b *ProgressBroker) unsubscribe(ch chan *progrock.StatusUpdate) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *ProgressBroker) WriteStatus(update *progrock.StatusUpdate) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	for _, vtx := range update.Vertexes {
+		if vtx.Completed != nil {
+			b.completed[vtx.Id] = struct{}{}
+		}
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- update:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// VertexLogs returns a channel receiving the logs written by the vertex from
+// now on. The channel is closed once the vertex completes, or right away if
+// it already has, e.g. when its result was cached earlier in the session, as
+// well as in the same cases as Subscribe. A vertex which is never solved
+// never completes, so its logs are only closed along with ctx.
+func (b *ProgressBroker) VertexLogs(ctx context.Context, vertex string) <-chan *progrock.VertexLog {
+	out := make(chan *progrock.VertexLog)
+
+	ctx, cancel := context.WithCancel(ctx)
+	updates := b.Subscribe(ctx)
+
+	// checked after subscribing, so that it can't complete in between
+	b.l.Lock()
+	_, done := b.completed[vertex]
+	b.l.Unlock()
+
+	go func() {
+		defer close(out)
+		defer cancel()
+		if done {
+			return
+		}
+		for update := range updates {
+			for _, log := range update.Logs {
+				if log.Vertex != vertex {
+					continue
+				}
+				select {
+				case out <- log:
+				case <-ctx.Done():
+					return
+				}
+			}
+			for _, vtx := range update.Vertexes {
+				if vtx.Id == vertex && vtx.Completed != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+func (b *ProgressBroker) Close() error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+	b.closed = true
+	return nil
+}
+
+// ProgressUpdate is an update to the progress of a session.
+type ProgressUpdate struct {
+	Vertexes []ProgressVertex `json:"vertexes"`
+	Tasks    []ProgressTask   `json:"tasks"`
+	Logs     []ProgressLog    `json:"logs"`
+}
+
+// ProgressVertex is a step of a pipeline, e.g. an API call or a buildkit op.
+type ProgressVertex struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Inputs    []string `json:"inputs"`
+	Started   *string  `json:"started,omitempty"`
+	Completed *string  `json:"completed,omitempty"`
+	Cached    bool     `json:"cached"`
+	Error     *string  `json:"error,omitempty"`
+	Canceled  bool     `json:"canceled"`
+	Internal  bool     `json:"internal"`
+}
+
+// ProgressTask is a unit of work run by a vertex, e.g. pulling a layer.
+type ProgressTask struct {
+	Vertex    string  `json:"vertex"`
+	Name      string  `json:"name"`
+	Current   int64   `json:"current"`
+	Total     int64   `json:"total"`
+	Started   *string `json:"started,omitempty"`
+	Completed *string `json:"completed,omitempty"`
+}
+
+// ProgressLog is a chunk of output written by a vertex.
+type ProgressLog struct {
+	Vertex string `json:"vertex"`
+	// NB: a plain string rather than a named type, so that the enum mapping
+	// works; it's the name of the progrock.LogStream, e.g. STDOUT.
+	Stream    string  `json:"stream"`
+	Data      string  `json:"data"`
+	Timestamp *string `json:"timestamp,omitempty"`
+}
+
+func NewProgressUpdate(update *progrock.StatusUpdate) ProgressUpdate {
+	res := ProgressUpdate{
+		Vertexes: []ProgressVertex{},
+		Tasks:    []ProgressTask{},
+		Logs:     []ProgressLog{},
+	}
+	for _, vtx := range update.Vertexes {
+		res.Vertexes = append(res.Vertexes, ProgressVertex{
+			ID:        vtx.Id,
+			Name:      vtx.Name,
+			Inputs:    append([]string{}, vtx.Inputs...),
+			Started:   formatTimestamp(vtx.Started),
+			Completed: formatTimestamp(vtx.Completed),
+			Cached:    vtx.Cached,
+			Error:     vtx.Error,
+			Canceled:  vtx.Canceled,
+			Internal:  vtx.Internal,
+		})
+	}
+	for _, task := range update.Tasks {
+		res.Tasks = append(res.Tasks, ProgressTask{
+			Vertex:    task.Vertex,
+			Name:      task.Name,
+			Current:   task.Current,
+			Total:     task.Total,
+			Started:   formatTimestamp(task.Started),
+			Completed: formatTimestamp(task.Completed),
+		})
+	}
+	for _, log := range update.Logs {
+		res.Logs = append(res.Logs, NewProgressLog(log))
+	}
+	return res
+}
+
+func NewProgressLog(log *progrock.VertexLog) ProgressLog {
+	return ProgressLog{
+		Vertex:    log.Vertex,
+		Stream:    log.Stream.String(),
+		Data:      string(log.Data),
+		Timestamp: formatTimestamp(log.Timestamp),
+	}
+}
+
+func formatTimestamp(ts *timestamppb.Timestamp) *string {
+	if ts == nil {
+		return nil
+	}
+	s := ts.AsTime().Format(time.RFC3339Nano)
+	return &s
+}
+
+// ExecVertex returns the ID of the vertex running the container's last
+// command, as it's reported in the session's progress.
+func (container *Container) ExecVertex() (string, error) {
+	if container.Meta == nil || len(container.Meta.Def) == 0 {
+		return "", ErrContainerNoExec
+	}
+
+	// the last op of a definition only points to the op producing its
+	// output, which for the meta mount is the exec itself
+	var op pb.Op
+	if err := op.Unmarshal(container.Meta.Def[len(container.Meta.Def)-1]); err != nil {
+		return "", fmt.Errorf("unmarshal meta op: %w", err)
+	}
+	if len(op.Inputs) != 1 {
+		return "", fmt.Errorf("unexpected meta definition with %d inputs", len(op.Inputs))
+	}
+	return op.Inputs[0].Digest.String(), nil
+}
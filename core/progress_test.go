@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client/llb"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"github.com/vito/progrock"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestProgressBroker(t *testing.T) {
+	t.Parallel()
+
+	broker := NewProgressBroker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := broker.Subscribe(ctx)
+	other := broker.Subscribe(context.Background())
+
+	update := &progrock.StatusUpdate{
+		Logs: []*progrock.VertexLog{{Vertex: "v", Stream: progrock.LogStream_STDERR, Data: []byte("hi")}},
+	}
+	require.NoError(t, broker.WriteStatus(update))
+	require.Equal(t, update, <-updates)
+	require.Equal(t, update, <-other)
+
+	// subscribers go away with their context
+	cancel()
+	_, ok := <-updates
+	require.False(t, ok)
+
+	require.NoError(t, broker.Close())
+	_, ok = <-other
+	require.False(t, ok)
+
+	_, ok = <-broker.Subscribe(context.Background())
+	require.False(t, ok)
+
+	require.Equal(t, []ProgressLog{{Vertex: "v", Stream: "STDERR", Data: "hi"}}, NewProgressUpdate(update).Logs)
+}
+
+func TestProgressBrokerVertexLogs(t *testing.T) {
+	t.Parallel()
+
+	broker := NewProgressBroker()
+	ctx := context.Background()
+
+	logs := broker.VertexLogs(ctx, "v")
+	require.NoError(t, broker.WriteStatus(&progrock.StatusUpdate{
+		Logs: []*progrock.VertexLog{
+			{Vertex: "other", Data: []byte("nope")},
+			{Vertex: "v", Data: []byte("hi")},
+		},
+	}))
+	log := <-logs
+	require.Equal(t, "hi", string(log.Data))
+
+	require.NoError(t, broker.WriteStatus(&progrock.StatusUpdate{
+		Vertexes: []*progrock.Vertex{{Id: "v", Completed: timestamppb.Now()}},
+	}))
+	_, ok := <-logs
+	require.False(t, ok, "logs should end once the vertex completes")
+
+	// the vertex completed before subscribing, e.g. it was cached
+	select {
+	case _, ok := <-broker.VertexLogs(ctx, "v"):
+		require.False(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("logs of a completed vertex should end right away")
+	}
+}
+
+func TestContainerExecVertex(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	_, err := (&Container{}).ExecVertex()
+	require.ErrorIs(t, err, ErrContainerNoExec)
+
+	execSt := llb.Image("alpine").Run(llb.Shlex("echo hello"))
+	execSt.AddMount(metaMountDestPath, llb.Scratch())
+	execDef, err := execSt.Root().Marshal(ctx)
+	require.NoError(t, err)
+	metaDef, err := execSt.GetMount(metaMountDestPath).Marshal(ctx)
+	require.NoError(t, err)
+
+	vertex, err := (&Container{Meta: metaDef.ToPB()}).ExecVertex()
+	require.NoError(t, err)
+
+	// the exec is the root's input too
+	require.Equal(t, digest.FromBytes(execDef.Def[len(execDef.Def)-2]).String(), vertex)
+}
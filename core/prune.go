@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	bkclient "github.com/moby/buildkit/client"
+	"github.com/pkg/errors"
+)
+
+// EngineCacheRecord is a record in the engine's build cache.
+type EngineCacheRecord struct {
+	Description string `json:"description"`
+	RecordType  string `json:"recordType"`
+	Size        int64  `json:"size"`
+}
+
+// EnginePruneOpts selects which records PruneEngineCache deletes.
+type EnginePruneOpts struct {
+	// Filters selects the records to delete. A record is deleted if it
+	// matches any of the filters.
+	Filters []string
+
+	// KeepDuration keeps records which were used more recently.
+	KeepDuration time.Duration
+
+	// KeepBytes stops deleting records once the cache is below this size.
+	KeepBytes int64
+
+	// All also deletes internal records, which are normally kept.
+	All bool
+}
+
+// PruneEngineCache deletes records from the engine's build cache, the same
+// way its garbage collection does, and returns the deleted records.
+func PruneEngineCache(ctx context.Context, bk *bkclient.Client, opts EnginePruneOpts) ([]EngineCacheRecord, error) {
+	pruneOpts := []bkclient.PruneOption{
+		bkclient.WithFilter(opts.Filters),
+		bkclient.WithKeepOpt(opts.KeepDuration, opts.KeepBytes),
+	}
+	if opts.All {
+		pruneOpts = append(pruneOpts, bkclient.PruneAll)
+	}
+
+	ch := make(chan bkclient.UsageInfo)
+	done := make(chan struct{})
+	records := []EngineCacheRecord{}
+	go func() {
+		defer close(done)
+		for info := range ch {
+			records = append(records, EngineCacheRecord{
+				Description: info.Description,
+				RecordType:  string(info.RecordType),
+				Size:        info.Size,
+			})
+		}
+	}()
+
+	err := bk.Prune(ctx, ch, pruneOpts...)
+	close(ch)
+	<-done
+	if err != nil {
+		return nil, errors.Wrap(err, "prune")
+	}
+
+	return records, nil
+}
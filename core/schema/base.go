@@ -13,18 +13,21 @@ import (
 
 type InitializeArgs struct {
 	Router         *router.Router
-	Workdir        string
+	Host           *core.Host
 	Gateway        *core.GatewayClient
 	BKClient       *bkclient.Client
 	SolveOpts      bkclient.SolveOpt
 	SolveCh        chan *bkclient.SolveStatus
 	OCIStore       content.Store
 	Platform       specs.Platform
-	DisableHostRW  bool
 	Auth           *auth.RegistryAuthProvider
 	Secrets        *secret.Store
 	ProgrockSocket string
 
+	// Progress receives the session's progress, for the progress and execLogs
+	// subscriptions.
+	Progress *core.ProgressBroker
+
 	// TODO(vito): remove when stable
 	EnableServices bool
 }
@@ -45,12 +48,12 @@ func New(params InitializeArgs) (router.ExecutableSchema, error) {
 
 		progSock: params.ProgrockSocket,
 	}
-	host := core.NewHost(params.Workdir, params.DisableHostRW)
+	host := params.Host
 	return router.MergeExecutableSchemas("core",
 		&querySchema{base},
 		&directorySchema{base, host},
 		&fileSchema{base, host},
-		&gitSchema{base},
+		&gitSchema{base, host},
 		&containerSchema{base, host, params.OCIStore},
 		&cacheSchema{base},
 		&secretSchema{base},
@@ -59,6 +62,7 @@ func New(params InitializeArgs) (router.ExecutableSchema, error) {
 		&httpSchema{base},
 		&platformSchema{base},
 		&socketSchema{base, host},
+		&progressSchema{base, params.Progress},
 	)
 }
 
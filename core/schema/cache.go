@@ -1,6 +1,9 @@
 package schema
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/router"
 )
@@ -25,10 +28,18 @@ func (s *cacheSchema) Resolvers() router.Resolvers {
 	return router.Resolvers{
 		"CacheID": cacheIDResolver,
 		"Query": router.ObjectResolver{
-			"cacheVolume": router.ToResolver(s.cacheVolume),
+			"cacheVolume":      router.ToResolver(s.cacheVolume),
+			"cacheVolumes":     router.ToResolver(s.cacheVolumes),
+			"pruneEngineCache": router.ToResolver(s.pruneEngineCache),
 		},
 		"CacheVolume": router.ObjectResolver{
-			"id": router.ToResolver(s.id),
+			"id":           router.ToResolver(s.id),
+			"checksum":     router.ToResolver(s.checksum),
+			"size":         router.ToResolver(s.size),
+			"lastUsed":     router.ToResolver(s.lastUsed),
+			"prune":        router.ToResolver(s.prune),
+			"snapshot":     router.ToResolver(s.snapshot),
+			"withContents": router.ToResolver(s.withContents),
 		},
 	}
 }
@@ -45,10 +56,92 @@ type cacheArgs struct {
 	Key string
 }
 
-func (s *cacheSchema) cacheVolume(ctx *router.Context, parent any, args cacheArgs) (*core.CacheVolume, error) {
+func (s *cacheSchema) cacheVolume(ctx *router.Context, parent *core.Query, args cacheArgs) (*core.CacheVolume, error) {
 	// TODO(vito): inject some sort of scope/session/project/user derived value
 	// here instead of a static value
 	//
 	// we have to inject something so we can tell it's a valid ID
-	return core.NewCache(args.Key), nil
+	cache := core.NewCache(args.Key)
+	cache.Pipeline = parent.PipelinePath()
+	return cache, nil
+}
+
+type cacheVolumesArgs struct {
+	Checksums []string
+}
+
+func (s *cacheSchema) cacheVolumes(ctx *router.Context, parent any, args cacheVolumesArgs) ([]*core.CacheVolume, error) {
+	return core.CacheVolumes(ctx, s.bkClient, args.Checksums)
+}
+
+type pruneEngineCacheArgs struct {
+	Filters      []string
+	KeepDuration int
+	KeepBytes    int
+	All          bool
+}
+
+func (s *cacheSchema) pruneEngineCache(ctx *router.Context, parent any, args pruneEngineCacheArgs) ([]core.EngineCacheRecord, error) {
+	if args.KeepDuration < 0 || args.KeepBytes < 0 {
+		return nil, fmt.Errorf("keepDuration and keepBytes must not be negative")
+	}
+
+	return core.PruneEngineCache(ctx, s.bkClient, core.EnginePruneOpts{
+		Filters:      args.Filters,
+		KeepDuration: time.Duration(args.KeepDuration) * time.Second,
+		KeepBytes:    int64(args.KeepBytes),
+		All:          args.All,
+	})
+}
+
+func (s *cacheSchema) checksum(ctx *router.Context, parent *core.CacheVolume, args any) (string, error) {
+	return parent.Sum(), nil
+}
+
+func (s *cacheSchema) size(ctx *router.Context, parent *core.CacheVolume, args any) (int, error) {
+	usage, err := parent.Usage(ctx, s.bkClient)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(usage.Size), nil
+}
+
+func (s *cacheSchema) lastUsed(ctx *router.Context, parent *core.CacheVolume, args any) (*int, error) {
+	usage, err := parent.Usage(ctx, s.bkClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if usage.LastUsed == nil {
+		return nil, nil
+	}
+
+	unix := int(usage.LastUsed.Unix())
+	return &unix, nil
+}
+
+func (s *cacheSchema) prune(ctx *router.Context, parent *core.CacheVolume, args any) (core.CacheID, error) {
+	if err := parent.Prune(ctx, s.bkClient); err != nil {
+		return "", err
+	}
+
+	return parent.ID()
+}
+
+func (s *cacheSchema) snapshot(ctx *router.Context, parent *core.CacheVolume, args any) (*core.Directory, error) {
+	return parent.Snapshot(ctx, s.gw, s.platform)
+}
+
+type cacheWithContentsArgs struct {
+	Source core.DirectoryID
+}
+
+func (s *cacheSchema) withContents(ctx *router.Context, parent *core.CacheVolume, args cacheWithContentsArgs) (*core.CacheVolume, error) {
+	dir, err := args.Source.ToDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	return parent.WithContents(dir), nil
 }
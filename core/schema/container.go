@@ -54,8 +54,8 @@ func (s *containerSchema) Resolvers() router.Resolvers {
 			"withUser":             router.ToResolver(s.withUser),
 			"workdir":              router.ToResolver(s.workdir),
 			"withWorkdir":          router.ToResolver(s.withWorkdir),
-			"envVariables":         router.ToResolver(s.envVariables),
-			"envVariable":          router.ToResolver(s.envVariable),
+			"envVariables":         router.ToPureResolver(s.envVariables),
+			"envVariable":          router.ToPureResolver(s.envVariable),
 			"withEnvVariable":      router.ToResolver(s.withEnvVariable),
 			"withSecretVariable":   router.ToResolver(s.withSecretVariable),
 			"withoutEnvVariable":   router.ToResolver(s.withoutEnvVariable),
@@ -426,9 +426,10 @@ func (s *containerSchema) label(ctx *router.Context, parent *core.Container, arg
 }
 
 type containerWithMountedDirectoryArgs struct {
-	Path   string
-	Source core.DirectoryID
-	Owner  string
+	Path     string
+	Source   core.DirectoryID
+	Owner    string
+	ReadOnly bool
 }
 
 func (s *containerSchema) withMountedDirectory(ctx *router.Context, parent *core.Container, args containerWithMountedDirectoryArgs) (*core.Container, error) {
@@ -436,7 +437,7 @@ func (s *containerSchema) withMountedDirectory(ctx *router.Context, parent *core
 	if err != nil {
 		return nil, err
 	}
-	return parent.WithMountedDirectory(ctx, s.gw, args.Path, dir, args.Owner)
+	return parent.WithMountedDirectory(ctx, s.gw, args.Path, dir, args.Owner, args.ReadOnly)
 }
 
 type containerPublishArgs struct {
@@ -450,9 +451,10 @@ func (s *containerSchema) publish(ctx *router.Context, parent *core.Container, a
 }
 
 type containerWithMountedFileArgs struct {
-	Path   string
-	Source core.FileID
-	Owner  string
+	Path     string
+	Source   core.FileID
+	Owner    string
+	ReadOnly bool
 }
 
 func (s *containerSchema) withMountedFile(ctx *router.Context, parent *core.Container, args containerWithMountedFileArgs) (*core.Container, error) {
@@ -460,7 +462,7 @@ func (s *containerSchema) withMountedFile(ctx *router.Context, parent *core.Cont
 	if err != nil {
 		return nil, err
 	}
-	return parent.WithMountedFile(ctx, s.gw, args.Path, file, args.Owner)
+	return parent.WithMountedFile(ctx, s.gw, args.Path, file, args.Owner, args.ReadOnly)
 }
 
 type containerWithMountedCacheArgs struct {
@@ -469,6 +471,7 @@ type containerWithMountedCacheArgs struct {
 	Source      core.DirectoryID
 	Concurrency core.CacheSharingMode
 	Owner       string
+	ReadOnly    bool
 }
 
 func (s *containerSchema) withMountedCache(ctx *router.Context, parent *core.Container, args containerWithMountedCacheArgs) (*core.Container, error) {
@@ -486,15 +489,16 @@ func (s *containerSchema) withMountedCache(ctx *router.Context, parent *core.Con
 		return nil, err
 	}
 
-	return parent.WithMountedCache(ctx, s.gw, args.Path, cache, dir, args.Concurrency, args.Owner)
+	return parent.WithMountedCache(ctx, s.gw, args.Path, cache, dir, args.Concurrency, args.Owner, args.ReadOnly)
 }
 
 type containerWithMountedTempArgs struct {
 	Path string
+	Size int
 }
 
 func (s *containerSchema) withMountedTemp(ctx *router.Context, parent *core.Container, args containerWithMountedTempArgs) (*core.Container, error) {
-	return parent.WithMountedTemp(ctx, args.Path)
+	return parent.WithMountedTemp(ctx, args.Path, args.Size)
 }
 
 type containerWithoutMountArgs struct {
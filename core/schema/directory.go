@@ -37,7 +37,7 @@ func (s *directorySchema) Resolvers() router.Resolvers {
 		"Directory": router.ToIDableObjectResolver(core.DirectoryID.ToDirectory, router.ObjectResolver{
 			"id":               router.ToResolver(s.id),
 			"pipeline":         router.ToResolver(s.pipeline),
-			"entries":          router.ToResolver(s.entries),
+			"entries":          router.ToPureResolver(s.entries),
 			"file":             router.ToResolver(s.file),
 			"withFile":         router.ToResolver(s.withFile),
 			"withNewFile":      router.ToResolver(s.withNewFile),
@@ -48,7 +48,10 @@ func (s *directorySchema) Resolvers() router.Resolvers {
 			"withNewDirectory": router.ToResolver(s.withNewDirectory),
 			"withoutDirectory": router.ToResolver(s.withoutDirectory),
 			"diff":             router.ToResolver(s.diff),
+			"changes":          router.ToResolver(s.changes),
+			"asPatch":          router.ToResolver(s.asPatch),
 			"export":           router.ToResolver(s.export),
+			"exportDryRun":     router.ToResolver(s.exportDryRun),
 			"dockerBuild":      router.ToResolver(s.dockerBuild),
 		}),
 	}
@@ -194,12 +197,35 @@ func (s *directorySchema) diff(ctx *router.Context, parent *core.Directory, args
 	return parent.Diff(ctx, dir)
 }
 
+func (s *directorySchema) changes(ctx *router.Context, parent *core.Directory, args diffArgs) ([]Change, error) {
+	dir, err := args.Other.ToDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := parent.Changes(ctx, s.gw, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return toChanges(changes), nil
+}
+
+func (s *directorySchema) asPatch(ctx *router.Context, parent *core.Directory, args diffArgs) (*core.File, error) {
+	dir, err := args.Other.ToDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return parent.Patch(ctx, s.gw, dir)
+}
+
 type dirExportArgs struct {
 	Path string
+	Wipe bool
 }
 
 func (s *directorySchema) export(ctx *router.Context, parent *core.Directory, args dirExportArgs) (bool, error) {
-	err := parent.Export(ctx, s.host, args.Path, s.bkClient, s.solveOpts, s.solveCh)
+	err := parent.Export(ctx, s.host, args.Path, args.Wipe, s.bkClient, s.solveOpts, s.solveCh)
 	if err != nil {
 		return false, err
 	}
@@ -207,6 +233,37 @@ func (s *directorySchema) export(ctx *router.Context, parent *core.Directory, ar
 	return true, nil
 }
 
+// NB: we have to use a different type with a regular string Kind field so
+// that the enum mapping works.
+type Change struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	OldSize *int   `json:"oldSize,omitempty"`
+	NewSize *int   `json:"newSize,omitempty"`
+}
+
+func toChanges(changes []core.Change) []Change {
+	res := make([]Change, 0, len(changes))
+	for _, change := range changes {
+		res = append(res, Change{
+			Kind:    string(change.Kind),
+			Path:    change.Path,
+			OldSize: change.OldSize,
+			NewSize: change.NewSize,
+		})
+	}
+	return res
+}
+
+func (s *directorySchema) exportDryRun(ctx *router.Context, parent *core.Directory, args dirExportArgs) ([]Change, error) {
+	changes, err := parent.ExportChanges(ctx, s.gw, s.host, args.Path, args.Wipe)
+	if err != nil {
+		return nil, err
+	}
+
+	return toChanges(changes), nil
+}
+
 type dirDockerBuildArgs struct {
 	Platform   *specs.Platform
 	Dockerfile string
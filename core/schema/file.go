@@ -31,9 +31,9 @@ func (s *fileSchema) Resolvers() router.Resolvers {
 		},
 		"File": router.ToIDableObjectResolver(core.FileID.ToFile, router.ObjectResolver{
 			"id":             router.ToResolver(s.id),
-			"contents":       router.ToResolver(s.contents),
+			"contents":       router.ToPureResolver(s.contents),
 			"secret":         router.ToResolver(s.secret),
-			"size":           router.ToResolver(s.size),
+			"size":           router.ToPureResolver(s.size),
 			"export":         router.ToResolver(s.export),
 			"withTimestamps": router.ToResolver(s.withTimestamps),
 		}),
@@ -1,16 +1,21 @@
 package schema
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/core/pipeline"
 	"github.com/dagger/dagger/router"
-	"github.com/moby/buildkit/client/llb"
 )
 
 var _ router.ExecutableSchema = &gitSchema{}
 
 type gitSchema struct {
 	*baseSchema
+
+	host *core.Host
 }
 
 func (s *gitSchema) Name() string {
@@ -27,14 +32,20 @@ func (s *gitSchema) Resolvers() router.Resolvers {
 			"git": router.ToResolver(s.git),
 		},
 		"GitRepository": router.ObjectResolver{
+			"head":     router.ToResolver(s.head),
+			"ref":      router.ToResolver(s.ref),
 			"branches": router.ToResolver(s.branches),
 			"branch":   router.ToResolver(s.branch),
 			"tags":     router.ToResolver(s.tags),
 			"tag":      router.ToResolver(s.tag),
 			"commit":   router.ToResolver(s.commit),
+			"refs":     router.ToResolver(s.refs),
+			"dirty":    router.ToResolver(s.dirty),
 		},
 		"GitRef": router.ObjectResolver{
 			"digest": router.ToResolver(s.digest),
+			"commit": router.ToResolver(s.refCommit),
+			"log":    router.ToResolver(s.log),
 			"tree":   router.ToResolver(s.tree),
 		},
 	}
@@ -45,10 +56,54 @@ func (s *gitSchema) Dependencies() []router.ExecutableSchema {
 }
 
 type gitRepository struct {
-	URL         string            `json:"url"`
-	KeepGitDir  bool              `json:"keepGitDir"`
-	Pipeline    pipeline.Path     `json:"pipeline"`
-	ServiceHost *core.ContainerID `json:"serviceHost,omitempty"`
+	URL            string            `json:"url"`
+	KeepGitDir     bool              `json:"keepGitDir"`
+	Depth          int               `json:"depth"`
+	SkipSubmodules bool              `json:"skipSubmodules"`
+	AuthToken      core.SecretID     `json:"authToken,omitempty"`
+	AuthHeader     core.SecretID     `json:"authHeader,omitempty"`
+	SSHKnownHosts  string            `json:"sshKnownHosts,omitempty"`
+	SSHAuthSocket  core.SocketID     `json:"sshAuthSocket,omitempty"`
+	SSHKey         core.SecretID     `json:"sshKey,omitempty"`
+	Pipeline       pipeline.Path     `json:"pipeline"`
+	ServiceHost    *core.ContainerID `json:"serviceHost,omitempty"`
+
+	// HostPath is the root of the working tree of a repository on the host,
+	// which is read directly rather than fetched from URL.
+	HostPath string `json:"hostPath,omitempty"`
+}
+
+// location returns where the repository is, for error messages.
+func (repo gitRepository) location() string {
+	if repo.HostPath != "" {
+		return repo.HostPath
+	}
+	return repo.URL
+}
+
+// fetchOpts returns the options for fetching from the repository which don't
+// depend on the ref.
+func (repo gitRepository) fetchOpts() core.GitFetchOpts {
+	return core.GitFetchOpts{
+		KeepGitDir:     repo.KeepGitDir,
+		Depth:          repo.Depth,
+		SkipSubmodules: repo.SkipSubmodules,
+		AuthToken:      repo.AuthToken,
+		AuthHeader:     repo.AuthHeader,
+		SSHKnownHosts:  repo.SSHKnownHosts,
+		SSHAuthSocket:  repo.SSHAuthSocket,
+		SSHKey:         repo.SSHKey,
+	}
+}
+
+// services returns the services which must be running to reach the
+// repository.
+func (repo gitRepository) services() core.ServiceBindings {
+	if repo.ServiceHost == nil {
+		return nil
+	}
+
+	return core.ServiceBindings{*repo.ServiceHost: nil}
 }
 
 type gitRef struct {
@@ -59,15 +114,51 @@ type gitRef struct {
 type gitArgs struct {
 	URL                     string            `json:"url"`
 	KeepGitDir              bool              `json:"keepGitDir"`
+	Depth                   int               `json:"depth"`
+	SkipSubmodules          bool              `json:"skipSubmodules"`
+	HTTPAuthToken           core.SecretID     `json:"httpAuthToken"`
+	HTTPAuthHeader          core.SecretID     `json:"httpAuthHeader"`
+	SSHKnownHosts           string            `json:"sshKnownHosts"`
+	SSHAuthSocket           core.SocketID     `json:"sshAuthSocket"`
+	SSHKey                  core.SecretID     `json:"sshKey"`
 	ExperimentalServiceHost *core.ContainerID `json:"experimentalServiceHost"`
 }
 
 func (s *gitSchema) git(ctx *router.Context, parent *core.Query, args gitArgs) (gitRepository, error) {
+	if args.Depth < 0 {
+		return gitRepository{}, fmt.Errorf("invalid depth %d: must not be negative", args.Depth)
+	}
+
 	return gitRepository{
-		URL:         args.URL,
-		KeepGitDir:  args.KeepGitDir,
-		ServiceHost: args.ExperimentalServiceHost,
-		Pipeline:    parent.PipelinePath(),
+		URL:            args.URL,
+		KeepGitDir:     args.KeepGitDir,
+		Depth:          args.Depth,
+		SkipSubmodules: args.SkipSubmodules,
+		AuthToken:      args.HTTPAuthToken,
+		AuthHeader:     args.HTTPAuthHeader,
+		SSHKnownHosts:  args.SSHKnownHosts,
+		SSHAuthSocket:  args.SSHAuthSocket,
+		SSHKey:         args.SSHKey,
+		ServiceHost:    args.ExperimentalServiceHost,
+		Pipeline:       parent.PipelinePath(),
+	}, nil
+}
+
+func (s *gitSchema) head(ctx *router.Context, parent gitRepository, args any) (gitRef, error) {
+	// an empty name resolves to the default branch
+	return gitRef{
+		Repository: parent,
+	}, nil
+}
+
+type refArgs struct {
+	Name string
+}
+
+func (s *gitSchema) ref(ctx *router.Context, parent gitRepository, args refArgs) (gitRef, error) {
+	return gitRef{
+		Repository: parent,
+		Name:       args.Name,
 	}, nil
 }
 
@@ -93,8 +184,8 @@ func (s *gitSchema) branch(ctx *router.Context, parent gitRepository, args branc
 	}, nil
 }
 
-func (s *gitSchema) branches(ctx *router.Context, parent any, args any) (any, error) {
-	return nil, ErrNotImplementedYet
+func (s *gitSchema) branches(ctx *router.Context, parent gitRepository, args any) ([]string, error) {
+	return s.refNames(ctx, parent, "refs/heads/")
 }
 
 type tagArgs struct {
@@ -108,35 +199,161 @@ func (s *gitSchema) tag(ctx *router.Context, parent gitRepository, args tagArgs)
 	}, nil
 }
 
-func (s *gitSchema) tags(ctx *router.Context, parent any, args any) (any, error) {
-	return nil, ErrNotImplementedYet
+func (s *gitSchema) tags(ctx *router.Context, parent gitRepository, args any) ([]string, error) {
+	return s.refNames(ctx, parent, "refs/tags/")
+}
+
+// refNames lists the names of the refs beneath the given prefix, without the
+// prefix.
+func (s *gitSchema) refNames(ctx *router.Context, parent gitRepository, prefix string) ([]string, error) {
+	refs, err := s.lsRemote(ctx, parent, prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, ref := range refs {
+		if name, found := strings.CutPrefix(ref.Name, prefix); found {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+type refsArgs struct {
+	Pattern string
 }
 
-func (s *gitSchema) digest(ctx *router.Context, parent any, args any) (any, error) {
-	return nil, ErrNotImplementedYet
+func (s *gitSchema) refs(ctx *router.Context, parent gitRepository, args refsArgs) ([]core.GitRemoteRef, error) {
+	return s.lsRemote(ctx, parent, args.Pattern)
+}
+
+func (s *gitSchema) lsRemote(ctx *router.Context, repo gitRepository, pattern string) ([]core.GitRemoteRef, error) {
+	patterns := []string{}
+	if pattern != "" {
+		patterns = append(patterns, pattern)
+	}
+
+	if repo.HostPath != "" {
+		return core.HostGitRefs(repo.HostPath, patterns)
+	}
+
+	return core.GitLsRemote(ctx, s.gw, repo.URL, patterns, repo.fetchOpts(), repo.Pipeline, s.platform, repo.services())
+}
+
+func (s *gitSchema) dirty(ctx *router.Context, parent gitRepository, args any) (bool, error) {
+	if parent.HostPath == "" {
+		return false, fmt.Errorf("%s has no working tree: only repositories on the host do", parent.URL)
+	}
+
+	return core.HostGitDirty(parent.HostPath)
+}
+
+func (s *gitSchema) digest(ctx *router.Context, parent gitRef, args any) (string, error) {
+	if parent.Repository.HostPath != "" {
+		return core.HostGitResolve(parent.Repository.HostPath, parent.Name)
+	}
+
+	if core.IsGitCommitSHA(parent.Name) {
+		return parent.Name, nil
+	}
+
+	name := parent.Name
+	if name == "" {
+		name = "HEAD"
+	}
+
+	refs, err := s.lsRemote(ctx, parent.Repository, name)
+	if err != nil {
+		return "", err
+	}
+
+	// prefer an exact match, in the same order of precedence as git, since the
+	// pattern may match the tails of other refs too (e.g. "main" matches
+	// "refs/heads/main" and "refs/remotes/origin/main")
+	for _, candidate := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name} {
+		for _, ref := range refs {
+			if ref.Name == candidate {
+				return ref.SHA, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("ref %q not found in %s", name, parent.Repository.location())
+}
+
+func (s *gitSchema) refCommit(ctx *router.Context, parent gitRef, args any) (core.GitCommit, error) {
+	commits, err := s.gitLog(ctx, parent, 1)
+	if err != nil {
+		return core.GitCommit{}, err
+	}
+
+	if len(commits) == 0 {
+		return core.GitCommit{}, fmt.Errorf("no commit found for ref %q", parent.Name)
+	}
+
+	return commits[0], nil
+}
+
+type gitLogArgs struct {
+	Limit int
+}
+
+func (s *gitSchema) log(ctx *router.Context, parent gitRef, args gitLogArgs) ([]core.GitCommit, error) {
+	if args.Limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d: must not be negative", args.Limit)
+	}
+
+	return s.gitLog(ctx, parent, args.Limit)
+}
+
+func (s *gitSchema) gitLog(ctx *router.Context, ref gitRef, limit int) ([]core.GitCommit, error) {
+	if ref.Repository.HostPath != "" {
+		return core.HostGitLog(ref.Repository.HostPath, ref.Name, limit)
+	}
+
+	return core.GitLog(ctx, s.gw, ref.Repository.URL, ref.Name, limit, ref.Repository.fetchOpts(), ref.Repository.Pipeline, s.platform, ref.Repository.services())
 }
 
 type gitTreeArgs struct {
-	SSHKnownHosts string        `json:"sshKnownHosts"`
-	SSHAuthSocket core.SocketID `json:"sshAuthSocket"`
+	SSHKnownHosts      string        `json:"sshKnownHosts"`
+	SSHAuthSocket      core.SocketID `json:"sshAuthSocket"`
+	SSHKey             core.SecretID `json:"sshKey"`
+	IncludeUncommitted bool          `json:"includeUncommitted"`
 }
 
 func (s *gitSchema) tree(ctx *router.Context, parent gitRef, args gitTreeArgs) (*core.Directory, error) {
-	opts := []llb.GitOption{}
+	if args.IncludeUncommitted {
+		if parent.Repository.HostPath == "" || parent.Name != "" {
+			return nil, errors.New("uncommitted changes can only be included in the head of a repository on the host")
+		}
+
+		return s.host.Directory(ctx, parent.Repository.HostPath, parent.Repository.Pipeline, s.platform, core.CopyFilter{
+			Exclude: []string{".git"},
+		}, true, false)
+	}
+
+	if parent.Repository.HostPath != "" {
+		sha, err := core.HostGitResolve(parent.Repository.HostPath, parent.Name)
+		if err != nil {
+			return nil, err
+		}
 
-	if parent.Repository.KeepGitDir {
-		opts = append(opts, llb.KeepGitDir())
+		return s.host.GitTree(ctx, s.gw, parent.Repository.HostPath, sha, parent.Repository.Pipeline, s.platform)
+	}
+
+	// the tree's own SSH options take precedence over the repository's
+	opts := parent.Repository.fetchOpts()
+	if args.SSHKey != "" {
+		opts.SSHKey = args.SSHKey
 	}
 	if args.SSHKnownHosts != "" {
-		opts = append(opts, llb.KnownSSHHosts(args.SSHKnownHosts))
+		opts.SSHKnownHosts = args.SSHKnownHosts
 	}
 	if args.SSHAuthSocket != "" {
-		opts = append(opts, llb.MountSSHSock(args.SSHAuthSocket.LLBID()))
-	}
-	var svcs core.ServiceBindings
-	if parent.Repository.ServiceHost != nil {
-		svcs = core.ServiceBindings{*parent.Repository.ServiceHost: nil}
+		opts.SSHAuthSocket = args.SSHAuthSocket
 	}
-	st := llb.Git(parent.Repository.URL, parent.Name, opts...)
-	return core.NewDirectorySt(ctx, st, "", parent.Repository.Pipeline, s.platform, svcs)
+	st := core.GitFetch(s.gw, parent.Repository.URL, parent.Name, opts)
+	return core.NewDirectorySt(ctx, st, "", parent.Repository.Pipeline, s.platform, parent.Repository.services())
 }
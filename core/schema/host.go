@@ -34,6 +34,8 @@ func (s *hostSchema) Resolvers() router.Resolvers {
 			"file":        router.ToResolver(s.file),
 			"envVariable": router.ToResolver(s.envVariable),
 			"unixSocket":  router.ToResolver(s.socket),
+
+			"gitRepository": router.ToResolver(s.gitRepository),
 		},
 		"HostVariable": router.ObjectResolver{
 			"value":  router.ToResolver(s.envVariableValue),
@@ -51,7 +53,7 @@ type hostWorkdirArgs struct {
 }
 
 func (s *hostSchema) workdir(ctx *router.Context, parent *core.Query, args hostWorkdirArgs) (*core.Directory, error) {
-	return s.host.Directory(ctx, ".", parent.PipelinePath(), s.platform, args.CopyFilter)
+	return s.host.Directory(ctx, ".", parent.PipelinePath(), s.platform, args.CopyFilter, false, false)
 }
 
 type hostVariableArgs struct {
@@ -73,13 +75,15 @@ func (s *hostSchema) envVariableSecret(ctx *router.Context, parent *core.HostVar
 }
 
 type hostDirectoryArgs struct {
-	Path string
+	Path      string
+	Gitignore bool
+	Watch     bool
 
 	core.CopyFilter
 }
 
 func (s *hostSchema) directory(ctx *router.Context, parent *core.Query, args hostDirectoryArgs) (*core.Directory, error) {
-	return s.host.Directory(ctx, args.Path, parent.PipelinePath(), s.platform, args.CopyFilter)
+	return s.host.Directory(ctx, args.Path, parent.PipelinePath(), s.platform, args.CopyFilter, args.Gitignore, args.Watch)
 }
 
 type hostSocketArgs struct {
@@ -97,3 +101,19 @@ type hostFileArgs struct {
 func (s *hostSchema) file(ctx *router.Context, parent *core.Query, args hostFileArgs) (*core.File, error) {
 	return s.host.File(ctx, args.Path, parent.PipelinePath(), s.platform)
 }
+
+type hostGitRepositoryArgs struct {
+	Path string
+}
+
+func (s *hostSchema) gitRepository(ctx *router.Context, parent *core.Query, args hostGitRepositoryArgs) (gitRepository, error) {
+	path, err := s.host.GitRepositoryPath(args.Path)
+	if err != nil {
+		return gitRepository{}, err
+	}
+
+	return gitRepository{
+		HostPath: path,
+		Pipeline: parent.PipelinePath(),
+	}, nil
+}
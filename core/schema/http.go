@@ -1,9 +1,11 @@
 package schema
 
 import (
+	"fmt"
+	"path"
+
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/router"
-	"github.com/moby/buildkit/client/llb"
 	"github.com/opencontainers/go-digest"
 )
 
@@ -35,18 +37,43 @@ func (s *httpSchema) Dependencies() []router.ExecutableSchema {
 
 type httpArgs struct {
 	URL                     string            `json:"url"`
+	Checksum                string            `json:"checksum"`
+	Headers                 []core.HTTPHeader `json:"headers"`
+	AuthHeader              core.SecretID     `json:"authHeader"`
+	Permissions             int               `json:"permissions"`
+	Name                    string            `json:"name"`
 	ExperimentalServiceHost *core.ContainerID `json:"experimentalServiceHost"`
 }
 
 func (s *httpSchema) http(ctx *router.Context, parent *core.Query, args httpArgs) (*core.File, error) {
 	pipeline := parent.PipelinePath()
 
+	if args.Name != "" && (path.Base(args.Name) != args.Name || args.Name == "." || args.Name == ".." || args.Name == "/") {
+		return nil, fmt.Errorf("invalid name %q: must be a file name, not a path", args.Name)
+	}
+
+	if args.Permissions < 0 || args.Permissions > 0o777 {
+		return nil, fmt.Errorf("invalid permissions %d: must be between 0 and 511 (0777)", args.Permissions)
+	}
+
 	// Use a filename that is set to the URL. Buildkit internally stores some cache metadata of etags
 	// and http checksums using an id based on this name, so setting it to the URL maximizes our chances
 	// of following more optimized cache codepaths.
 	// Do a hash encode to prevent conflicts with use of `/` in the URL while also not hitting max filename limits
 	filename := digest.FromString(args.URL).Encoded()
-	st := llb.HTTP(args.URL, llb.Filename(filename))
+	if args.Name != "" {
+		filename = args.Name
+	}
+
+	st, err := core.HTTPFetch(s.gw, args.URL, filename, core.HTTPFetchOpts{
+		Checksum:    digest.Digest(args.Checksum),
+		Headers:     args.Headers,
+		AuthHeader:  args.AuthHeader,
+		Permissions: args.Permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	svcs := core.ServiceBindings{}
 	if args.ExperimentalServiceHost != nil {
@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"errors"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/router"
+)
+
+type progressSchema struct {
+	*baseSchema
+
+	progress *core.ProgressBroker
+}
+
+var _ router.ExecutableSchema = &progressSchema{}
+
+func (s *progressSchema) Name() string {
+	return "progress"
+}
+
+func (s *progressSchema) Schema() string {
+	return Progress
+}
+
+func (s *progressSchema) Resolvers() router.Resolvers {
+	return router.Resolvers{
+		"Subscription": router.SubscriptionResolver{
+			"progress": router.ToSubscriber(s.progressUpdates),
+			"execLogs": router.ToSubscriber(s.execLogs),
+		},
+	}
+}
+
+func (s *progressSchema) Dependencies() []router.ExecutableSchema {
+	return nil
+}
+
+var errProgressUnavailable = errors.New("progress is not available in this session")
+
+func (s *progressSchema) progressUpdates(ctx *router.Context, _ any, _ any) (<-chan core.ProgressUpdate, error) {
+	if s.progress == nil {
+		return nil, errProgressUnavailable
+	}
+
+	updates := s.progress.Subscribe(ctx)
+	out := make(chan core.ProgressUpdate)
+	go func() {
+		defer close(out)
+		for update := range updates {
+			select {
+			case out <- core.NewProgressUpdate(update):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+type execLogsArgs struct {
+	Container core.ContainerID
+}
+
+func (s *progressSchema) execLogs(ctx *router.Context, _ any, args execLogsArgs) (<-chan core.ProgressLog, error) {
+	if s.progress == nil {
+		return nil, errProgressUnavailable
+	}
+
+	container, err := args.Container.ToContainer()
+	if err != nil {
+		return nil, err
+	}
+	vertex, err := container.ExecVertex()
+	if err != nil {
+		return nil, err
+	}
+
+	logs := s.progress.VertexLogs(ctx, vertex)
+	out := make(chan core.ProgressLog)
+	go func() {
+		defer close(out)
+		for log := range logs {
+			select {
+			case out <- core.NewProgressLog(log):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -75,6 +75,25 @@ func defToState(def *pb.Definition) (llb.State, error) {
 	return llb.NewState(defop), nil
 }
 
+// hasLocalSource returns whether the definition reads from the host, i.e.
+// has a local:// source op.
+func hasLocalSource(def *pb.Definition) bool {
+	if def == nil {
+		return false
+	}
+	for _, dt := range def.Def {
+		var op pb.Op
+		if err := (&op).Unmarshal(dt); err != nil {
+			// can't tell, so assume it may
+			return true
+		}
+		if src := op.GetSource(); src != nil && strings.HasPrefix(src.Identifier, "local://") {
+			return true
+		}
+	}
+	return false
+}
+
 // mirrorCh mirrors messages from one channel to another, protecting the
 // destination channel from being closed.
 //
@@ -308,3 +327,23 @@ func mergeImageConfig(dst, src specs.ImageConfig) specs.ImageConfig {
 
 	return res
 }
+
+// toolsImageRef is the image the engine runs its own helper commands in,
+// e.g. git and curl. It's pinned by digest so that they run the same way
+// everywhere.
+const toolsImageRef = "docker.io/library/alpine:3.17.0@sha256:8914eb54f968791faf6a8638949e480fef81e697984fba772b3976835194c6d4"
+
+// toolsImage returns the image the engine runs its own helper commands in.
+func toolsImage(gw bkgw.Client) llb.State {
+	return llb.Image(toolsImageRef, llb.WithMetaResolver(gw))
+}
+
+// toolsBase returns the tools image with every tool the helper commands need
+// installed, in a single step so that they all share the same cached layer.
+func toolsBase(gw bkgw.Client) llb.State {
+	return toolsImage(gw).
+		Run(
+			llb.Shlex(`apk add --no-cache git openssh-client curl`),
+			llb.WithCustomName("install engine tools"),
+		).Root()
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moby/buildkit/client/llb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasLocalSource(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name  string
+		st    llb.State
+		local bool
+	}{
+		{name: "scratch", st: llb.Scratch()},
+		{name: "image", st: llb.Image("alpine")},
+		{name: "local", st: llb.Local("src"), local: true},
+		{
+			name:  "copied from local",
+			st:    llb.Scratch().File(llb.Copy(llb.Local("src"), "/", "/")),
+			local: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			def, err := tc.st.Marshal(ctx)
+			require.NoError(t, err)
+			require.Equal(t, tc.local, hasLocalSource(def.ToPB()))
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package core
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/moby/patternmatcher"
+)
+
+// hostWatcher observes host directories loaded with watch enabled and counts
+// how many times each of them has changed.
+//
+// The count is mixed into the directory's LLB so that a re-sync isn't
+// deduplicated against the previous one within the same session, while the
+// shared key stays the same so that filesync only transfers what changed.
+type hostWatcher struct {
+	fsw *fsnotify.Watcher
+
+	// onChange is called with the root of a watched directory whenever
+	// anything beneath it changes.
+	onChange func(string)
+
+	// roots are the watched directories.
+	roots map[string]*watchedRoot
+	l     sync.Mutex
+}
+
+// watchedRoot is a directory loaded with watch enabled, possibly several
+// times with different filters.
+type watchedRoot struct {
+	// generation counts the changes seen beneath the directory.
+	generation int
+
+	// filters are the filters the directory was loaded with, by key.
+	filters map[string]*watchFilter
+}
+
+// watchFilter matches the paths synced by a host directory, so that paths it
+// excludes, e.g. node_modules, don't use up inotify watches nor count as
+// changes, e.g. when a pipeline writes its output into the directory.
+type watchFilter struct {
+	include *patternmatcher.PatternMatcher
+	exclude *patternmatcher.PatternMatcher
+}
+
+func newWatchFilter(filter CopyFilter) (*watchFilter, error) {
+	f := &watchFilter{}
+	if len(filter.Include) > 0 {
+		include, err := patternmatcher.New(filter.Include)
+		if err != nil {
+			return nil, err
+		}
+		f.include = include
+	}
+	if len(filter.Exclude) > 0 {
+		exclude, err := patternmatcher.New(filter.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = exclude
+	}
+	return f, nil
+}
+
+// skipDir returns whether nothing beneath the directory, relative to the
+// root, is synced.
+func (f *watchFilter) skipDir(rel string) bool {
+	if isGitDir(rel) {
+		return true
+	}
+	if f.exclude == nil || f.exclude.Exclusions() {
+		// a later pattern may re-include something beneath it
+		return false
+	}
+	excluded, err := f.exclude.MatchesOrParentMatches(rel)
+	return err == nil && excluded
+}
+
+// matches returns whether the path, relative to the root, is synced.
+func (f *watchFilter) matches(rel string) bool {
+	if isGitDir(rel) {
+		return false
+	}
+	if f.include != nil {
+		included, err := f.include.MatchesOrParentMatches(rel)
+		if err == nil && !included {
+			return false
+		}
+	}
+	if f.exclude != nil {
+		excluded, err := f.exclude.MatchesOrParentMatches(rel)
+		if err == nil && excluded {
+			return false
+		}
+	}
+	return true
+}
+
+// isGitDir returns whether the path is in the .git directory at the root,
+// whose contents change on their own, e.g. when git refreshes its index.
+func isGitDir(rel string) bool {
+	return rel == ".git" || strings.HasPrefix(rel, ".git/")
+}
+
+func (root *watchedRoot) skipDir(rel string) bool {
+	for _, f := range root.filters {
+		if !f.skipDir(rel) {
+			return false
+		}
+	}
+	return true
+}
+
+func (root *watchedRoot) matches(rel string) bool {
+	for _, f := range root.filters {
+		if f.matches(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func newHostWatcher(onChange func(string)) (*hostWatcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &hostWatcher{
+		fsw:      fsw,
+		onChange: onChange,
+		roots:    map[string]*watchedRoot{},
+	}
+
+	go watcher.run()
+
+	return watcher, nil
+}
+
+// Watch starts watching the paths of the directory matched by the filter, if
+// they aren't watched already, and returns the number of changes seen
+// beneath it so far.
+func (watcher *hostWatcher) Watch(root string, filter CopyFilter) (int, error) {
+	watcher.l.Lock()
+	defer watcher.l.Unlock()
+
+	key := strings.Join(filter.Include, "\x00") + "\x01" + strings.Join(filter.Exclude, "\x00")
+
+	watched, found := watcher.roots[root]
+	if found {
+		if _, found := watched.filters[key]; found {
+			return watched.generation, nil
+		}
+	} else {
+		watched = &watchedRoot{filters: map[string]*watchFilter{}}
+	}
+
+	f, err := newWatchFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	watched.filters[key] = f
+
+	if err := watcher.addRecursive(root, root, watched); err != nil {
+		delete(watched.filters, key)
+		return 0, err
+	}
+
+	watcher.roots[root] = watched
+
+	return watched.generation, nil
+}
+
+// Close stops watching every directory.
+func (watcher *hostWatcher) Close() error {
+	return watcher.fsw.Close()
+}
+
+// addRecursive watches dir and the directories beneath it which aren't
+// skipped by the root's filters, since inotify watches aren't recursive.
+func (watcher *hostWatcher) addRecursive(root, dir string, watched *watchedRoot) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// removed while walking
+				return nil
+			}
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if rel := relPath(root, p); rel != "." && watched.skipDir(rel) {
+			return filepath.SkipDir
+		}
+
+		return watcher.fsw.Add(p)
+	})
+}
+
+// relPath returns the slash-separated path of p relative to root, which
+// contains it.
+func relPath(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return p
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (watcher *hostWatcher) run() {
+	for {
+		select {
+		case ev, ok := <-watcher.fsw.Events:
+			if !ok {
+				return
+			}
+
+			watcher.handle(ev)
+		case _, ok := <-watcher.fsw.Errors:
+			if !ok {
+				return
+			}
+
+			// NB: errors are only reported for overflowed event queues and the
+			// like; the next event will trigger a full re-sync anyway.
+		}
+	}
+}
+
+func (watcher *hostWatcher) handle(ev fsnotify.Event) {
+	watcher.l.Lock()
+
+	isDir := false
+	if ev.Has(fsnotify.Create) {
+		if fi, err := os.Lstat(ev.Name); err == nil && fi.IsDir() {
+			isDir = true
+		}
+	}
+
+	changed := []string{}
+	for root, watched := range watcher.roots {
+		if ev.Name != root && !strings.HasPrefix(ev.Name, root+string(filepath.Separator)) {
+			continue
+		}
+
+		rel := relPath(root, ev.Name)
+		if isDir && !watched.skipDir(rel) {
+			// best-effort; a failure here only means missing later events
+			_ = watcher.addRecursive(root, ev.Name, watched)
+		}
+
+		if rel == "." || watched.matches(rel) {
+			watched.generation++
+			changed = append(changed, root)
+		}
+	}
+
+	watcher.l.Unlock()
+
+	if watcher.onChange == nil {
+		return
+	}
+
+	for _, root := range changed {
+		watcher.onChange(root)
+	}
+}
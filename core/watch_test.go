@@ -0,0 +1,85 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostWatcherFilter(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, dir := range []string{"src", "node_modules/pkg", ".git/objects"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0o755))
+	}
+
+	changes := make(chan string, 100)
+	watcher, err := newHostWatcher(func(root string) { changes <- root })
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	generation, err := watcher.Watch(root, CopyFilter{Exclude: []string{"node_modules"}})
+	require.NoError(t, err)
+	require.Zero(t, generation)
+
+	watched := watcher.fsw.WatchList()
+	require.ElementsMatch(t, []string{root, filepath.Join(root, "src")}, watched)
+
+	// excluded paths don't count as changes
+	require.NoError(t, os.WriteFile(filepath.Join(root, "node_modules", "index.js"), []byte("x"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".git", "index"), []byte("x"), 0o600))
+	select {
+	case changed := <-changes:
+		t.Fatalf("unexpected change of %s", changed)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("x"), 0o600))
+	select {
+	case changed := <-changes:
+		require.Equal(t, root, changed)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no change seen")
+	}
+	generation, err = watcher.Watch(root, CopyFilter{Exclude: []string{"node_modules"}})
+	require.NoError(t, err)
+	require.Positive(t, generation)
+
+	// directories created later are watched unless excluded
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "pkg"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "other"), 0o755))
+	require.Eventually(t, func() bool {
+		watcher.l.Lock()
+		defer watcher.l.Unlock()
+		for _, p := range watcher.fsw.WatchList() {
+			if p == filepath.Join(root, "src", "pkg") {
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 10*time.Millisecond)
+	require.NotContains(t, watcher.fsw.WatchList(), filepath.Join(root, "node_modules", "other"))
+
+	// a different filter watches what it doesn't exclude too
+	_, err = watcher.Watch(root, CopyFilter{})
+	require.NoError(t, err)
+	require.Contains(t, watcher.fsw.WatchList(), filepath.Join(root, "node_modules"))
+	require.NotContains(t, watcher.fsw.WatchList(), filepath.Join(root, ".git"))
+}
+
+func TestHostClose(t *testing.T) {
+	t.Parallel()
+
+	host := NewHost(t.TempDir(), false, nil)
+	_, err := host.watch(host.Workdir, CopyFilter{})
+	require.NoError(t, err)
+
+	watcher := host.watcher
+	require.NoError(t, host.Close())
+	require.Nil(t, host.watcher)
+	require.Error(t, watcher.fsw.Add(host.Workdir), "watcher should be closed")
+}
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/moby/buildkit/util/bklog"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+/*
+A Backend is a plain blob store that the engine exports its cache to and imports it from, as an
+alternative to the cache service. The engine manages the cache metadata itself and stores it in the
+backend next to the layers:
+  - indexBlobName holds the cache config (the same format buildkit uses for remote caches), plus the
+    bookkeeping needed to only push layers for cache refs that haven't been pushed yet.
+  - blobs/<algorithm>/<hex> holds each layer, and the contents of each synced cache mount,
+    addressed by its digest.
+  - mounts/<name>.json points at the latest contents of each synced cache mount.
+
+Backends are configured with a string of the form "type=<type>,key1=value1,key2=value2,...". The
+supported types and their attributes are:
+  - local: path (required), a directory on the engine's filesystem.
+  - http: url (required), the base URL of a server supporting GET, HEAD and PUT, and token, sent
+    as a bearer token.
+  - registry: ref (required), an image reference the cache is stored under.
+  - s3: bucket (required), region (required), prefix, endpoint_url, use_path_style, access_key_id,
+    secret_access_key and session_token. Credentials default to the AWS SDK's usual discovery.
+
+Every type also accepts import_period, export_period and export_timeout, as Go durations, and
+cache_mounts, a list of cache volume keys separated by ";" whose contents are synced with the
+backend: they're downloaded when the engine starts and uploaded when it shuts down.
+*/
+type Backend interface {
+	// Get returns the contents of the named blob, starting at the given offset. It returns an error
+	// wrapping ErrBlobNotFound if the blob doesn't exist.
+	Get(ctx context.Context, name string, offset int64) (io.ReadCloser, error)
+
+	// Put writes the named blob, replacing it if it already exists.
+	Put(ctx context.Context, name string, r io.ReaderAt, size int64) error
+
+	// Exists returns whether the named blob exists.
+	Exists(ctx context.Context, name string) (bool, error)
+}
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+const (
+	indexBlobName = "index.json"
+
+	defaultBackendImportPeriod  = 5 * time.Minute
+	defaultBackendExportPeriod  = 5 * time.Minute
+	defaultBackendExportTimeout = 30 * time.Minute
+)
+
+func blobName(dgst digest.Digest) string {
+	return "blobs/" + dgst.Algorithm().String() + "/" + dgst.Encoded()
+}
+
+type BackendConfig struct {
+	Type  string
+	Attrs map[string]string
+}
+
+// ParseBackendConfig parses a backend config of the form "type=<type>,key1=value1,...".
+func ParseBackendConfig(s string) (*BackendConfig, error) {
+	cfg := &BackendConfig{Attrs: map[string]string{}}
+	for _, kv := range strings.Split(s, ",") {
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid form for cache backend config %q", kv)
+		}
+		if k == "type" {
+			cfg.Type = v
+			continue
+		}
+		cfg.Attrs[k] = v
+	}
+	if cfg.Type == "" {
+		return nil, fmt.Errorf("missing type in cache backend config: %q", s)
+	}
+	return cfg, nil
+}
+
+// Config returns the import/export periods for the backend, falling back to the defaults.
+func (cfg *BackendConfig) Config() (*Config, error) {
+	c := &Config{
+		ImportPeriod:  defaultBackendImportPeriod,
+		ExportPeriod:  defaultBackendExportPeriod,
+		ExportTimeout: defaultBackendExportTimeout,
+	}
+	for attr, dst := range map[string]*time.Duration{
+		"import_period":  &c.ImportPeriod,
+		"export_period":  &c.ExportPeriod,
+		"export_timeout": &c.ExportTimeout,
+	} {
+		v, ok := cfg.Attrs[attr]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in cache backend config: %w", attr, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid %s in cache backend config: must be positive", attr)
+		}
+		*dst = d
+	}
+	return c, nil
+}
+
+// NewBackend returns the backend described by the config.
+func NewBackend(ctx context.Context, cfg *BackendConfig) (Backend, error) {
+	switch cfg.Type {
+	case "local":
+		return newLocalBackend(cfg.Attrs)
+	case "http":
+		return newHTTPBackend(cfg.Attrs)
+	case "registry":
+		return newRegistryBackend(cfg.Attrs)
+	case "s3":
+		return newS3Backend(ctx, cfg.Attrs)
+	default:
+		return nil, fmt.Errorf("unsupported cache backend type %q", cfg.Type)
+	}
+}
+
+// backendLayerStore reads and writes layers directly from a backend.
+type backendLayerStore struct {
+	backend Backend
+}
+
+var _ layerStore = &backendLayerStore{}
+
+func (s *backendLayerStore) ReaderAt(ctx context.Context, desc ocispecs.Descriptor) (content.ReaderAt, error) {
+	return &backendReaderAt{
+		ctx:     ctx,
+		backend: s.backend,
+		desc:    desc,
+	}, nil
+}
+
+func (s *backendLayerStore) Push(ctx context.Context, desc ocispecs.Descriptor, provider content.Provider) error {
+	name := blobName(desc.Digest)
+	// layers are content-addressed, so there's no need to push them again
+	exists, err := s.backend.Exists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		bklog.G(ctx).Debugf("layer %s already exists in cache backend", desc.Digest)
+		return nil
+	}
+
+	readerAt, err := provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer readerAt.Close()
+	return s.backend.Put(ctx, name, readerAt, readerAt.Size())
+}
+
+// backendReaderAt reads a layer sequentially from a backend, the same way urlReaderAt does over
+// HTTP: the blob is opened at the first offset read and only re-opened on a non-sequential read.
+type backendReaderAt struct {
+	ctx     context.Context
+	backend Backend
+	desc    ocispecs.Descriptor
+
+	// internally set fields
+	body   io.ReadCloser
+	offset int64
+}
+
+func (r *backendReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if r.body == nil || off != r.offset {
+		body, err := r.backend.Get(r.ctx, blobName(r.desc.Digest), off)
+		if err != nil {
+			return 0, err
+		}
+		if r.body != nil {
+			bklog.G(r.ctx).Debugf("non-sequential read in backendReaderAt for %s at offset %d", r.desc.Digest, off)
+			r.body.Close()
+		}
+		r.body = body
+		r.offset = off
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *backendReaderAt) Size() int64 {
+	return r.desc.Size
+}
+
+func (r *backendReaderAt) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// httpBackend stores blobs on a plain HTTP server under a base URL, reading them with GET and
+// writing them with PUT. This works with e.g. WebDAV servers, nginx with dav_methods enabled, or
+// buckets that accept unsigned writes.
+type httpBackend struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+func newHTTPBackend(attrs map[string]string) (*httpBackend, error) {
+	baseURL := attrs["url"]
+	if baseURL == "" {
+		return nil, fmt.Errorf("url is required for http cache backend")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url for http cache backend: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url for http cache backend: unsupported scheme %q", u.Scheme)
+	}
+	return &httpBackend{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      attrs["token"],
+	}, nil
+}
+
+func (b *httpBackend) request(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+"/"+name, body)
+	if err != nil {
+		return nil, err
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	return req, nil
+}
+
+func (b *httpBackend) Get(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	req, err := b.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	//nolint:bodyclose // the body is closed by the caller
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", name, ErrBlobNotFound)
+	case offset > 0 && resp.StatusCode == http.StatusOK:
+		// the server ignored the range, so skip to the offset instead
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent:
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s: %s", name, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (b *httpBackend) Put(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	req, err := b.request(ctx, http.MethodPut, name, io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to put %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (b *httpBackend) Exists(ctx context.Context, name string) (bool, error) {
+	req, err := b.request(ctx, http.MethodHead, name, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check %s: %s", name, resp.Status)
+	}
+}
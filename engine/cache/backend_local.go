@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// localBackend stores blobs in a directory on the engine's filesystem.
+type localBackend struct {
+	dir string
+}
+
+func newLocalBackend(attrs map[string]string) (*localBackend, error) {
+	dir := attrs["path"]
+	if dir == "" {
+		return nil, fmt.Errorf("path is required for local cache backend")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &localBackend{dir: dir}, nil
+}
+
+func (b *localBackend) path(name string) string {
+	return filepath.Join(b.dir, filepath.FromSlash(name))
+}
+
+func (b *localBackend) Get(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	f, err := os.Open(b.path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", name, ErrBlobNotFound)
+		}
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (b *localBackend) Put(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	dest := b.path(name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".tmp-"+filepath.Base(dest))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, io.NewSectionReader(r, 0, size)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func (b *localBackend) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := os.Stat(b.path(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
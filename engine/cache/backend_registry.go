@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/opencontainers/go-digest"
+)
+
+const registryConfigMediaType types.MediaType = "application/vnd.dagger.cache.config.v1+json"
+
+// registryBackend stores blobs in an OCI registry. Layers are pushed as blobs of the repository.
+// The index is pushed as the config of a manifest tagged at the given ref, and every other blob
+// the same way under a tag derived from the ref and the blob's name. The manifests list the layers
+// the blobs reference, so that the registry doesn't garbage collect them.
+type registryBackend struct {
+	ref name.Tag
+}
+
+func newRegistryBackend(attrs map[string]string) (*registryBackend, error) {
+	ref := attrs["ref"]
+	if ref == "" {
+		return nil, fmt.Errorf("ref is required for registry cache backend")
+	}
+	tag, err := name.NewTag(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ref for registry cache backend: %w", err)
+	}
+	return &registryBackend{ref: tag}, nil
+}
+
+func (b *registryBackend) options(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}
+}
+
+func (b *registryBackend) Get(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	dgst, err := b.blobDigest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	layer, err := remote.Layer(b.ref.Context().Digest(dgst.String()), b.options(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		if isRegistryNotFound(err) {
+			return nil, fmt.Errorf("%s: %w", name, ErrBlobNotFound)
+		}
+		return nil, err
+	}
+	// registries don't reliably support range requests, so skip to the offset instead
+	if _, err := io.CopyN(io.Discard, rc, offset); err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return rc, nil
+}
+
+func (b *registryBackend) Put(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	if isContentBlob(name) {
+		dgst, err := blobNameDigest(name)
+		if err != nil {
+			return err
+		}
+		return b.writeBlob(ctx, dgst, types.OCILayerZStd, r, size)
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	configDigest := digest.FromBytes(data)
+	if err := b.writeBlob(ctx, configDigest, registryConfigMediaType, bytes.NewReader(data), size); err != nil {
+		return err
+	}
+
+	layers, err := referencedBlobs(name, data)
+	if err != nil {
+		return err
+	}
+	manifest := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config: v1.Descriptor{
+			MediaType: registryConfigMediaType,
+			Size:      size,
+			Digest:    v1.Hash{Algorithm: configDigest.Algorithm().String(), Hex: configDigest.Encoded()},
+		},
+		Layers: []v1.Descriptor{},
+	}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, v1.Descriptor{
+			MediaType: types.MediaType(layer.MediaType),
+			Size:      layer.Size,
+			Digest:    v1.Hash{Algorithm: layer.Digest.Algorithm().String(), Hex: layer.Digest.Encoded()},
+		})
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return remote.Put(b.tag(name), rawManifest{raw: raw, mediaType: types.OCIManifestSchema1}, b.options(ctx)...)
+}
+
+func (b *registryBackend) Exists(ctx context.Context, name string) (bool, error) {
+	if !isContentBlob(name) {
+		if _, err := remote.Head(b.tag(name), b.options(ctx)...); err != nil {
+			if isRegistryNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	dgst, err := blobNameDigest(name)
+	if err != nil {
+		return false, err
+	}
+	layer, err := remote.Layer(b.ref.Context().Digest(dgst.String()), b.options(ctx)...)
+	if err != nil {
+		return false, err
+	}
+	if exister, ok := layer.(interface{ Exists() (bool, error) }); ok {
+		return exister.Exists()
+	}
+	return false, nil
+}
+
+// tag returns the tag of the manifest holding the named blob.
+func (b *registryBackend) tag(name string) name.Tag {
+	if name == indexBlobName {
+		return b.ref
+	}
+	return b.ref.Context().Tag(b.ref.TagStr() + "-" + digest.FromString(name).Encoded()[:16])
+}
+
+// blobDigest returns the digest of the registry blob holding the named blob.
+func (b *registryBackend) blobDigest(ctx context.Context, name string) (digest.Digest, error) {
+	if isContentBlob(name) {
+		return blobNameDigest(name)
+	}
+	desc, err := remote.Get(b.tag(name), b.options(ctx)...)
+	if err != nil {
+		if isRegistryNotFound(err) {
+			return "", fmt.Errorf("%s: %w", name, ErrBlobNotFound)
+		}
+		return "", err
+	}
+	var manifest v1.Manifest
+	if err := json.Unmarshal(desc.Manifest, &manifest); err != nil {
+		return "", err
+	}
+	if manifest.Config.MediaType != registryConfigMediaType {
+		return "", fmt.Errorf("%s is not a dagger cache: unexpected config media type %q", b.ref, manifest.Config.MediaType)
+	}
+	return digest.Digest(manifest.Config.Digest.String()), nil
+}
+
+func (b *registryBackend) writeBlob(ctx context.Context, dgst digest.Digest, mediaType types.MediaType, r io.ReaderAt, size int64) error {
+	layer, err := partial.CompressedToLayer(&readerAtBlob{
+		dgst:      dgst,
+		mediaType: mediaType,
+		r:         r,
+		size:      size,
+	})
+	if err != nil {
+		return err
+	}
+	return remote.WriteLayer(b.ref.Context(), layer, b.options(ctx)...)
+}
+
+func isContentBlob(name string) bool {
+	return strings.HasPrefix(name, "blobs/")
+}
+
+func blobNameDigest(name string) (digest.Digest, error) {
+	rest, ok := strings.CutPrefix(name, "blobs/")
+	if !ok {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	alg, hex, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	dgst := digest.NewDigestFromEncoded(digest.Algorithm(alg), hex)
+	if err := dgst.Validate(); err != nil {
+		return "", fmt.Errorf("invalid blob name %q: %w", name, err)
+	}
+	return dgst, nil
+}
+
+func isRegistryNotFound(err error) bool {
+	var terr *transport.Error
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound
+}
+
+// readerAtBlob is a registry blob whose contents are read from an io.ReaderAt.
+type readerAtBlob struct {
+	dgst      digest.Digest
+	mediaType types.MediaType
+	r         io.ReaderAt
+	size      int64
+}
+
+func (l *readerAtBlob) Digest() (v1.Hash, error) {
+	return v1.NewHash(l.dgst.String())
+}
+
+func (l *readerAtBlob) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(l.r, 0, l.size)), nil
+}
+
+func (l *readerAtBlob) Size() (int64, error) {
+	return l.size, nil
+}
+
+func (l *readerAtBlob) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+type rawManifest struct {
+	raw       []byte
+	mediaType types.MediaType
+}
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m.raw, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// s3Backend stores blobs in an S3 bucket, or any S3-compatible store when endpoint_url is set.
+// The attributes have the same names as those of buildkit's s3 cache exporter.
+type s3Backend struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Backend(ctx context.Context, attrs map[string]string) (*s3Backend, error) {
+	bucket := attrs["bucket"]
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket is required for s3 cache backend")
+	}
+	region := attrs["region"]
+	if region == "" {
+		return nil, fmt.Errorf("region is required for s3 cache backend")
+	}
+	usePathStyle := false
+	if v, ok := attrs["use_path_style"]; ok {
+		var err error
+		usePathStyle, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid use_path_style in cache backend config: %w", err)
+		}
+	}
+
+	cfg, err := aws_config.LoadDefaultConfig(ctx, aws_config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(options *s3.Options) {
+		if attrs["access_key_id"] != "" && attrs["secret_access_key"] != "" {
+			options.Credentials = credentials.NewStaticCredentialsProvider(
+				attrs["access_key_id"],
+				attrs["secret_access_key"],
+				attrs["session_token"],
+			)
+		}
+		if endpointURL := attrs["endpoint_url"]; endpointURL != "" {
+			options.UsePathStyle = usePathStyle
+			options.EndpointResolver = s3.EndpointResolverFromURL(endpointURL)
+		}
+	})
+
+	return &s3Backend{
+		client: client,
+		bucket: bucket,
+		prefix: attrs["prefix"],
+	}, nil
+}
+
+func (b *s3Backend) key(name string) *string {
+	key := path.Join(b.prefix, name)
+	return &key
+}
+
+func (b *s3Backend) Get(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    b.key(name),
+	}
+	if offset > 0 {
+		rng := fmt.Sprintf("bytes=%d-", offset)
+		input.Range = &rng
+	}
+	output, err := b.client.GetObject(ctx, input)
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, fmt.Errorf("%s: %w", name, ErrBlobNotFound)
+		}
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (b *s3Backend) Put(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &b.bucket,
+		Key:           b.key(name),
+		Body:          io.NewSectionReader(r, 0, size),
+		ContentLength: size,
+	})
+	return err
+}
+
+func (b *s3Backend) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &b.bucket,
+		Key:    b.key(name),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func isS3NotFound(err error) bool {
+	var errapi smithy.APIError
+	return errors.As(err, &errapi) && (errapi.ErrorCode() == "NoSuchKey" || errapi.ErrorCode() == "NotFound")
+}
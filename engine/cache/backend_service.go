@@ -0,0 +1,418 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	remotecache "github.com/moby/buildkit/cache/remotecache/v1"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// backendIndex is the content of the index blob in a backend.
+type backendIndex struct {
+	// Config is the cache config the engine imports.
+	Config remotecache.CacheConfig `json:"config"`
+	// Refs holds the layers pushed for each cache ref, so they only have to be pushed once.
+	Refs map[string][]ocispecs.Descriptor `json:"refs,omitempty"`
+}
+
+// backendService implements the cache service on top of a Backend. Where the cache service keeps
+// the cache metadata on its side, backendService builds the cache config itself and stores it in
+// the backend's index blob. The index always reflects the most recent export, so engines sharing a
+// backend overwrite each other's exports rather than merging them.
+type backendService struct {
+	backend     Backend
+	config      Config
+	cacheMounts []string
+
+	mu        sync.Mutex
+	cacheKeys []CacheKey
+	links     []Link
+	refLayers map[string][]ocispecs.Descriptor
+	pending   map[digest.Digest]string // export record digest -> cache ref ID
+	lastIndex []byte
+}
+
+var _ Service = &backendService{}
+
+func newBackendService(ctx context.Context, cfg *BackendConfig) (*backendService, error) {
+	config, err := cfg.Config()
+	if err != nil {
+		return nil, err
+	}
+	backend, err := NewBackend(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var cacheMounts []string
+	if v := cfg.Attrs["cache_mounts"]; v != "" {
+		cacheMounts = strings.Split(v, ";")
+	}
+	return &backendService{
+		backend:     backend,
+		config:      *config,
+		cacheMounts: cacheMounts,
+		refLayers:   map[string][]ocispecs.Descriptor{},
+		pending:     map[digest.Digest]string{},
+	}, nil
+}
+
+func (s *backendService) GetConfig(context.Context, GetConfigRequest) (*Config, error) {
+	return &s.config, nil
+}
+
+func (s *backendService) UpdateCacheRecords(ctx context.Context, req UpdateCacheRecordsRequest) (*UpdateCacheRecordsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cacheKeys = req.CacheKeys
+	s.links = req.Links
+
+	resp := &UpdateCacheRecordsResponse{}
+	seen := map[string]struct{}{}
+	for _, key := range req.CacheKeys {
+		for _, res := range key.Results {
+			if _, ok := s.refLayers[res.ID]; ok {
+				continue
+			}
+			if _, ok := seen[res.ID]; ok {
+				continue
+			}
+			seen[res.ID] = struct{}{}
+			dgst := digest.FromString(res.ID)
+			s.pending[dgst] = res.ID
+			resp.ExportRecords = append(resp.ExportRecords, ExportRecord{
+				Digest:     dgst,
+				CacheRefID: res.ID,
+			})
+		}
+	}
+
+	// write out the records whose layers were already pushed; the rest follow in UpdateCacheLayers
+	if err := s.writeIndex(ctx); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *backendService) UpdateCacheLayers(ctx context.Context, req UpdateCacheLayersRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range req.UpdatedRecords {
+		refID, ok := s.pending[record.RecordDigest]
+		if !ok {
+			return fmt.Errorf("unknown cache record %s", record.RecordDigest)
+		}
+		delete(s.pending, record.RecordDigest)
+		s.refLayers[refID] = record.Layers
+	}
+	return s.writeIndex(ctx)
+}
+
+func (s *backendService) ImportCache(ctx context.Context) (*remotecache.CacheConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rc, err := s.backend.Get(ctx, indexBlobName, 0)
+	if err != nil {
+		if errors.Is(err, ErrBlobNotFound) {
+			// nothing has been exported yet
+			return &remotecache.CacheConfig{}, nil
+		}
+		return nil, err
+	}
+	defer rc.Close()
+
+	var index backendIndex
+	if err := json.NewDecoder(rc).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to decode cache index: %w", err)
+	}
+	for refID, layers := range index.Refs {
+		if _, ok := s.refLayers[refID]; !ok {
+			s.refLayers[refID] = layers
+		}
+	}
+	return &index.Config, nil
+}
+
+func (s *backendService) GetLayerDownloadURL(context.Context, GetLayerDownloadURLRequest) (*GetLayerDownloadURLResponse, error) {
+	return nil, errors.New("layer download URLs are not supported by cache backends")
+}
+
+func (s *backendService) GetLayerUploadURL(context.Context, GetLayerUploadURLRequest) (*GetLayerUploadURLResponse, error) {
+	return nil, errors.New("layer upload URLs are not supported by cache backends")
+}
+
+func (s *backendService) GetCacheMountConfig(ctx context.Context, _ GetCacheMountConfigRequest) (*GetCacheMountConfigResponse, error) {
+	resp := &GetCacheMountConfigResponse{}
+	for _, name := range s.cacheMounts {
+		cfg := SyncedCacheMountConfig{Name: name}
+		mount, err := readBackendCacheMount(ctx, s.backend, name)
+		if err != nil {
+			return nil, err
+		}
+		if mount != nil {
+			cfg.Digest = mount.Digest
+			cfg.Size = mount.Size
+			cfg.MediaType = mount.MediaType
+		}
+		resp.SyncedCacheMounts = append(resp.SyncedCacheMounts, cfg)
+	}
+	return resp, nil
+}
+
+func (s *backendService) GetCacheMountUploadURL(context.Context, GetCacheMountUploadURLRequest) (*GetCacheMountUploadURLResponse, error) {
+	return nil, errors.New("cache mount upload URLs are not supported by cache backends")
+}
+
+// writeIndex writes the index blob, unless it hasn't changed since the last write.
+func (s *backendService) writeIndex(ctx context.Context) error {
+	index := backendIndex{
+		Config: buildCacheConfig(s.cacheKeys, s.links, s.refLayers),
+		Refs:   map[string][]ocispecs.Descriptor{},
+	}
+	for _, key := range s.cacheKeys {
+		for _, res := range key.Results {
+			if layers, ok := s.refLayers[res.ID]; ok {
+				index.Refs[res.ID] = layers
+			}
+		}
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(b, s.lastIndex) {
+		return nil
+	}
+	if err := s.backend.Put(ctx, indexBlobName, bytes.NewReader(b), int64(len(b))); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	s.lastIndex = b
+	return nil
+}
+
+// buildCacheConfig turns the engine's cache metadata into a cache config, the same way buildkit's
+// cache exporters do. Only results whose layers have been pushed are included.
+func buildCacheConfig(cacheKeys []CacheKey, links []Link, refLayers map[string][]ocispecs.Descriptor) remotecache.CacheConfig {
+	backlinks := map[string][]Link{}
+	for _, link := range links {
+		if strings.HasPrefix(link.Digest.String(), "random:") {
+			continue
+		}
+		backlinks[link.ID] = append(backlinks[link.ID], link)
+	}
+
+	keys := map[string]CacheKey{}
+	for _, key := range cacheKeys {
+		if strings.HasPrefix(key.ID, "random:") {
+			continue
+		}
+		keys[key.ID] = key
+	}
+
+	// drop keys with an input that can't be satisfied, until none are left
+	for {
+		var dropped bool
+		for id := range keys {
+			var numInputs int
+			satisfied := map[int]bool{}
+			for _, link := range backlinks[id] {
+				if link.Input+1 > numInputs {
+					numInputs = link.Input + 1
+				}
+				if _, ok := keys[link.LinkedID]; ok {
+					satisfied[link.Input] = true
+				}
+			}
+			if len(satisfied) < numInputs {
+				delete(keys, id)
+				dropped = true
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	recordIndexes := make(map[string]int, len(ids))
+	for i, id := range ids {
+		recordIndexes[id] = i
+	}
+
+	var config remotecache.CacheConfig
+	layerIndexes := map[digest.Digest]int{}
+	for _, id := range ids {
+		record := remotecache.CacheRecord{
+			Digest: digest.Digest(id),
+		}
+
+		for _, link := range backlinks[id] {
+			linkIndex, ok := recordIndexes[link.LinkedID]
+			if !ok {
+				continue
+			}
+			// linked keys are looked up by the digest of their op and output rather than their ID
+			record.Digest = outputKey(link.Digest, link.Output)
+			for len(record.Inputs) <= link.Input {
+				record.Inputs = append(record.Inputs, nil)
+			}
+			record.Inputs[link.Input] = append(record.Inputs[link.Input], remotecache.CacheInput{
+				Selector:  link.Selector.String(),
+				LinkIndex: linkIndex,
+			})
+		}
+
+		for _, res := range keys[id].Results {
+			layers, ok := refLayers[res.ID]
+			if !ok || len(layers) == 0 {
+				continue
+			}
+			chain := remotecache.ChainedResult{CreatedAt: res.CreatedAt}
+			for _, layer := range layers {
+				layerIndex, ok := layerIndexes[layer.Digest]
+				if !ok {
+					layerIndex = len(config.Layers)
+					layerIndexes[layer.Digest] = layerIndex
+					config.Layers = append(config.Layers, cacheLayer(layer))
+				}
+				chain.LayerIndexes = append(chain.LayerIndexes, layerIndex)
+			}
+			record.ChainedResults = append(record.ChainedResults, chain)
+		}
+
+		config.Records = append(config.Records, record)
+	}
+	return config
+}
+
+func cacheLayer(desc ocispecs.Descriptor) remotecache.CacheLayer {
+	annotations := &remotecache.LayerAnnotations{
+		MediaType: desc.MediaType,
+		Size:      desc.Size,
+		DiffID:    digest.Digest(desc.Annotations["containerd.io/uncompressed"]),
+	}
+	if createdAt, ok := desc.Annotations["buildkit/createdat"]; ok {
+		var t time.Time
+		if err := t.UnmarshalText([]byte(createdAt)); err == nil {
+			annotations.CreatedAt = t
+		}
+	}
+	return remotecache.CacheLayer{
+		Blob:        desc.Digest,
+		ParentIndex: -1,
+		Annotations: annotations,
+	}
+}
+
+func outputKey(dgst digest.Digest, output int) digest.Digest {
+	return digest.FromBytes([]byte(fmt.Sprintf("%s@%d", dgst, output)))
+}
+
+// backendCacheMount is the content of the blob pointing at the latest upload of a synced cache
+// mount.
+type backendCacheMount struct {
+	Digest    digest.Digest `json:"digest"`
+	Size      int64         `json:"size"`
+	MediaType string        `json:"mediaType"`
+}
+
+func cacheMountBlobName(name string) string {
+	return "mounts/" + url.PathEscape(name) + ".json"
+}
+
+// readBackendCacheMount returns the latest upload of the named cache mount, or nil if there's
+// none yet.
+func readBackendCacheMount(ctx context.Context, backend Backend, name string) (*backendCacheMount, error) {
+	rc, err := backend.Get(ctx, cacheMountBlobName(name), 0)
+	if err != nil {
+		if errors.Is(err, ErrBlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rc.Close()
+
+	var mount backendCacheMount
+	if err := json.NewDecoder(rc).Decode(&mount); err != nil {
+		return nil, fmt.Errorf("failed to decode cache mount %q: %w", name, err)
+	}
+	return &mount, nil
+}
+
+// backendCacheMountStore stores synced cache mounts in a backend. The contents are stored as a
+// blob like layers are, and a small blob per cache mount points at its latest contents.
+type backendCacheMountStore struct {
+	backend Backend
+}
+
+var _ cacheMountStore = &backendCacheMountStore{}
+
+func (s *backendCacheMountStore) Provider(cfg SyncedCacheMountConfig) content.Provider {
+	if cfg.Digest == "" {
+		return nil
+	}
+	return &backendLayerStore{backend: s.backend}
+}
+
+func (s *backendCacheMountStore) Push(ctx context.Context, name string, desc ocispecs.Descriptor, provider content.Provider) error {
+	if err := (&backendLayerStore{backend: s.backend}).Push(ctx, desc, provider); err != nil {
+		return err
+	}
+	b, err := json.Marshal(backendCacheMount{
+		Digest:    desc.Digest,
+		Size:      desc.Size,
+		MediaType: desc.MediaType,
+	})
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(ctx, cacheMountBlobName(name), bytes.NewReader(b), int64(len(b)))
+}
+
+// referencedBlobs returns the blobs referenced by the named index or cache mount blob.
+func referencedBlobs(name string, data []byte) ([]ocispecs.Descriptor, error) {
+	if name == indexBlobName {
+		var index backendIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, err
+		}
+		descs := make([]ocispecs.Descriptor, 0, len(index.Config.Layers))
+		for _, layer := range index.Config.Layers {
+			desc := ocispecs.Descriptor{Digest: layer.Blob}
+			if layer.Annotations != nil {
+				desc.MediaType = layer.Annotations.MediaType
+				desc.Size = layer.Annotations.Size
+			}
+			descs = append(descs, desc)
+		}
+		return descs, nil
+	}
+
+	var mount backendCacheMount
+	if err := json.Unmarshal(data, &mount); err != nil {
+		return nil, err
+	}
+	return []ocispecs.Descriptor{{
+		Digest:    mount.Digest,
+		Size:      mount.Size,
+		MediaType: mount.MediaType,
+	}}, nil
+}
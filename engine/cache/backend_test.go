@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBackendConfig(t *testing.T) {
+	cfg, err := ParseBackendConfig("type=local,path=/var/cache/dagger,export_period=30s")
+	require.NoError(t, err)
+	require.Equal(t, "local", cfg.Type)
+	require.Equal(t, map[string]string{"path": "/var/cache/dagger", "export_period": "30s"}, cfg.Attrs)
+
+	config, err := cfg.Config()
+	require.NoError(t, err)
+	require.Equal(t, defaultBackendImportPeriod, config.ImportPeriod)
+	require.Equal(t, 30*time.Second, config.ExportPeriod)
+	require.Equal(t, defaultBackendExportTimeout, config.ExportTimeout)
+
+	_, err = ParseBackendConfig("path=/var/cache/dagger")
+	require.ErrorContains(t, err, "missing type")
+
+	_, err = ParseBackendConfig("type=local,path")
+	require.ErrorContains(t, err, "invalid form")
+
+	cfg, err = ParseBackendConfig("type=local,import_period=0s")
+	require.NoError(t, err)
+	_, err = cfg.Config()
+	require.ErrorContains(t, err, "must be positive")
+}
+
+func TestLocalBackend(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewBackend(ctx, &BackendConfig{
+		Type:  "local",
+		Attrs: map[string]string{"path": t.TempDir()},
+	})
+	require.NoError(t, err)
+
+	name := blobName(digest.FromString("hello world"))
+	exists, err := backend.Exists(ctx, name)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, err = backend.Get(ctx, name, 0)
+	require.True(t, errors.Is(err, ErrBlobNotFound))
+
+	content := "hello world"
+	require.NoError(t, backend.Put(ctx, name, strings.NewReader(content), int64(len(content))))
+
+	exists, err = backend.Exists(ctx, name)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	rc, err := backend.Get(ctx, name, 6)
+	require.NoError(t, err)
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(b))
+}
+
+func TestBuildCacheConfig(t *testing.T) {
+	rootID := digest.FromString("root@0").String()
+	opDigest := digest.FromString("op")
+	layer := ocispecs.Descriptor{
+		MediaType: ocispecs.MediaTypeImageLayerZstd,
+		Digest:    digest.FromString("layer"),
+		Size:      5,
+		Annotations: map[string]string{
+			"containerd.io/uncompressed": digest.FromString("diff").String(),
+		},
+	}
+
+	config := buildCacheConfig(
+		[]CacheKey{
+			{ID: rootID, Results: []Result{{ID: "ref-root"}}},
+			{ID: "child", Results: []Result{{ID: "ref-child"}}},
+			{ID: "orphan"},
+			{ID: "random:nope"},
+		},
+		[]Link{
+			{ID: "child", LinkedID: rootID, Input: 0, Output: 1, Digest: opDigest},
+			{ID: "orphan", LinkedID: "random:nope", Input: 0, Digest: opDigest},
+		},
+		map[string][]ocispecs.Descriptor{
+			"ref-root":  {layer},
+			"ref-child": {layer},
+		},
+	)
+
+	// the orphan lost its only input along with the random key, so only two records are left
+	require.Len(t, config.Records, 2)
+	require.Len(t, config.Layers, 1)
+	require.Equal(t, layer.Digest, config.Layers[0].Blob)
+	require.Equal(t, -1, config.Layers[0].ParentIndex)
+	require.Equal(t, digest.FromString("diff"), config.Layers[0].Annotations.DiffID)
+
+	records := map[digest.Digest]int{}
+	for i, rec := range config.Records {
+		records[rec.Digest] = i
+		require.Len(t, rec.ChainedResults, 1)
+		require.Equal(t, []int{0}, rec.ChainedResults[0].LayerIndexes)
+	}
+	rootIndex, ok := records[digest.Digest(rootID)]
+	require.True(t, ok)
+	childIndex, ok := records[outputKey(opDigest, 1)]
+	require.True(t, ok)
+	require.Equal(t, rootIndex, config.Records[childIndex].Inputs[0][0].LinkIndex)
+}
+
+func TestHTTPBackend(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	blobs := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			blobs[r.URL.Path] = b
+			w.WriteHeader(http.StatusCreated)
+		default:
+			b, ok := blobs[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+		}
+	}))
+	defer srv.Close()
+
+	backend, err := NewBackend(ctx, &BackendConfig{
+		Type:  "http",
+		Attrs: map[string]string{"url": srv.URL + "/cache/", "token": "s3cr3t"},
+	})
+	require.NoError(t, err)
+
+	exists, err := backend.Exists(ctx, indexBlobName)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, err = backend.Get(ctx, indexBlobName, 0)
+	require.True(t, errors.Is(err, ErrBlobNotFound))
+
+	content := "hello world"
+	require.NoError(t, backend.Put(ctx, indexBlobName, strings.NewReader(content), int64(len(content))))
+	require.Contains(t, blobs, "/cache/index.json")
+
+	exists, err = backend.Exists(ctx, indexBlobName)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	rc, err := backend.Get(ctx, indexBlobName, 6)
+	require.NoError(t, err)
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(b))
+}
+
+func TestBackendCacheMounts(t *testing.T) {
+	ctx := context.Background()
+
+	src, err := newLocalBackend(map[string]string{"path": t.TempDir()})
+	require.NoError(t, err)
+	data := "cache mount contents"
+	desc := ocispecs.Descriptor{
+		MediaType: ocispecs.MediaTypeImageLayerZstd,
+		Digest:    digest.FromString(data),
+		Size:      int64(len(data)),
+	}
+	require.NoError(t, src.Put(ctx, blobName(desc.Digest), strings.NewReader(data), desc.Size))
+
+	svc, err := newBackendService(ctx, &BackendConfig{
+		Type:  "local",
+		Attrs: map[string]string{"path": t.TempDir(), "cache_mounts": "go-mod;npm/cache"},
+	})
+	require.NoError(t, err)
+	store := &backendCacheMountStore{backend: svc.backend}
+
+	// nothing has been uploaded yet, so there's nothing to download
+	resp, err := svc.GetCacheMountConfig(ctx, GetCacheMountConfigRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []SyncedCacheMountConfig{{Name: "go-mod"}, {Name: "npm/cache"}}, resp.SyncedCacheMounts)
+	require.Nil(t, store.Provider(resp.SyncedCacheMounts[0]))
+
+	require.NoError(t, store.Push(ctx, "npm/cache", desc, &backendLayerStore{backend: src}))
+
+	resp, err = svc.GetCacheMountConfig(ctx, GetCacheMountConfigRequest{})
+	require.NoError(t, err)
+	require.Equal(t, SyncedCacheMountConfig{Name: "go-mod"}, resp.SyncedCacheMounts[0])
+	require.Equal(t, SyncedCacheMountConfig{
+		Name:      "npm/cache",
+		Digest:    desc.Digest,
+		Size:      desc.Size,
+		MediaType: desc.MediaType,
+	}, resp.SyncedCacheMounts[1])
+
+	provider := store.Provider(resp.SyncedCacheMounts[1])
+	require.NotNil(t, provider)
+	readerAt, err := provider.ReaderAt(ctx, desc)
+	require.NoError(t, err)
+	defer readerAt.Close()
+	b, err := io.ReadAll(content.NewReader(readerAt))
+	require.NoError(t, err)
+	require.Equal(t, data, string(b))
+}
@@ -23,11 +23,12 @@ import (
 
 type manager struct {
 	ManagerConfig
-	cacheClient   Service
-	httpClient    *http.Client
-	layerProvider content.Provider
-	runtimeConfig Config
-	localCache    solver.CacheManager
+	cacheClient     Service
+	httpClient      *http.Client
+	layerProvider   layerStore
+	cacheMountStore cacheMountStore
+	runtimeConfig   Config
+	localCache      solver.CacheManager
 
 	mu                 sync.RWMutex
 	inner              solver.CacheManager
@@ -44,6 +45,8 @@ type ManagerConfig struct {
 	ServiceURL   string
 	Token        string
 	EngineID     string
+	// Backend, if set, is used to export and import the cache instead of the cache service.
+	Backend *BackendConfig
 }
 
 func NewManager(ctx context.Context, managerConfig ManagerConfig) (Manager, error) {
@@ -56,19 +59,41 @@ func NewManager(ctx context.Context, managerConfig ManagerConfig) (Manager, erro
 		httpClient:    &http.Client{},
 	}
 
-	if managerConfig.ServiceURL == "" {
-		return defaultCacheManager{m.localCache}, nil
-	}
-	bklog.G(ctx).Debugf("using cache service at %s", managerConfig.ServiceURL)
+	switch {
+	case managerConfig.ServiceURL != "" && managerConfig.Backend != nil:
+		return nil, fmt.Errorf("cannot use both a cache service and a cache backend")
+	case managerConfig.Backend != nil:
+		bklog.G(ctx).Debugf("using %s cache backend", managerConfig.Backend.Type)
 
-	serviceClient, err := newClient(managerConfig.ServiceURL, managerConfig.Token)
-	if err != nil {
-		return nil, err
-	}
-	m.cacheClient = serviceClient
-	m.layerProvider = &layerProvider{
-		httpClient:  m.httpClient,
-		cacheClient: m.cacheClient,
+		backendService, err := newBackendService(ctx, managerConfig.Backend)
+		if err != nil {
+			return nil, err
+		}
+		m.cacheClient = backendService
+		m.layerProvider = &backendLayerStore{
+			backend: backendService.backend,
+		}
+		m.cacheMountStore = &backendCacheMountStore{
+			backend: backendService.backend,
+		}
+	case managerConfig.ServiceURL != "":
+		bklog.G(ctx).Debugf("using cache service at %s", managerConfig.ServiceURL)
+
+		serviceClient, err := newClient(managerConfig.ServiceURL, managerConfig.Token)
+		if err != nil {
+			return nil, err
+		}
+		m.cacheClient = serviceClient
+		m.layerProvider = &layerProvider{
+			httpClient:  m.httpClient,
+			cacheClient: m.cacheClient,
+		}
+		m.cacheMountStore = &serviceCacheMountStore{
+			httpClient:  m.httpClient,
+			cacheClient: m.cacheClient,
+		}
+	default:
+		return defaultCacheManager{m.localCache}, nil
 	}
 
 	config, err := m.cacheClient.GetConfig(ctx, GetConfigRequest{
@@ -157,6 +182,7 @@ func (m *manager) Export(ctx context.Context) error {
 				ID:       id,
 				LinkedID: linkedID,
 				Input:    int(linkInfo.Input),
+				Output:   int(linkInfo.Output),
 				Digest:   linkInfo.Digest,
 				Selector: linkInfo.Selector,
 			}
@@ -316,37 +342,7 @@ func (m *manager) pushLayer(ctx context.Context, layerDesc ocispecs.Descriptor,
 		bklog.G(ctx).Debugf("finished pushing layer %s in %s", layerDesc.Digest, time.Since(pushLayerStart))
 	}()
 
-	getURLResp, err := m.cacheClient.GetLayerUploadURL(ctx, GetLayerUploadURLRequest{Digest: layerDesc.Digest})
-	if err != nil {
-		return err
-	}
-
-	readerAt, err := provider.ReaderAt(ctx, layerDesc)
-	if err != nil {
-		return err
-	}
-	defer readerAt.Close()
-	reader := content.NewReader(readerAt)
-
-	req, err := http.NewRequest("PUT", getURLResp.URL, reader)
-	if err != nil {
-		return err
-	}
-	defer req.Body.Close()
-	req.ContentLength = readerAt.Size()
-	for k, v := range getURLResp.Headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := m.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
-	}
-	return nil
+	return m.layerProvider.Push(ctx, layerDesc, provider)
 }
 
 func (m *manager) Import(ctx context.Context) error {
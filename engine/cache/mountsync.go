@@ -5,8 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -40,8 +38,9 @@ func (m *manager) StartCacheMountSynchronization(ctx context.Context) error {
 	var eg errgroup.Group
 	for _, syncedCacheMount := range syncedCacheMounts {
 		syncedCacheMount := syncedCacheMount
-		if syncedCacheMount.URL == "" {
-			// nothing to download, have to start fresh, skip it until we sync back to cloud at shutdown
+		provider := m.cacheMountStore.Provider(syncedCacheMount)
+		if provider == nil {
+			// nothing to download, have to start fresh, skip it until we sync back at shutdown
 			continue
 		}
 		eg.Go(func() error {
@@ -63,10 +62,7 @@ func (m *manager) StartCacheMountSynchronization(ctx context.Context) error {
 					return nil
 				}
 
-				fsApplier := apply.NewFileSystemApplier(&cacheMountProvider{
-					httpClient: m.httpClient,
-					url:        syncedCacheMount.URL,
-				})
+				fsApplier := apply.NewFileSystemApplier(provider)
 				_, err = fsApplier.Apply(ctx, ocispecs.Descriptor{
 					Digest:    syncedCacheMount.Digest,
 					Size:      syncedCacheMount.Size,
@@ -142,40 +138,19 @@ func (m *manager) StartCacheMountSynchronization(ctx context.Context) error {
 					}
 					contentDigest := contentWriter.Digest()
 
-					// now that we have the digest we can upload from the content store to the url
-					contentReaderAt, err := m.Worker.ContentStore().ReaderAt(ctx, ocispecs.Descriptor{
-						Digest: contentDigest,
-					})
+					// now that we have the digest we can upload from the content store
+					contentInfo, err := m.Worker.ContentStore().Info(ctx, contentDigest)
 					if err != nil {
-						return fmt.Errorf("failed to create content reader: %w", err)
+						return fmt.Errorf("failed to get content info: %w", err)
 					}
-					defer contentReaderAt.Close()
-					contentLength := contentReaderAt.Size()
-					getURLResp, err := m.cacheClient.GetCacheMountUploadURL(ctx, GetCacheMountUploadURLRequest{
-						CacheName: syncedCacheMount.Name,
+					err = m.cacheMountStore.Push(ctx, syncedCacheMount.Name, ocispecs.Descriptor{
 						Digest:    contentDigest,
-						Size:      contentLength,
-					})
-					if err != nil {
-						return fmt.Errorf("failed to get cache mount upload url: %w", err)
-					}
-					contentReader := io.NewSectionReader(contentReaderAt, 0, contentLength)
-					httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, getURLResp.URL, contentReader)
-					if err != nil {
-						return fmt.Errorf("failed to create http request: %w", err)
-					}
-					httpReq.ContentLength = contentLength // set it here, go stdlib will ignore if set on Header (??!!)
-					for k, v := range getURLResp.Headers {
-						httpReq.Header.Set(k, v)
-					}
-					resp, err := m.httpClient.Do(httpReq)
+						Size:      contentInfo.Size,
+						MediaType: ocispecs.MediaTypeImageLayerZstd,
+					}, m.Worker.ContentStore())
 					if err != nil {
 						return fmt.Errorf("failed to upload cache mount: %w", err)
 					}
-					defer resp.Body.Close()
-					if resp.StatusCode != http.StatusOK {
-						return fmt.Errorf("failed to upload cache mount: %s", resp.Status)
-					}
 
 					bklog.G(ctx).Debugf("synced cache mount remotely %s", syncedCacheMount.Name)
 					return nil
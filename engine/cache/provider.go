@@ -11,6 +11,14 @@ import (
 	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+// layerStore is where layers are pushed to on export and read from on import.
+type layerStore interface {
+	content.Provider
+	Push(ctx context.Context, desc ocispecs.Descriptor, provider content.Provider) error
+}
+
+// layerProvider transfers layers to and from the cache service, using the
+// URLs it hands out.
 type layerProvider struct {
 	httpClient  *http.Client
 	cacheClient Service
@@ -32,6 +40,101 @@ func (p *layerProvider) ReaderAt(ctx context.Context, desc ocispecs.Descriptor)
 	}, nil
 }
 
+func (p *layerProvider) Push(ctx context.Context, layerDesc ocispecs.Descriptor, provider content.Provider) error {
+	getURLResp, err := p.cacheClient.GetLayerUploadURL(ctx, GetLayerUploadURLRequest{Digest: layerDesc.Digest})
+	if err != nil {
+		return err
+	}
+
+	readerAt, err := provider.ReaderAt(ctx, layerDesc)
+	if err != nil {
+		return err
+	}
+	defer readerAt.Close()
+	reader := content.NewReader(readerAt)
+
+	req, err := http.NewRequest("PUT", getURLResp.URL, reader)
+	if err != nil {
+		return err
+	}
+	defer req.Body.Close()
+	req.ContentLength = readerAt.Size()
+	for k, v := range getURLResp.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// cacheMountStore is where synced cache mounts are downloaded from at startup and uploaded to at
+// shutdown.
+type cacheMountStore interface {
+	// Provider returns the provider to download the cache mount from, or nil if there's nothing
+	// to download yet.
+	Provider(SyncedCacheMountConfig) content.Provider
+	Push(ctx context.Context, name string, desc ocispecs.Descriptor, provider content.Provider) error
+}
+
+// serviceCacheMountStore transfers cache mounts to and from the cache service, using the URLs it
+// hands out.
+type serviceCacheMountStore struct {
+	httpClient  *http.Client
+	cacheClient Service
+}
+
+func (s *serviceCacheMountStore) Provider(cfg SyncedCacheMountConfig) content.Provider {
+	if cfg.URL == "" {
+		return nil
+	}
+	return &cacheMountProvider{
+		httpClient: s.httpClient,
+		url:        cfg.URL,
+	}
+}
+
+func (s *serviceCacheMountStore) Push(ctx context.Context, name string, desc ocispecs.Descriptor, provider content.Provider) error {
+	contentReaderAt, err := provider.ReaderAt(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("failed to create content reader: %w", err)
+	}
+	defer contentReaderAt.Close()
+	contentLength := contentReaderAt.Size()
+	getURLResp, err := s.cacheClient.GetCacheMountUploadURL(ctx, GetCacheMountUploadURLRequest{
+		CacheName: name,
+		Digest:    desc.Digest,
+		Size:      contentLength,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get cache mount upload url: %w", err)
+	}
+	contentReader := io.NewSectionReader(contentReaderAt, 0, contentLength)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, getURLResp.URL, contentReader)
+	if err != nil {
+		return fmt.Errorf("failed to create http request: %w", err)
+	}
+	httpReq.ContentLength = contentLength // set it here, go stdlib will ignore if set on Header (??!!)
+	for k, v := range getURLResp.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
 type cacheMountProvider struct {
 	httpClient *http.Client
 	url        string
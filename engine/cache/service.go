@@ -120,6 +120,7 @@ type Link struct {
 	ID       string
 	LinkedID string
 	Input    int
+	Output   int
 	Digest   digest.Digest
 	Selector digest.Digest
 }
@@ -40,15 +40,26 @@ import (
 )
 
 type Config struct {
-	Workdir            string
-	JournalFile        string
-	ProgrockWriter     progrock.Writer
-	DisableHostRW      bool
-	RunnerHost         string
-	SessionToken       string
+	Workdir        string
+	JournalFile    string
+	ProgrockWriter progrock.Writer
+	DisableHostRW  bool
+	RunnerHost     string
+	SessionToken   string
+	// QueryLimits bounds the queries executed in the session.
+	QueryLimits router.Limits
+	// Tokens are additional tokens accepted by the session, each restricted
+	// to its scope.
+	Tokens map[string]router.Scope
+	// Labels are added to the session's default labels.
+	Labels             []pipeline.Label
 	UserAgent          string
 	EngineNameCallback func(string)
 	CloudURLCallback   func(string)
+
+	// HostChangeCallback is called with the path of any host directory loaded
+	// with watch enabled whenever it changes.
+	HostChangeCallback func(string)
 }
 
 type StartCallback func(context.Context, *router.Router) error
@@ -106,6 +117,16 @@ func Start(ctx context.Context, startOpts Config, fn StartCallback) error {
 			Value: label.Value,
 		})
 	}
+	for _, label := range startOpts.Labels {
+		labels = append(labels, &progrock.Label{
+			Name:  label.Name,
+			Value: label.Value,
+		})
+	}
+
+	// fan out progress to the session's subscriptions
+	progress := core.NewProgressBroker()
+	progMultiW = append(progMultiW, progress)
 
 	progSock, progW, cleanup, err := progrockForwarder(progMultiW)
 	if err != nil {
@@ -142,7 +163,11 @@ func Start(ctx context.Context, startOpts Config, fn StartCallback) error {
 		return fmt.Errorf("normalize workdir: %w", err)
 	}
 
-	router := router.New(startOpts.SessionToken, recorder)
+	router := router.New(startOpts.SessionToken, recorder, startOpts.QueryLimits, startOpts.Tokens)
+
+	// summarize the deprecated APIs used once the session is over, so they
+	// can be migrated away from before they're removed
+	defer reportDeprecations(recorder, router.Deprecations)
 	secretStore := secret.NewStore()
 
 	socketProviders := SocketProvider{
@@ -220,21 +245,25 @@ func Start(ctx context.Context, startOpts Config, fn StartCallback) error {
 			// Thankfully we can just yeet the gateway into the store.
 			secretStore.SetGateway(gw)
 
+			// stop watching the host's directories at the end of the session
+			host := core.NewHost(startOpts.Workdir, startOpts.DisableHostRW, startOpts.HostChangeCallback)
+			defer host.Close()
+
 			gwClient := core.NewGatewayClient(gw, cacheConfigType, cacheConfigAttrs)
 			coreAPI, err := schema.New(schema.InitializeArgs{
 				Router:         router,
-				Workdir:        startOpts.Workdir,
+				Host:           host,
 				Gateway:        gwClient,
 				BKClient:       c.BuildkitClient,
 				SolveOpts:      solveOpts,
 				SolveCh:        solveCh,
 				Platform:       *platform,
-				DisableHostRW:  startOpts.DisableHostRW,
 				Auth:           registryAuth,
 				EnableServices: os.Getenv(engine.ServicesDNSEnvName) != "0",
 				Secrets:        secretStore,
 				OCIStore:       ociStore,
 				ProgrockSocket: progSock,
+				Progress:       progress,
 			})
 			if err != nil {
 				return nil, err
@@ -279,6 +308,22 @@ func Start(ctx context.Context, startOpts Config, fn StartCallback) error {
 	return nil
 }
 
+// reportDeprecations records a vertex listing the deprecated fields resolved
+// in the session, if any.
+func reportDeprecations(recorder *progrock.Recorder, deprecations func() []router.DeprecatedField) {
+	fields := deprecations()
+	if len(fields) == 0 {
+		return
+	}
+
+	vtx := recorder.Vertex("deprecations", "deprecated API usage")
+	w := vtx.Stderr()
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s (%dx): %s\n", f.Field, f.Count, f.Reason)
+	}
+	vtx.Done(nil)
+}
+
 func NormalizeWorkdir(workdir string) (string, error) {
 	if workdir == "" {
 		workdir = os.Getenv("DAGGER_WORKDIR")
@@ -350,6 +395,13 @@ func cacheConfigFromEnv() (string, map[string]string, error) {
 		return "", nil, errors.Errorf("missing type in cache config: %q", envVal)
 	}
 	delete(attrs, "type")
+	if path, ok := attrs["path"]; ok && typeVal == "local" {
+		// accept the same form as the engine-wide cache backend; buildkit's local cache
+		// exporter and importer call the directory dest and src respectively
+		delete(attrs, "path")
+		attrs["dest"] = path
+		attrs["src"] = path
+	}
 	return typeVal, attrs, nil
 }
 
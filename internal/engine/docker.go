@@ -19,8 +19,9 @@ const (
 	// NOTE: this needs to be consistent with engineDefaultStateDir in internal/mage/engine.go
 	DefaultStateDir = "/var/lib/dagger"
 
-	CacheConfigEnvName = "_EXPERIMENTAL_DAGGER_CACHE_CONFIG"
-	ServicesDNSEnvName = "_EXPERIMENTAL_DAGGER_SERVICES_DNS"
+	CacheConfigEnvName  = "_EXPERIMENTAL_DAGGER_CACHE_CONFIG"
+	CacheBackendEnvName = "_EXPERIMENTAL_DAGGER_CACHE_BACKEND"
+	ServicesDNSEnvName  = "_EXPERIMENTAL_DAGGER_SERVICES_DNS"
 
 	// trim image digests to 16 characters to makeoutput more readable
 	hashLen             = 16
@@ -93,6 +94,7 @@ func dockerImageProvider(ctx context.Context, runnerHost *url.URL, userAgent str
 		"-d",
 		"--restart", "always",
 		"-e", CacheConfigEnvName,
+		"-e", CacheBackendEnvName,
 		"-e", ServicesDNSEnvName,
 		"-v", DefaultStateDir,
 		"--privileged",
@@ -312,6 +312,7 @@ func (t Engine) Dev(ctx context.Context) error {
 		"-d",
 		// "--rm",
 		"-e", util.CacheConfigEnvName,
+		"-e", util.CacheBackendEnvName,
 		"-e", util.ServicesDNSEnvName,
 		"-e", "_EXPERIMENTAL_DAGGER_CLOUD_TOKEN",
 		"-e", "_EXPERIMENTAL_DAGGER_CLOUD_URL",
@@ -123,12 +123,19 @@ func (t Python) Generate(ctx context.Context) error {
 	}
 	cliBinPath := "/.dagger-cli"
 
+	// relative to the SDK, which is the container's workdir
+	gen := strings.TrimPrefix(pythonGeneratedAPIPaths[0], "sdk/python/")
+	genSync := strings.TrimPrefix(pythonGeneratedAPIPaths[1], "sdk/python/")
+
 	generated := pythonBase(c, pythonDefaultVersion).
+		WithMountedFile("/usr/local/bin/client-gen", util.ClientGenBinary(c)).
 		WithServiceBinding("dagger-engine", devEngine).
 		WithEnvVariable("_EXPERIMENTAL_DAGGER_RUNNER_HOST", endpoint).
 		WithMountedFile(cliBinPath, util.DaggerBinary(c)).
 		WithEnvVariable("_EXPERIMENTAL_DAGGER_CLI_BIN", cliBinPath).
-		WithExec([]string{"poe", "generate"})
+		WithExec([]string{"client-gen", "--lang", "python", "-o", gen}).
+		WithExec([]string{"client-gen", "--lang", "python", "--sync", "-o", genSync}).
+		WithExec([]string{"black", "--preview", gen, genSync})
 
 	for _, f := range pythonGeneratedAPIPaths {
 		contents, err := generated.File(strings.TrimPrefix(f, "sdk/python/")).Contents(ctx)
@@ -26,8 +26,9 @@ const (
 
 	engineEntrypointPath = "/usr/local/bin/dagger-entrypoint.sh"
 
-	CacheConfigEnvName = "_EXPERIMENTAL_DAGGER_CACHE_CONFIG"
-	ServicesDNSEnvName = "_EXPERIMENTAL_DAGGER_SERVICES_DNS"
+	CacheConfigEnvName  = "_EXPERIMENTAL_DAGGER_CACHE_CONFIG"
+	CacheBackendEnvName = "_EXPERIMENTAL_DAGGER_CACHE_BACKEND"
+	ServicesDNSEnvName  = "_EXPERIMENTAL_DAGGER_SERVICES_DNS"
 )
 
 const engineEntrypointTmpl = `#!/bin/sh
@@ -6,7 +6,7 @@ import (
 	tools "github.com/dagger/graphql-go-tools"
 )
 
-func compile(s ExecutableSchema) (*graphql.Schema, error) {
+func compile(s ExecutableSchema, deprecations *deprecationReport) (*graphql.Schema, error) {
 	typeResolvers := tools.ResolverMap{}
 	for name, resolver := range s.Resolvers() {
 		switch resolver := resolver.(type) {
@@ -17,7 +17,21 @@ func compile(s ExecutableSchema) (*graphql.Schema, error) {
 			typeResolvers[name] = obj
 			for fieldName, fn := range resolver.Fields() {
 				obj.Fields[fieldName] = &tools.FieldResolve{
-					Resolve: fn,
+					Resolve: scoped(name, fieldName, fn),
+				}
+			}
+		case SubscriptionResolver:
+			obj := &tools.ObjectResolver{
+				Fields: tools.FieldResolveMap{},
+			}
+			typeResolvers[name] = obj
+			for fieldName, fn := range resolver {
+				obj.Fields[fieldName] = &tools.FieldResolve{
+					Subscribe: scoped(name, fieldName, fn),
+					// each event is the root value of the response
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return p.Source, nil
+					},
 				}
 			}
 		case ScalarResolver:
@@ -46,7 +60,10 @@ func compile(s ExecutableSchema) (*graphql.Schema, error) {
 				},
 			},
 		},
-		Extensions: []graphql.Extension{&tracing.GraphQLTracer{}},
+		Extensions: []graphql.Extension{
+			&tracing.GraphQLTracer{},
+			&deprecationTracker{session: deprecations},
+		},
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,122 @@
+package router
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/dagger/graphql"
+	"github.com/dagger/graphql/gqlerrors"
+)
+
+// DeprecatedField is a deprecated field resolved in a session or request.
+type DeprecatedField struct {
+	// Field is the field as "Type.field", e.g. "Container.exec".
+	Field string `json:"field"`
+	// Reason is the field's deprecation reason, which usually points to its
+	// replacement.
+	Reason string `json:"reason"`
+	// Count is how many times the field was resolved.
+	Count int `json:"count"`
+}
+
+// deprecationReport counts the deprecated fields resolved.
+type deprecationReport struct {
+	fields map[string]*DeprecatedField
+	l      sync.Mutex
+}
+
+func newDeprecationReport() *deprecationReport {
+	return &deprecationReport{fields: map[string]*DeprecatedField{}}
+}
+
+func (r *deprecationReport) record(field, reason string) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	f, ok := r.fields[field]
+	if !ok {
+		f = &DeprecatedField{Field: field, Reason: reason}
+		r.fields[field] = f
+	}
+	f.Count++
+}
+
+// list returns the deprecated fields resolved, sorted by field.
+func (r *deprecationReport) list() []DeprecatedField {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	fields := make([]DeprecatedField, 0, len(r.fields))
+	for _, f := range r.fields {
+		fields = append(fields, *f)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Field < fields[j].Field
+	})
+	return fields
+}
+
+var _ graphql.Extension = &deprecationTracker{}
+
+// deprecationTracker is a GraphQL extension recording the deprecated fields
+// resolved, both for the session and for each request. The request's are
+// returned in the "deprecations" extension of its response.
+type deprecationTracker struct {
+	session *deprecationReport
+}
+
+type deprecationReportKey struct{}
+
+func (t *deprecationTracker) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return context.WithValue(ctx, deprecationReportKey{}, newDeprecationReport())
+}
+
+func (t *deprecationTracker) Name() string {
+	return "deprecations"
+}
+
+func (t *deprecationTracker) HasResult() bool {
+	return true
+}
+
+func (t *deprecationTracker) GetResult(ctx context.Context) interface{} {
+	report, ok := ctx.Value(deprecationReportKey{}).(*deprecationReport)
+	if !ok {
+		return nil
+	}
+	fields := report.list()
+	if len(fields) == 0 {
+		// omitted from the response
+		return nil
+	}
+	return fields
+}
+
+func (t *deprecationTracker) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+func (t *deprecationTracker) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+func (t *deprecationTracker) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	return ctx, func(*graphql.Result) {}
+}
+
+func (t *deprecationTracker) ResolveFieldDidStart(ctx context.Context, i *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	parent, ok := i.ParentType.(interface {
+		Fields() graphql.FieldDefinitionMap
+	})
+	if ok {
+		if def, ok := parent.Fields()[i.FieldName]; ok && def.DeprecationReason != "" {
+			field := i.ParentType.Name() + "." + i.FieldName
+			t.session.record(field, def.DeprecationReason)
+			if report, ok := ctx.Value(deprecationReportKey{}).(*deprecationReport); ok {
+				report.record(field, def.DeprecationReason)
+			}
+		}
+	}
+	return ctx, func(interface{}, error) {}
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dagger/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterDeprecations(t *testing.T) {
+	t.Parallel()
+
+	r := New("", nil, Limits{}, nil)
+	require.NoError(t, r.Add(StaticSchema(StaticSchemaParams{
+		Name: "test",
+		Schema: `
+		type Query {
+			current: String
+			old: String @deprecated(reason: "Replaced by ` + "`current`" + `.")
+		}
+		`,
+		Resolvers: Resolvers{
+			"Query": ObjectResolver{
+				"current": func(p graphql.ResolveParams) (any, error) { return "yes", nil },
+				"old":     func(p graphql.ResolveParams) (any, error) { return "yes", nil },
+			},
+		},
+	})))
+
+	query := func(q string) map[string]any {
+		req := httptest.NewRequest("GET", "/query?query="+url.QueryEscape(q), nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		var result struct {
+			Extensions map[string]any `json:"extensions"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		return result.Extensions
+	}
+
+	require.Nil(t, query("{current}"))
+	require.Empty(t, r.Deprecations())
+
+	require.Equal(t, map[string]any{
+		"deprecations": []any{
+			map[string]any{"field": "Query.old", "reason": "Replaced by `current`.", "count": float64(2)},
+		},
+	}, query("{current old alias: old}"))
+
+	query("{old}")
+	require.Equal(t, []DeprecatedField{
+		{Field: "Query.old", Reason: "Replaced by `current`.", Count: 3},
+	}, r.Deprecations())
+}
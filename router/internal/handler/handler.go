@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/dagger/graphql"
 	"github.com/dagger/graphql/gqlerrors"
@@ -22,6 +25,8 @@ type ResultCallbackFn func(ctx context.Context, params *graphql.Params, result *
 
 type Handler struct {
 	Schema           *graphql.Schema
+	persistedQueries *PersistedQueryCache
+	limits           Limits
 	pretty           bool
 	rootObjectFn     RootObjectFn
 	resultCallbackFn ResultCallbackFn
@@ -32,6 +37,11 @@ type RequestOptions struct {
 	Query         string                 `json:"query" url:"query" schema:"query"`
 	Variables     map[string]interface{} `json:"variables" url:"variables" schema:"variables"`
 	OperationName string                 `json:"operationName" url:"operationName" schema:"operationName"`
+	Extensions    *RequestExtensions     `json:"extensions,omitempty" url:"extensions" schema:"extensions"`
+}
+
+type RequestExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
 }
 
 // a workaround for getting`variables` as a JSON string
@@ -43,17 +53,23 @@ type requestOptionsCompatibility struct {
 
 func getFromForm(values url.Values) *RequestOptions {
 	query := values.Get("query")
-	if query != "" {
+	extensionsStr := values.Get("extensions")
+	if query != "" || extensionsStr != "" {
 		// get variables map
 		variables := make(map[string]interface{}, len(values))
 		variablesStr := values.Get("variables")
 		json.Unmarshal([]byte(variablesStr), &variables)
 
-		return &RequestOptions{
+		opts := &RequestOptions{
 			Query:         query,
 			Variables:     variables,
 			OperationName: values.Get("operationName"),
 		}
+		if extensionsStr != "" {
+			opts.Extensions = &RequestExtensions{}
+			json.Unmarshal([]byte(extensionsStr), opts.Extensions)
+		}
+		return opts
 	}
 
 	return nil
@@ -118,16 +134,122 @@ func NewRequestOptions(r *http.Request) *RequestOptions {
 	}
 }
 
+// newBatchRequestOptions parses a batch of requests, sent as a JSON array in
+// the body of a POST. If the request isn't a batch, its body is left for
+// NewRequestOptions to read.
+func newBatchRequestOptions(r *http.Request) ([]*RequestOptions, bool) {
+	if r.Method != http.MethodPost || r.Body == nil || r.URL.Query().Get("query") != "" {
+		return nil, false
+	}
+	contentType := strings.Split(r.Header.Get("Content-Type"), ";")[0]
+	if contentType != ContentTypeJSON {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '[' {
+		return nil, false
+	}
+	var batch []*RequestOptions
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		return nil, false
+	}
+	for i, opts := range batch {
+		if opts == nil {
+			batch[i] = &RequestOptions{}
+		}
+	}
+	return batch, true
+}
+
 // ContextHandler provides an entrypoint into executing graphQL queries with a
 // user-provided context.
+//
+// A POST with a JSON array of requests is executed as a batch: the requests
+// run concurrently, and their results are returned as an array in the same
+// order.
 func (h *Handler) ContextHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	// get query
-	opts := NewRequestOptions(r)
+	batch, isBatch := newBatchRequestOptions(r)
+	if !isBatch {
+		batch = []*RequestOptions{NewRequestOptions(r)}
+	} else if err := h.limits.CheckBatch(len(batch)); err != nil {
+		// the queries of a batch run concurrently, so bound how many
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		buff, _ := json.Marshal(&graphql.Result{Errors: []gqlerrors.FormattedError{*err}})
+		w.Write(buff)
+		return
+	}
 
-	// execute graphql query
-	params := graphql.Params{
+	results := make([]*graphql.Result, len(batch))
+	params := make([]*graphql.Params, len(batch))
+	if isBatch {
+		var wg sync.WaitGroup
+		for i, opts := range batch {
+			i, opts := i, opts
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					// a panic would otherwise take down the whole server, since
+					// it's not on the request's goroutine
+					if v := recover(); v != nil {
+						results[i] = &graphql.Result{
+							Errors: []gqlerrors.FormattedError{
+								gqlerrors.NewFormattedError(fmt.Sprint(v)),
+							},
+						}
+					}
+				}()
+				params[i], results[i] = h.execute(ctx, r, opts)
+			}()
+		}
+		wg.Wait()
+	} else {
+		params[0], results[0] = h.execute(ctx, r, batch[0])
+	}
+
+	// use proper JSON Header
+	w.Header().Add("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	var res any = results[0]
+	if isBatch {
+		res = results
+	}
+	var buff []byte
+	if h.pretty {
+		buff, _ = json.MarshalIndent(res, "", "\t")
+	} else {
+		buff, _ = json.Marshal(res)
+	}
+	w.Write(buff)
+
+	if h.resultCallbackFn != nil {
+		for i, result := range results {
+			if params[i] == nil {
+				// the request panicked
+				continue
+			}
+			resultBuff := buff
+			if isBatch {
+				resultBuff, _ = json.Marshal(result)
+			}
+			h.resultCallbackFn(ctx, params[i], result, resultBuff)
+		}
+	}
+}
+
+// execute runs a single request, resolving its persisted query if needed.
+func (h *Handler) execute(ctx context.Context, r *http.Request, opts *RequestOptions) (*graphql.Params, *graphql.Result) {
+	params := &graphql.Params{
 		Schema:         *h.Schema,
-		RequestString:  opts.Query,
 		VariableValues: opts.Variables,
 		OperationName:  opts.OperationName,
 		Context:        ctx,
@@ -135,7 +257,15 @@ func (h *Handler) ContextHandler(ctx context.Context, w http.ResponseWriter, r *
 	if h.rootObjectFn != nil {
 		params.RootObject = h.rootObjectFn(ctx, r)
 	}
-	result := graphql.Do(params)
+
+	query, result := h.persistedQueries.resolve(opts)
+	if result != nil {
+		return params, result
+	}
+	params.RequestString = query
+
+	// execute graphql query
+	result = h.limits.Do(*params)
 
 	if formatErrorFn := h.formatErrorFn; formatErrorFn != nil && len(result.Errors) > 0 {
 		formatted := make([]gqlerrors.FormattedError, len(result.Errors))
@@ -144,30 +274,28 @@ func (h *Handler) ContextHandler(ctx context.Context, w http.ResponseWriter, r *
 		}
 		result.Errors = formatted
 	}
+	omitNilExtensions(result)
+	return params, result
+}
 
-	// use proper JSON Header
-	w.Header().Add("Content-Type", "application/json; charset=utf-8")
-
-	var buff []byte
-	if h.pretty {
-		w.WriteHeader(http.StatusOK)
-		buff, _ = json.MarshalIndent(result, "", "\t")
-
-		w.Write(buff)
-	} else {
-		w.WriteHeader(http.StatusOK)
-		buff, _ = json.Marshal(result)
-
-		w.Write(buff)
-	}
-
-	if h.resultCallbackFn != nil {
-		h.resultCallbackFn(ctx, &params, result, buff)
+// omitNilExtensions removes the extensions which have nothing to report for
+// the request from its result, since extensions are included in every
+// response otherwise.
+func omitNilExtensions(result *graphql.Result) {
+	for name, ext := range result.Extensions {
+		if ext == nil {
+			delete(result.Extensions, name)
+		}
 	}
 }
 
-// ServeHTTP provides an entrypoint into executing graphQL queries.
+// ServeHTTP provides an entrypoint into executing graphQL queries, or
+// subscriptions if the request is a WebSocket handshake.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isWebSocket(r) {
+		h.ServeWebSocket(r.Context(), w, r)
+		return
+	}
 	h.ContextHandler(r.Context(), w, r)
 }
 
@@ -175,7 +303,13 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 type RootObjectFn func(ctx context.Context, r *http.Request) map[string]interface{}
 
 type Config struct {
-	Schema           *graphql.Schema
+	Schema *graphql.Schema
+	// PersistedQueries stores the queries sent along with their hash, so they
+	// can be sent by hash alone from then on. Persisted queries are not
+	// supported if it's nil.
+	PersistedQueries *PersistedQueryCache
+	// Limits bounds the queries executed.
+	Limits           Limits
 	Pretty           bool
 	RootObjectFn     RootObjectFn
 	ResultCallbackFn ResultCallbackFn
@@ -200,6 +334,8 @@ func New(p *Config) *Handler {
 
 	return &Handler{
 		Schema:           p.Schema,
+		persistedQueries: p.PersistedQueries,
+		limits:           p.Limits,
 		pretty:           p.Pretty,
 		rootObjectFn:     p.RootObjectFn,
 		resultCallbackFn: p.ResultCallbackFn,
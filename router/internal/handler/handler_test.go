@@ -1,11 +1,14 @@
 package handler_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -286,3 +289,87 @@ func TestHandler_BasicQuery_WithFormatErrorFn(t *testing.T) {
 		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, result))
 	}
 }
+
+func TestHandler_BatchQuery(t *testing.T) {
+	body := `[
+		{"query": "query HeroNameQuery { hero { name } }", "operationName": "HeroNameQuery"},
+		{"query": "query { hero(episode: EMPIRE) { name } }"}
+	]`
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+
+	h := handler.New(&handler.Config{
+		Schema: &testutil.StarWarsSchema,
+	})
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected server response %v", resp.Code)
+	}
+
+	var results []*graphql.Result
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	expected := []*graphql.Result{
+		{Data: map[string]interface{}{"hero": map[string]interface{}{"name": "R2-D2"}}},
+		{Data: map[string]interface{}{"hero": map[string]interface{}{"name": "Luke Skywalker"}}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, results))
+	}
+}
+
+func TestHandler_PersistedQuery(t *testing.T) {
+	query := "query HeroNameQuery { hero { name } }"
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+
+	cache, err := handler.NewPersistedQueryCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.New(&handler.Config{
+		Schema:           &testutil.StarWarsSchema,
+		PersistedQueries: cache,
+	})
+
+	post := func(body string) *graphql.Result {
+		req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		result, _ := executeTest(t, h, req)
+		return result
+	}
+	extensions := fmt.Sprintf(`{"persistedQuery": {"version": 1, "sha256Hash": %q}}`, hash)
+	expected := &graphql.Result{
+		Data: map[string]interface{}{"hero": map[string]interface{}{"name": "R2-D2"}},
+	}
+
+	// the hash alone isn't known yet
+	result := post(fmt.Sprintf(`{"extensions": %s}`, extensions))
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "PERSISTED_QUERY_NOT_FOUND" {
+		t.Fatalf("expected PERSISTED_QUERY_NOT_FOUND, got %v", result.Errors)
+	}
+
+	// the query doesn't match the hash
+	result = post(fmt.Sprintf(`{"query": "{ hero { id } }", "extensions": %s}`, extensions))
+	if len(result.Errors) != 1 || result.Errors[0].Message != "provided sha does not match query" {
+		t.Fatalf("expected a hash mismatch, got %v", result.Errors)
+	}
+
+	result = post(fmt.Sprintf(`{"query": %q, "extensions": %s}`, query, extensions))
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, result))
+	}
+
+	// from then on, the hash is enough, including over GET
+	result = post(fmt.Sprintf(`{"extensions": %s}`, extensions))
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, result))
+	}
+	req, _ := http.NewRequest("GET", "/graphql?extensions="+url.QueryEscape(extensions), nil)
+	result, _ = executeTest(t, h, req)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("wrong result, graphql result diff: %v", testutil.Diff(expected, result))
+	}
+}
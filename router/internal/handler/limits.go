@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dagger/graphql"
+	"github.com/dagger/graphql/gqlerrors"
+	"github.com/dagger/graphql/language/ast"
+	"github.com/dagger/graphql/language/parser"
+	"github.com/dagger/graphql/language/source"
+)
+
+// Limits bounds the queries a handler executes. The zero value of each
+// limit means unlimited.
+type Limits struct {
+	// MaxDepth is the maximum nesting of fields in a query.
+	MaxDepth int
+
+	// MaxAliases is the maximum number of aliased fields in a query,
+	// counting the fields of fragments each time they're spread.
+	MaxAliases int
+
+	// MaxBatchSize is the maximum number of queries sent in a batch.
+	MaxBatchSize int
+
+	// Timeout is how long a query may run. It doesn't apply to
+	// subscriptions, which run until the subscriber goes away.
+	Timeout time.Duration
+}
+
+// error codes set in the extensions of the errors returned when a limit is
+// exceeded
+const (
+	ErrCodeQueryTooDeep   = "QUERY_TOO_DEEP"
+	ErrCodeTooManyAliases = "TOO_MANY_ALIASES"
+	ErrCodeQueryTimedOut  = "QUERY_TIMEOUT"
+	ErrCodeBatchTooLarge  = "BATCH_TOO_LARGE"
+)
+
+// CheckBatch returns an error if a batch of size queries is too large.
+func (l Limits) CheckBatch(size int) *gqlerrors.FormattedError {
+	if l.MaxBatchSize <= 0 || size <= l.MaxBatchSize {
+		return nil
+	}
+	return &gqlerrors.FormattedError{
+		Message: fmt.Sprintf("batch of %d queries exceeds the maximum of %d", size, l.MaxBatchSize),
+		Extensions: map[string]interface{}{
+			"code":         ErrCodeBatchTooLarge,
+			"size":         size,
+			"maxBatchSize": l.MaxBatchSize,
+		},
+	}
+}
+
+// Check returns an error for each limit the query exceeds. Queries which
+// don't parse pass, leaving it to the execution to report the syntax error.
+func (l Limits) Check(query string) []gqlerrors.FormattedError {
+	if l.MaxDepth <= 0 && l.MaxAliases <= 0 {
+		return nil
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(query),
+			Name: "GraphQL request",
+		}),
+	})
+	if err != nil {
+		return nil
+	}
+
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok && frag.Name != nil {
+			fragments[frag.Name.Value] = frag
+		}
+	}
+
+	var errs []gqlerrors.FormattedError
+	if l.MaxDepth > 0 {
+		counter := &depthCounter{
+			fragments: fragments,
+			depths:    map[string]int{},
+			visiting:  map[string]bool{},
+		}
+		depth := 0
+		for _, def := range doc.Definitions {
+			if op, ok := def.(*ast.OperationDefinition); ok {
+				if d := counter.depth(op.SelectionSet); d > depth {
+					depth = d
+				}
+			}
+		}
+		if depth > l.MaxDepth {
+			errs = append(errs, gqlerrors.FormattedError{
+				Message: fmt.Sprintf("query depth %d exceeds the maximum of %d", depth, l.MaxDepth),
+				Extensions: map[string]interface{}{
+					"code":     ErrCodeQueryTooDeep,
+					"depth":    depth,
+					"maxDepth": l.MaxDepth,
+				},
+			})
+		}
+	}
+	if l.MaxAliases > 0 {
+		counter := &aliasCounter{
+			fragments: fragments,
+			counts:    map[string]int{},
+			visiting:  map[string]bool{},
+		}
+		aliases := 0
+		for _, def := range doc.Definitions {
+			if op, ok := def.(*ast.OperationDefinition); ok {
+				aliases = saturatingAdd(aliases, counter.count(op.SelectionSet))
+			}
+		}
+		if aliases > l.MaxAliases {
+			errs = append(errs, gqlerrors.FormattedError{
+				Message: fmt.Sprintf("query has %d aliases, exceeding the maximum of %d", aliases, l.MaxAliases),
+				Extensions: map[string]interface{}{
+					"code":       ErrCodeTooManyAliases,
+					"aliases":    aliases,
+					"maxAliases": l.MaxAliases,
+				},
+			})
+		}
+	}
+	return errs
+}
+
+// WithTimeout returns a context which is done once the timeout elapses.
+func (l Limits) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, l.Timeout)
+}
+
+// TimeoutError returns the error to report for a query which ran out of
+// time, if it did.
+func (l Limits) TimeoutError(ctx context.Context) *gqlerrors.FormattedError {
+	if l.Timeout <= 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	return &gqlerrors.FormattedError{
+		Message: fmt.Sprintf("query exceeded the timeout of %s", l.Timeout),
+		Extensions: map[string]interface{}{
+			"code":    ErrCodeQueryTimedOut,
+			"timeout": l.Timeout.String(),
+		},
+	}
+}
+
+// Do executes a query within the limits, like graphql.Do.
+func (l Limits) Do(params graphql.Params) *graphql.Result {
+	if errs := l.Check(params.RequestString); len(errs) > 0 {
+		return &graphql.Result{Errors: errs}
+	}
+
+	ctx, cancel := l.WithTimeout(params.Context)
+	defer cancel()
+	params.Context = ctx
+
+	result := graphql.Do(params)
+	if err := l.TimeoutError(ctx); err != nil {
+		// the resolvers' own errors only say the context was canceled
+		result.Data = nil
+		result.Errors = []gqlerrors.FormattedError{*err}
+	}
+	return result
+}
+
+// depthCounter computes how deeply fields are nested in selection sets. The
+// depth of each fragment is only computed once, since fragments may be
+// spread many times over, and fragments being visited are tracked to avoid
+// following cycles, which fail validation anyway.
+type depthCounter struct {
+	fragments map[string]*ast.FragmentDefinition
+	depths    map[string]int
+	visiting  map[string]bool
+}
+
+func (c *depthCounter) depth(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	depth := 0
+	for _, sel := range set.Selections {
+		d := 0
+		switch sel := sel.(type) {
+		case *ast.Field:
+			d = 1 + c.depth(sel.SelectionSet)
+		case *ast.InlineFragment:
+			d = c.depth(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Name != nil {
+				d = c.fragmentDepth(sel.Name.Value)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+func (c *depthCounter) fragmentDepth(name string) int {
+	if d, ok := c.depths[name]; ok {
+		return d
+	}
+	frag, ok := c.fragments[name]
+	if !ok || c.visiting[name] {
+		return 0
+	}
+	c.visiting[name] = true
+	d := c.depth(frag.SelectionSet)
+	delete(c.visiting, name)
+	c.depths[name] = d
+	return d
+}
+
+// aliasCounter counts the aliased fields in selection sets, including the
+// ones of the fragments they spread, each time they're spread: nesting
+// fragments would otherwise multiply the fields resolved while keeping the
+// count small. Like depthCounter, the count of each fragment is only
+// computed once, and cycles aren't followed.
+type aliasCounter struct {
+	fragments map[string]*ast.FragmentDefinition
+	counts    map[string]int
+	visiting  map[string]bool
+}
+
+func (c *aliasCounter) count(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	count := 0
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if sel.Alias != nil {
+				count = saturatingAdd(count, 1)
+			}
+			count = saturatingAdd(count, c.count(sel.SelectionSet))
+		case *ast.InlineFragment:
+			count = saturatingAdd(count, c.count(sel.SelectionSet))
+		case *ast.FragmentSpread:
+			if sel.Name != nil {
+				count = saturatingAdd(count, c.fragmentCount(sel.Name.Value))
+			}
+		}
+	}
+	return count
+}
+
+func (c *aliasCounter) fragmentCount(name string) int {
+	if n, ok := c.counts[name]; ok {
+		return n
+	}
+	frag, ok := c.fragments[name]
+	if !ok || c.visiting[name] {
+		return 0
+	}
+	c.visiting[name] = true
+	n := c.count(frag.SelectionSet)
+	delete(c.visiting, name)
+	c.counts[name] = n
+	return n
+}
+
+// saturatingAdd adds two non-negative counts, which may grow exponentially
+// with nested fragments, without overflowing.
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+	return a + b
+}
@@ -0,0 +1,167 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dagger/dagger/router/internal/handler"
+	"github.com/dagger/graphql"
+	"github.com/dagger/graphql/testutil"
+)
+
+func TestLimits_Check(t *testing.T) {
+	limits := handler.Limits{MaxDepth: 3, MaxAliases: 2}
+
+	for _, tc := range []struct {
+		query string
+		codes []string
+	}{
+		{query: `{ hero { friends { name } } }`},
+		{query: `{ hero { friends { friends { name } } } }`, codes: []string{handler.ErrCodeQueryTooDeep}},
+		{
+			// fragments count where they're spread
+			query: `{ hero { ...Friends } } fragment Friends on Character { friends { friends { name } } }`,
+			codes: []string{handler.ErrCodeQueryTooDeep},
+		},
+		{
+			// cycles don't hang the check
+			query: `{ hero { ...A } } fragment A on Character { ...B } fragment B on Character { ...A }`,
+		},
+		{query: `{ a: hero { name } b: hero { name } }`},
+		{
+			query: `{ a: hero { n: name } b: hero { name } c: hero { name } }`,
+			codes: []string{handler.ErrCodeTooManyAliases},
+		},
+		{
+			query: `{ a: hero { friends { friends { name } } } b: hero { name } c: hero { name } }`,
+			codes: []string{handler.ErrCodeQueryTooDeep, handler.ErrCodeTooManyAliases},
+		},
+		{
+			// aliases count each time their fragment is spread
+			query: `{ hero { ...A ...A } } fragment A on Character { a: name b: name }`,
+			codes: []string{handler.ErrCodeTooManyAliases},
+		},
+		{
+			// nested fragments multiply their aliases
+			query: `{ hero { ...C } }
+			fragment A on Character { a: name }
+			fragment B on Character { ...A ...A }
+			fragment C on Character { ...B ...B }`,
+			codes: []string{handler.ErrCodeTooManyAliases},
+		},
+		{
+			// unused fragments don't count
+			query: `{ hero { name } } fragment A on Character { a: name b: name c: name }`,
+		},
+		{
+			// syntax errors are left to the execution
+			query: `{ hero {`,
+		},
+	} {
+		errs := limits.Check(tc.query)
+		codes := []string{}
+		for _, err := range errs {
+			codes = append(codes, err.Extensions["code"].(string))
+		}
+		if len(codes) != len(tc.codes) || strings.Join(codes, ",") != strings.Join(tc.codes, ",") {
+			t.Fatalf("query %q: expected error codes %v, got %v", tc.query, tc.codes, codes)
+		}
+	}
+
+	if errs := (handler.Limits{}).Check(`{ a: hero { friends { friends { name } } } }`); len(errs) != 0 {
+		t.Fatalf("expected no limits by default, got %v", errs)
+	}
+
+	// exponentially many aliases don't overflow the count
+	query := "{ hero { ...F0 } } fragment F40 on Character { a: name b: name }"
+	for i := 0; i < 40; i++ {
+		query += fmt.Sprintf(" fragment F%d on Character { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	if errs := limits.Check(query); len(errs) != 1 || errs[0].Extensions["code"] != handler.ErrCodeTooManyAliases {
+		t.Fatalf("expected %s, got %v", handler.ErrCodeTooManyAliases, errs)
+	}
+}
+
+func TestHandler_Limits(t *testing.T) {
+	slowSchema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"slow": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						select {
+						case <-time.After(10 * time.Second):
+							return "done", nil
+						case <-p.Context.Done():
+							return nil, p.Context.Err()
+						}
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.New(&handler.Config{
+		Schema: &slowSchema,
+		Limits: handler.Limits{Timeout: 10 * time.Millisecond},
+	})
+	req, _ := http.NewRequest("GET", "/graphql?query={slow}", nil)
+	result, _ := executeTest(t, h, req)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != handler.ErrCodeQueryTimedOut {
+		t.Fatalf("expected %s, got %v", handler.ErrCodeQueryTimedOut, result.Errors)
+	}
+
+	h = handler.New(&handler.Config{
+		Schema: &testutil.StarWarsSchema,
+		Limits: handler.Limits{MaxDepth: 1},
+	})
+	req, _ = http.NewRequest("GET", "/graphql?query={hero{name}}", nil)
+	result, _ = executeTest(t, h, req)
+	if result.Data != nil || len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != handler.ErrCodeQueryTooDeep {
+		t.Fatalf("expected %s, got %v", handler.ErrCodeQueryTooDeep, result.Errors)
+	}
+}
+
+func TestHandler_BatchLimit(t *testing.T) {
+	h := handler.New(&handler.Config{
+		Schema: &testutil.StarWarsSchema,
+		Limits: handler.Limits{MaxBatchSize: 2},
+	})
+
+	post := func(size int) *httptest.ResponseRecorder {
+		queries := make([]string, size)
+		for i := range queries {
+			queries[i] = `{"query": "{ hero { name } }"}`
+		}
+		req, _ := http.NewRequest("POST", "/graphql", strings.NewReader("["+strings.Join(queries, ",")+"]"))
+		req.Header.Add("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := post(2); resp.Code != http.StatusOK {
+		t.Fatalf("unexpected server response %v", resp.Code)
+	}
+
+	resp := post(3)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected server response %v", resp.Code)
+	}
+	var result graphql.Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != handler.ErrCodeBatchTooLarge {
+		t.Fatalf("expected %s, got %v", handler.ErrCodeBatchTooLarge, result.Errors)
+	}
+}
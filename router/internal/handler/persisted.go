@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/dagger/graphql"
+	"github.com/dagger/graphql/gqlerrors"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// PersistedQuery is the persistedQuery request extension of Automatic
+// Persisted Queries: a query is sent along with its hash once, and by its
+// hash alone from then on.
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+const persistedQueryVersion = 1
+
+// errors defined by the Automatic Persisted Queries protocol, which clients
+// recognize to retry with the full query
+var (
+	errPersistedQueryNotFound = gqlerrors.FormattedError{
+		Message:    "PersistedQueryNotFound",
+		Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"},
+	}
+	errPersistedQueryNotSupported = gqlerrors.FormattedError{
+		Message:    "PersistedQueryNotSupported",
+		Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_SUPPORTED"},
+	}
+)
+
+// PersistedQueryCache holds the most recently used persisted queries by hash.
+type PersistedQueryCache struct {
+	queries *lru.Cache
+}
+
+func NewPersistedQueryCache(size int) (*PersistedQueryCache, error) {
+	queries, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &PersistedQueryCache{queries: queries}, nil
+}
+
+// resolve returns the query to execute for the request, or the result to
+// return instead if the request's persisted query can't be resolved.
+func (c *PersistedQueryCache) resolve(opts *RequestOptions) (string, *graphql.Result) {
+	if opts.Extensions == nil || opts.Extensions.PersistedQuery == nil {
+		return opts.Query, nil
+	}
+	pq := opts.Extensions.PersistedQuery
+	if c == nil || pq.Version != persistedQueryVersion {
+		return "", &graphql.Result{
+			Errors: []gqlerrors.FormattedError{errPersistedQueryNotSupported},
+		}
+	}
+
+	if opts.Query == "" {
+		query, ok := c.queries.Get(pq.Sha256Hash)
+		if !ok {
+			return "", &graphql.Result{
+				Errors: []gqlerrors.FormattedError{errPersistedQueryNotFound},
+			}
+		}
+		return query.(string), nil
+	}
+
+	sum := sha256.Sum256([]byte(opts.Query))
+	if hex.EncodeToString(sum[:]) != pq.Sha256Hash {
+		return "", &graphql.Result{
+			Errors: []gqlerrors.FormattedError{
+				gqlerrors.NewFormattedError("provided sha does not match query"),
+			},
+		}
+	}
+	c.queries.Add(pq.Sha256Hash, opts.Query)
+	return opts.Query, nil
+}
@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dagger/graphql"
+	"github.com/dagger/graphql/gqlerrors"
+	"github.com/dagger/graphql/language/ast"
+	"github.com/dagger/graphql/language/parser"
+	"github.com/dagger/graphql/language/source"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// ProtocolGraphQLTransportWS is the protocol implemented by the
+	// graphql-ws library.
+	ProtocolGraphQLTransportWS = "graphql-transport-ws"
+
+	// ProtocolGraphQLWS is the legacy protocol implemented by the
+	// subscriptions-transport-ws library, still used by many clients.
+	ProtocolGraphQLWS = "graphql-ws"
+
+	// connectionInitTimeout is how long a client has to send connection_init
+	// after connecting.
+	connectionInitTimeout = 10 * time.Second
+)
+
+// close codes defined by the graphql-transport-ws protocol
+const (
+	closeBadRequest          = 4400
+	closeUnauthorized        = 4401
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInitRequests = 4429
+)
+
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{ProtocolGraphQLTransportWS, ProtocolGraphQLWS},
+}
+
+// wsMessage is a message of either WebSocket protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsMessageTypes holds the message types which differ between the protocols.
+type wsMessageTypes struct {
+	subscribe string
+	next      string
+	stop      string
+}
+
+var (
+	transportWSMessageTypes = wsMessageTypes{
+		subscribe: "subscribe",
+		next:      "next",
+		stop:      "complete",
+	}
+	legacyWSMessageTypes = wsMessageTypes{
+		subscribe: "start",
+		next:      "data",
+		stop:      "stop",
+	}
+)
+
+// isWebSocket returns whether the request is a WebSocket handshake.
+func isWebSocket(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// ServeWebSocket serves GraphQL operations, including subscriptions, over a
+// WebSocket, using either the graphql-transport-ws or the legacy graphql-ws
+// protocol, as negotiated with the client. Any kind of operation may be sent,
+// but only subscriptions return more than one result.
+func (h *Handler) ServeWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied with an error
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c := &wsConn{
+		h:     h,
+		conn:  conn,
+		types: transportWSMessageTypes,
+		ops:   map[string]context.CancelFunc{},
+	}
+	if conn.Subprotocol() == ProtocolGraphQLWS {
+		c.legacy = true
+		c.types = legacyWSMessageTypes
+	}
+	c.serve(ctx)
+}
+
+type wsConn struct {
+	h      *Handler
+	conn   *websocket.Conn
+	legacy bool
+	types  wsMessageTypes
+
+	// writeL serializes writes, which may come from any operation
+	writeL sync.Mutex
+
+	ops   map[string]context.CancelFunc
+	opsL  sync.Mutex
+	opsWG sync.WaitGroup
+}
+
+func (c *wsConn) serve(ctx context.Context) {
+	defer func() {
+		c.opsL.Lock()
+		for _, cancel := range c.ops {
+			cancel()
+		}
+		c.opsL.Unlock()
+		c.opsWG.Wait()
+	}()
+
+	c.conn.SetReadDeadline(time.Now().Add(connectionInitTimeout))
+
+	initialized := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if !initialized && isTimeout(err) {
+				c.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if initialized {
+				c.close(closeTooManyInitRequests, "Too many initialisation requests")
+				return
+			}
+			initialized = true
+			c.conn.SetReadDeadline(time.Time{})
+			c.write(wsMessage{Type: "connection_ack"})
+			if c.legacy {
+				c.write(wsMessage{Type: "ka"})
+			}
+		case "ping":
+			c.write(wsMessage{Type: "pong", Payload: msg.Payload})
+		case "pong":
+		case c.types.subscribe:
+			if !initialized {
+				c.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var opts RequestOptions
+			if err := json.Unmarshal(msg.Payload, &opts); err != nil || msg.ID == "" {
+				c.close(closeBadRequest, "Invalid message received")
+				return
+			}
+			if !c.start(ctx, msg.ID, opts) {
+				c.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			}
+		case c.types.stop:
+			c.stop(msg.ID)
+		case "connection_terminate":
+			return
+		default:
+			c.close(closeBadRequest, fmt.Sprintf("Invalid message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// start runs an operation in the background, returning false if an
+// operation with the same ID is already running.
+func (c *wsConn) start(ctx context.Context, id string, opts RequestOptions) bool {
+	c.opsL.Lock()
+	defer c.opsL.Unlock()
+	if _, ok := c.ops[id]; ok {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.ops[id] = cancel
+	c.opsWG.Add(1)
+	go func() {
+		defer c.opsWG.Done()
+		c.run(ctx, id, opts)
+
+		c.opsL.Lock()
+		_, running := c.ops[id]
+		delete(c.ops, id)
+		c.opsL.Unlock()
+		cancel()
+
+		// the client doesn't expect complete for operations it stopped
+		if running {
+			c.write(wsMessage{ID: id, Type: "complete"})
+		}
+	}()
+	return true
+}
+
+func (c *wsConn) stop(id string) {
+	c.opsL.Lock()
+	defer c.opsL.Unlock()
+	if cancel, ok := c.ops[id]; ok {
+		delete(c.ops, id)
+		cancel()
+	}
+}
+
+func (c *wsConn) run(ctx context.Context, id string, opts RequestOptions) {
+	defer func() {
+		// resolvers may panic on invalid input, see router.ServeHTTP
+		if v := recover(); v != nil {
+			c.writeResult(id, &graphql.Result{
+				Errors: []gqlerrors.FormattedError{
+					gqlerrors.NewFormattedError(fmt.Sprint(v)),
+				},
+			})
+		}
+	}()
+
+	query, result := c.h.persistedQueries.resolve(&opts)
+	if result != nil {
+		c.writeResult(id, result)
+		return
+	}
+	opts.Query = query
+
+	params := graphql.Params{
+		Schema:         *c.h.Schema,
+		RequestString:  opts.Query,
+		VariableValues: opts.Variables,
+		OperationName:  opts.OperationName,
+		Context:        ctx,
+	}
+
+	if !isSubscription(opts) {
+		c.writeResult(id, c.h.limits.Do(params))
+		return
+	}
+
+	if errs := c.h.limits.Check(opts.Query); len(errs) > 0 {
+		c.writeResult(id, &graphql.Result{Errors: errs})
+		return
+	}
+
+	for result := range graphql.Subscribe(params) {
+		if ctx.Err() != nil {
+			// drain the results so the subscription can wind down
+			continue
+		}
+		c.writeResult(id, result)
+	}
+}
+
+func (c *wsConn) writeResult(id string, result *graphql.Result) {
+	if formatErrorFn := c.h.formatErrorFn; formatErrorFn != nil && len(result.Errors) > 0 {
+		formatted := make([]gqlerrors.FormattedError, len(result.Errors))
+		for i, formattedError := range result.Errors {
+			formatted[i] = formatErrorFn(formattedError.OriginalError())
+		}
+		result.Errors = formatted
+	}
+	omitNilExtensions(result)
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		payload, _ = json.Marshal(graphql.Result{
+			Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())},
+		})
+	}
+	c.write(wsMessage{ID: id, Type: c.types.next, Payload: payload})
+}
+
+func (c *wsConn) write(msg wsMessage) {
+	c.writeL.Lock()
+	defer c.writeL.Unlock()
+	// errors surface on the next read, which ends the connection
+	c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.writeL.Lock()
+	defer c.writeL.Unlock()
+	c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second),
+	)
+}
+
+// isSubscription returns whether the requested operation is a subscription.
+// Documents which don't parse aren't, so that graphql.Do reports the error.
+func isSubscription(opts RequestOptions) bool {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(opts.Query),
+			Name: "GraphQL request",
+		}),
+	})
+	if err != nil {
+		return false
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if opts.OperationName != "" && (op.Name == nil || op.Name.Value != opts.OperationName) {
+			continue
+		}
+		return op.Operation == ast.OperationTypeSubscription
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,198 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dagger/dagger/router/internal/handler"
+	"github.com/dagger/graphql"
+	"github.com/gorilla/websocket"
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func countdownSchema(t *testing.T) *graphql.Schema {
+	t.Helper()
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"hello": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return "world", nil
+					},
+				},
+			},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{
+				"countdown": &graphql.Field{
+					Type: graphql.Int,
+					Args: graphql.FieldConfigArgument{
+						{Name: "from", Type: graphql.Int},
+					},
+					Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+						ch := make(chan interface{})
+						go func() {
+							defer close(ch)
+							for i := p.Args["from"].(int64); i >= 0; i-- {
+								select {
+								case ch <- i:
+								case <-p.Context.Done():
+									return
+								}
+							}
+						}()
+						return ch, nil
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func dialWebSocket(t *testing.T, protocol string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(handler.New(&handler.Config{
+		Schema: countdownSchema(t),
+	}))
+	t.Cleanup(srv.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{protocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if conn.Subprotocol() != protocol {
+		t.Fatalf("unexpected subprotocol %q", conn.Subprotocol())
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func writeMessage(t *testing.T, conn *websocket.Conn, msg wsMessage) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectResult(t *testing.T, msg wsMessage, typ, id string, expected map[string]interface{}) {
+	t.Helper()
+	if msg.Type != typ || msg.ID != id {
+		t.Fatalf("unexpected message %s %q, expected %s %q", msg.Type, msg.ID, typ, id)
+	}
+	var result graphql.Result
+	if err := json.Unmarshal(msg.Payload, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.HasErrors() {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	data, _ := json.Marshal(result.Data)
+	expectedData, _ := json.Marshal(expected)
+	if !reflect.DeepEqual(data, expectedData) {
+		t.Fatalf("unexpected data %s, expected %s", data, expectedData)
+	}
+}
+
+func TestWebSocket_Subscription(t *testing.T) {
+	conn := dialWebSocket(t, handler.ProtocolGraphQLTransportWS)
+
+	writeMessage(t, conn, wsMessage{Type: "connection_init"})
+	if msg := readMessage(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %s", msg.Type)
+	}
+
+	writeMessage(t, conn, wsMessage{
+		ID:      "1",
+		Type:    "subscribe",
+		Payload: json.RawMessage(`{"query": "subscription { countdown(from: 2) }"}`),
+	})
+	for i := 2; i >= 0; i-- {
+		expectResult(t, readMessage(t, conn), "next", "1", map[string]interface{}{"countdown": i})
+	}
+	if msg := readMessage(t, conn); msg.Type != "complete" || msg.ID != "1" {
+		t.Fatalf("expected complete for 1, got %s %q", msg.Type, msg.ID)
+	}
+
+	// other operations return a single result
+	writeMessage(t, conn, wsMessage{
+		ID:      "2",
+		Type:    "subscribe",
+		Payload: json.RawMessage(`{"query": "{ hello }"}`),
+	})
+	expectResult(t, readMessage(t, conn), "next", "2", map[string]interface{}{"hello": "world"})
+	if msg := readMessage(t, conn); msg.Type != "complete" || msg.ID != "2" {
+		t.Fatalf("expected complete for 2, got %s %q", msg.Type, msg.ID)
+	}
+
+	writeMessage(t, conn, wsMessage{Type: "ping"})
+	if msg := readMessage(t, conn); msg.Type != "pong" {
+		t.Fatalf("expected pong, got %s", msg.Type)
+	}
+}
+
+func TestWebSocket_LegacyProtocol(t *testing.T) {
+	conn := dialWebSocket(t, handler.ProtocolGraphQLWS)
+
+	writeMessage(t, conn, wsMessage{Type: "connection_init"})
+	if msg := readMessage(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("expected connection_ack, got %s", msg.Type)
+	}
+	if msg := readMessage(t, conn); msg.Type != "ka" {
+		t.Fatalf("expected ka, got %s", msg.Type)
+	}
+
+	writeMessage(t, conn, wsMessage{
+		ID:      "1",
+		Type:    "start",
+		Payload: json.RawMessage(`{"query": "subscription { countdown(from: 1) }"}`),
+	})
+	expectResult(t, readMessage(t, conn), "data", "1", map[string]interface{}{"countdown": 1})
+	expectResult(t, readMessage(t, conn), "data", "1", map[string]interface{}{"countdown": 0})
+	if msg := readMessage(t, conn); msg.Type != "complete" || msg.ID != "1" {
+		t.Fatalf("expected complete for 1, got %s %q", msg.Type, msg.ID)
+	}
+}
+
+func TestWebSocket_SubscribeBeforeInit(t *testing.T) {
+	conn := dialWebSocket(t, handler.ProtocolGraphQLTransportWS)
+
+	writeMessage(t, conn, wsMessage{
+		ID:      "1",
+		Type:    "subscribe",
+		Payload: json.RawMessage(`{"query": "subscription { countdown(from: 1) }"}`),
+	})
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, 4401) {
+		t.Fatalf("expected close with 4401, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/opencontainers/go-digest"
+)
+
+// bounds of the results memoised per session, see ToPureResolver
+const (
+	memoMaxEntries = 10000
+	memoMaxBytes   = 64 * 1024 * 1024
+)
+
+// memoCache holds the most recently used results of pure fields by query
+// digest, up to a maximum number of entries and of bytes, as estimated by
+// the size of their JSON encoding.
+type memoCache struct {
+	entries  *simplelru.LRU
+	bytes    int
+	maxBytes int
+	l        sync.Mutex
+}
+
+type memoEntry struct {
+	val  any
+	size int
+}
+
+func newMemoCache(maxEntries, maxBytes int) *memoCache {
+	c := &memoCache{maxBytes: maxBytes}
+	entries, err := simplelru.NewLRU(maxEntries, func(_, val any) {
+		c.bytes -= val.(memoEntry).size
+	})
+	if err != nil {
+		// only fails on a non-positive size
+		panic(err)
+	}
+	c.entries = entries
+	return c
+}
+
+func (c *memoCache) get(key digest.Digest) (any, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	entry, ok := c.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return entry.(memoEntry).val, true
+}
+
+func (c *memoCache) add(key digest.Digest, val any) {
+	payload, err := json.Marshal(val)
+	if err != nil || len(payload) > c.maxBytes {
+		return
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	// the LRU replaces the value of a key that's already cached, e.g. when
+	// the same field is resolved concurrently, without evicting it
+	if old, ok := c.entries.Peek(key); ok {
+		c.bytes -= old.(memoEntry).size
+	}
+	c.entries.Add(key, memoEntry{val: val, size: len(payload)})
+	c.bytes += len(payload)
+	for c.bytes > c.maxBytes && c.entries.Len() > 0 {
+		c.entries.RemoveOldest()
+	}
+}
+
+type memoKey struct{}
+
+func memoFromContext(ctx context.Context) *memoCache {
+	memo, _ := ctx.Value(memoKey{}).(*memoCache)
+	return memo
+}
+
+func withMemo(ctx context.Context, memo *memoCache) context.Context {
+	return context.WithValue(ctx, memoKey{}, memo)
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPureResolver(t *testing.T) {
+	t.Parallel()
+
+	var calls, failures int32
+	type echoArgs struct {
+		Value string
+	}
+	r := New("", nil, Limits{}, nil)
+	require.NoError(t, r.Add(StaticSchema(StaticSchemaParams{
+		Name: "test",
+		Schema: `
+		type Query {
+			pure(value: String!): String!
+			impure(value: String!): String!
+			failing: String
+		}
+		`,
+		Resolvers: Resolvers{
+			"Query": ObjectResolver{
+				"pure": ToPureResolver(func(ctx *Context, parent any, args echoArgs) (string, error) {
+					atomic.AddInt32(&calls, 1)
+					return args.Value, nil
+				}),
+				"impure": ToResolver(func(ctx *Context, parent any, args echoArgs) (string, error) {
+					atomic.AddInt32(&calls, 1)
+					return args.Value, nil
+				}),
+				"failing": ToPureResolver(func(ctx *Context, parent any, args any) (any, error) {
+					atomic.AddInt32(&failures, 1)
+					return nil, errors.New("nope")
+				}),
+			},
+		},
+	})))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		var res struct{ Pure string }
+		_, err := r.Do(ctx, `{pure(value: "a")}`, "", nil, &res)
+		require.NoError(t, err)
+		require.Equal(t, "a", res.Pure)
+	}
+	require.EqualValues(t, 1, calls)
+
+	// memoised by args
+	_, err := r.Do(ctx, `{pure(value: "b")}`, "", nil, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, calls)
+
+	for i := 0; i < 3; i++ {
+		_, err := r.Do(ctx, `{impure(value: "a")}`, "", nil, nil)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 5, calls)
+
+	for i := 0; i < 2; i++ {
+		_, err := r.Do(ctx, `{failing}`, "", nil, nil)
+		require.Error(t, err)
+	}
+	require.EqualValues(t, 2, failures)
+}
+
+func TestMemoCacheBounds(t *testing.T) {
+	t.Parallel()
+
+	// each value is 12 bytes of JSON
+	memo := newMemoCache(10, 30)
+	memo.add("a", strings.Repeat("a", 10))
+	memo.add("b", strings.Repeat("b", 10))
+	memo.add("c", strings.Repeat("c", 10))
+
+	_, ok := memo.get("a")
+	require.False(t, ok, "oldest entry should have been evicted")
+	v, ok := memo.get("c")
+	require.True(t, ok)
+	require.Equal(t, strings.Repeat("c", 10), v)
+
+	// larger than the whole cache
+	memo.add("d", strings.Repeat("d", 100))
+	_, ok = memo.get("d")
+	require.False(t, ok)
+	_, ok = memo.get("b")
+	require.True(t, ok)
+}
+
+func TestMemoCacheReplace(t *testing.T) {
+	t.Parallel()
+
+	// each value is 12 bytes of JSON
+	memo := newMemoCache(10, 30)
+	memo.add("a", strings.Repeat("a", 10))
+	memo.add("a", strings.Repeat("b", 10))
+	require.Equal(t, 12, memo.bytes)
+
+	v, ok := memo.get("a")
+	require.True(t, ok)
+	require.Equal(t, strings.Repeat("b", 10), v)
+
+	// would never fit if the replaced size were still counted
+	memo.add("b", strings.Repeat("b", 10))
+	memo.add("c", strings.Repeat("c", 10))
+	_, ok = memo.get("b")
+	require.True(t, ok)
+	require.Equal(t, 24, memo.bytes)
+}
+
+type volatileParent struct {
+	Volatile_ bool `json:"volatile"`
+}
+
+func (p volatileParent) Volatile() bool {
+	return p.Volatile_
+}
+
+func TestPureResolverVolatile(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	r := New("", nil, Limits{}, nil)
+	require.NoError(t, r.Add(StaticSchema(StaticSchemaParams{
+		Name: "test",
+		Schema: `
+		type Query {
+			parent(volatile: Boolean!): Parent!
+		}
+
+		type Parent {
+			contents: String!
+		}
+		`,
+		Resolvers: Resolvers{
+			"Query": ObjectResolver{
+				"parent": ToResolver(func(ctx *Context, parent any, args struct{ Volatile bool }) (volatileParent, error) {
+					return volatileParent{Volatile_: args.Volatile}, nil
+				}),
+			},
+			"Parent": ObjectResolver{
+				"contents": ToPureResolver(func(ctx *Context, parent volatileParent, args any) (string, error) {
+					atomic.AddInt32(&calls, 1)
+					return "hi", nil
+				}),
+			},
+		},
+	})))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := r.Do(ctx, `{parent(volatile: true) { contents }}`, "", nil, nil)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 2, calls)
+
+	for i := 0; i < 2; i++ {
+		_, err := r.Do(ctx, `{parent(volatile: false) { contents }}`, "", nil, nil)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 3, calls)
+}
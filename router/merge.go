@@ -52,6 +52,20 @@ func MergeExecutableSchemas(name string, schemas ...ExecutableSchema) (Executabl
 					}
 				}
 				merged.Resolvers[name] = resolver
+			case SubscriptionResolver:
+				if existing, ok := merged.Resolvers[name]; ok {
+					existing, ok := existing.(SubscriptionResolver)
+					if !ok {
+						return nil, fmt.Errorf("conflict on type %q: %w", name, ErrMergeTypeConflict)
+					}
+					for fieldName, fn := range existing {
+						if _, ok := resolver[fieldName]; ok {
+							return nil, fmt.Errorf("conflict on type %q: %q: %w", name, fieldName, ErrMergeFieldConflict)
+						}
+						resolver[fieldName] = fn
+					}
+				}
+				merged.Resolvers[name] = resolver
 			case ScalarResolver:
 				if existing, ok := merged.Resolvers[name]; ok {
 					if _, ok := existing.(ScalarResolver); !ok {
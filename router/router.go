@@ -22,8 +22,28 @@ type Router struct {
 	resolvers    Resolvers
 	sessionToken string
 
+	// tokens are the additional tokens accepted by the router, which are
+	// restricted to their scope
+	tokens map[string]Scope
+
 	recorder *progrock.Recorder
 
+	limits Limits
+
+	// deprecations counts the deprecated fields resolved in the session
+	deprecations *deprecationReport
+
+	// memo holds the results of pure fields, see ToPureResolver
+	memo *memoCache
+
+	// ids holds the IDs returned in the session, which scoped tokens may
+	// pass back
+	ids *issuedIDs
+
+	// persistedQueries outlives the handler, which is replaced whenever a
+	// schema is added
+	persistedQueries *handler.PersistedQueryCache
+
 	s *graphql.Schema
 	// mergedSchemaString is the merged schemas in SDL format, useful
 	// for projects who need their dynamic schemas validated against
@@ -33,11 +53,34 @@ type Router struct {
 	l                  sync.RWMutex
 }
 
-func New(sessionToken string, recorder *progrock.Recorder) *Router {
+// persistedQueryCacheSize is how many persisted queries are kept per session.
+const persistedQueryCacheSize = 1000
+
+// Limits bounds the queries a router executes, whether they're served over
+// HTTP or executed directly with Do. Queries exceeding them fail with an
+// error whose extensions have a code, e.g. QUERY_TOO_DEEP.
+type Limits = handler.Limits
+
+// New returns a router accepting requests authenticated with the session
+// token, which has full access, or one of the scoped tokens. Requests aren't
+// authenticated if neither is set.
+func New(sessionToken string, recorder *progrock.Recorder, limits Limits, tokens map[string]Scope) *Router {
+	persistedQueries, err := handler.NewPersistedQueryCache(persistedQueryCacheSize)
+	if err != nil {
+		// only fails on a non-positive size
+		panic(err)
+	}
+
 	r := &Router{
-		schemas:      make(map[string]ExecutableSchema),
-		sessionToken: sessionToken,
-		recorder:     recorder,
+		schemas:          make(map[string]ExecutableSchema),
+		sessionToken:     sessionToken,
+		tokens:           tokens,
+		recorder:         recorder,
+		limits:           limits,
+		deprecations:     newDeprecationReport(),
+		memo:             newMemoCache(memoMaxEntries, memoMaxBytes),
+		ids:              newIssuedIDs(),
+		persistedQueries: persistedQueries,
 	}
 
 	return r
@@ -50,13 +93,13 @@ func (r *Router) Do(ctx context.Context, query string, opName string, variables
 	r.l.RUnlock()
 
 	params := graphql.Params{
-		Context:        ctx,
+		Context:        withIssuedIDs(withMemo(ctx, r.memo), r.ids),
 		Schema:         schema,
 		RequestString:  query,
 		VariableValues: variables,
 		OperationName:  opName,
 	}
-	result := graphql.Do(params)
+	result := r.limits.Do(params)
 	if result.HasErrors() {
 		messages := []string{}
 		for _, e := range result.Errors {
@@ -97,7 +140,7 @@ func (r *Router) Add(schema ExecutableSchema) error {
 		return err
 	}
 
-	s, err := compile(merged)
+	s, err := compile(merged, r.deprecations)
 	if err != nil {
 		return err
 	}
@@ -107,7 +150,9 @@ func (r *Router) Add(schema ExecutableSchema) error {
 	r.resolvers = merged.Resolvers()
 	r.mergedSchemaString = merged.Schema()
 	r.h = handler.New(&handler.Config{
-		Schema: s,
+		Schema:           s,
+		PersistedQueries: r.persistedQueries,
+		Limits:           r.limits,
 	})
 	return nil
 }
@@ -141,6 +186,12 @@ func (r *Router) Resolvers() Resolvers {
 	return r.resolvers
 }
 
+// Deprecations returns the deprecated fields resolved so far in the session,
+// sorted by field.
+func (r *Router) Deprecations() []DeprecatedField {
+	return r.deprecations.list()
+}
+
 func (r *Router) MergedSchemas() string {
 	r.l.RLock()
 	defer r.l.RUnlock()
@@ -155,13 +206,17 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	w.Header().Add("x-dagger-engine", engine.Version)
 
-	if r.sessionToken != "" {
+	if r.sessionToken != "" || len(r.tokens) > 0 {
 		username, _, ok := req.BasicAuth()
-		if !ok || username != r.sessionToken {
+		scope, scoped := r.tokens[username]
+		if !ok || username == "" || (username != r.sessionToken && !scoped) {
 			w.Header().Set("WWW-Authenticate", `Basic realm="Access to the Dagger engine session"`)
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		if username != r.sessionToken {
+			req = req.WithContext(withScope(req.Context(), &scope))
+		}
 	}
 
 	defer func() {
@@ -191,7 +246,10 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
-	req = req.WithContext(progrock.RecorderToContext(req.Context(), r.recorder))
+	ctx := progrock.RecorderToContext(req.Context(), r.recorder)
+	ctx = withMemo(ctx, r.memo)
+	ctx = withIssuedIDs(ctx, r.ids)
+	req = req.WithContext(ctx)
 
 	mux := http.NewServeMux()
 	mux.Handle("/query", h)
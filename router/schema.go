@@ -49,6 +49,13 @@ func (r ObjectResolver) SetField(name string, fn graphql.FieldResolveFn) {
 	r[name] = fn
 }
 
+// SubscriptionResolver resolves the fields of the Subscription type. Each
+// field returns a channel, and every value received from it is sent to the
+// subscriber as the value of the field, see ToSubscriber.
+type SubscriptionResolver map[string]graphql.FieldResolveFn
+
+func (SubscriptionResolver) _resolver() {}
+
 type IDableObjectResolver interface {
 	FromID(id string) (any, error)
 	Resolver
@@ -133,9 +140,29 @@ type Digestible interface {
 	Digest() (digest.Digest, error)
 }
 
+// Volatile is any object whose contents may change without it changing, e.g.
+// a directory read from the host. The results of its pure fields aren't
+// memoised.
+type Volatile interface {
+	Volatile() bool
+}
+
 // ToResolver transforms any function f with a *Context, a parent P and some args A that returns a Response R and an error
 // into a graphql resolver graphql.FieldResolveFn.
 func ToResolver[P any, A any, R any](f func(*Context, P, A) (R, error)) graphql.FieldResolveFn {
+	return toResolver(f, false)
+}
+
+// ToPureResolver is like ToResolver, for fields whose result only depends on
+// their parent and args, e.g. the contents of a file. Their results are
+// memoised for the rest of the session, keyed by the digest of the query, so
+// that evaluating the same field again doesn't solve or read anything.
+// Errors and the fields of Volatile parents aren't memoised.
+func ToPureResolver[P any, A any, R any](f func(*Context, P, A) (R, error)) graphql.FieldResolveFn {
+	return toResolver(f, true)
+}
+
+func toResolver[P any, A any, R any](f func(*Context, P, A) (R, error), pure bool) graphql.FieldResolveFn {
 	return func(p graphql.ResolveParams) (any, error) {
 		recorder := progrock.RecorderFromContext(p.Context)
 
@@ -164,11 +191,31 @@ func ToResolver[P any, A any, R any](f func(*Context, P, A) (R, error)) graphql.
 			p.Context = progrock.RecorderToContext(p.Context, recorder)
 		}
 
-		vtx, err := queryVertex(recorder, p.Info.FieldName, p.Source, args)
+		dig, err := queryDigest(p.Info.FieldName, p.Source, args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute query digest: %w", err)
+		}
+
+		vtx, err := queryVertex(recorder, dig, p.Info.FieldName, p.Source, args)
 		if err != nil {
 			return nil, err
 		}
 
+		var memo *memoCache
+		if pure {
+			memo = memoFromContext(p.Context)
+			if volatile, ok := p.Source.(Volatile); ok && volatile.Volatile() {
+				memo = nil
+			}
+		}
+		if memo != nil {
+			if res, ok := memo.get(dig); ok {
+				vtx.Cached()
+				vtx.Done(nil)
+				return res, nil
+			}
+		}
+
 		ctx := Context{
 			Context:       p.Context,
 			ResolveParams: p,
@@ -190,12 +237,70 @@ func ToResolver[P any, A any, R any](f func(*Context, P, A) (R, error)) graphql.
 			vtx.Output(dg)
 		}
 
+		if memo != nil {
+			memo.add(dig, res)
+		}
+
 		vtx.Done(nil)
 
 		return res, nil
 	}
 }
 
+// ToSubscriber transforms any function f with a *Context, a parent P and some
+// args A that returns a channel of events R into the subscribe function of a
+// SubscriptionResolver field. The subscription ends once the channel is
+// closed.
+//
+// Unlike ToResolver, no vertex is recorded for the subscription, since it
+// would stay running until the subscriber goes away.
+func ToSubscriber[P any, A any, R any](f func(*Context, P, A) (<-chan R, error)) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		var args A
+		argBytes, err := json.Marshal(p.Args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal args: %w", err)
+		}
+		if err := json.Unmarshal(argBytes, &args); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal args: %w", err)
+		}
+
+		parent, _ := p.Source.(P)
+
+		ctx := Context{
+			Context:       p.Context,
+			ResolveParams: p,
+		}
+
+		events, err := f(&ctx, parent, args)
+		if err != nil {
+			return nil, err
+		}
+
+		// graphql only accepts exactly a chan interface{}
+		out := make(chan any)
+		go func() {
+			defer close(out)
+			for {
+				select {
+				case <-p.Context.Done():
+					return
+				case ev, ok := <-events:
+					if !ok {
+						return
+					}
+					select {
+					case out <- ev:
+					case <-p.Context.Done():
+						return
+					}
+				}
+			}
+		}()
+		return out, nil
+	}
+}
+
 func PassthroughResolver(p graphql.ResolveParams) (any, error) {
 	return ToResolver(func(ctx *Context, parent any, args any) (any, error) {
 		if parent == nil {
@@ -211,12 +316,7 @@ func ErrResolver(err error) graphql.FieldResolveFn {
 	})
 }
 
-func queryVertex(recorder *progrock.Recorder, fieldName string, parent, args any) (*progrock.VertexRecorder, error) {
-	dig, err := queryDigest(fieldName, parent, args)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compute query digest: %w", err)
-	}
-
+func queryVertex(recorder *progrock.Recorder, dig digest.Digest, fieldName string, parent, args any) (*progrock.VertexRecorder, error) {
 	var inputs []digest.Digest
 
 	// Ensure we use any custom serialization defined on the args type when displaying this.
@@ -0,0 +1,302 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/dagger/graphql"
+	"github.com/opencontainers/go-digest"
+)
+
+// Scope restricts the API available to a token. The zero value allows
+// everything.
+type Scope struct {
+	// Fields lists the fields the token may resolve, as "Type.field", or
+	// "Type.*" for every field of a type. Every field is allowed if empty.
+	//
+	// Only fields with resolvers are checked: plain data fields, e.g. the
+	// name of an EnvVariable, are always allowed.
+	Fields []string `json:"fields,omitempty"`
+
+	// DeniedArgs lists the arguments the token may not set, as
+	// "Type.field.arg", e.g. "Container.withExec.insecureRootCapabilities".
+	// Setting them to their zero value, e.g. false, is allowed.
+	DeniedArgs []string `json:"deniedArgs,omitempty"`
+
+	// ArgPrefixes restricts string arguments, as "Type.field.arg", to
+	// values starting with one of the given prefixes, e.g.
+	// "Container.publish.address" to ["registry.example.com/team/"].
+	ArgPrefixes map[string][]string `json:"argPrefixes,omitempty"`
+}
+
+// ForbiddenError is returned when a token resolves a field outside of its
+// scope.
+type ForbiddenError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("%s is forbidden: %s", e.Field, e.Reason)
+}
+
+func (e *ForbiddenError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"_type": "FORBIDDEN",
+		"field": e.Field,
+	}
+}
+
+// Check returns an error if the scope doesn't allow resolving the field with
+// the given arguments.
+func (s *Scope) Check(typeName, fieldName string, args map[string]any) error {
+	field := typeName + "." + fieldName
+
+	if len(s.Fields) > 0 {
+		allowed := false
+		for _, f := range s.Fields {
+			if f == field || f == typeName+".*" {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &ForbiddenError{Field: field, Reason: "not in the token's scope"}
+		}
+	}
+
+	for _, denied := range s.DeniedArgs {
+		argName, ok := strings.CutPrefix(denied, field+".")
+		if !ok {
+			continue
+		}
+		if val, ok := args[argName]; ok && !isZero(val) {
+			return &ForbiddenError{Field: field, Reason: fmt.Sprintf("argument %q is not allowed", argName)}
+		}
+	}
+
+	for arg, prefixes := range s.ArgPrefixes {
+		argName, ok := strings.CutPrefix(arg, field+".")
+		if !ok {
+			continue
+		}
+		val, ok := args[argName]
+		if !ok || val == nil {
+			continue
+		}
+		str, ok := val.(string)
+		if !ok {
+			return &ForbiddenError{Field: field, Reason: fmt.Sprintf("argument %q must be a string", argName)}
+		}
+		allowed := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(str, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &ForbiddenError{
+				Field:  field,
+				Reason: fmt.Sprintf("argument %q must start with one of %s", argName, strings.Join(prefixes, ", ")),
+			}
+		}
+	}
+
+	return nil
+}
+
+func isZero(val any) bool {
+	if val == nil {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+type scopeKey struct{}
+
+// scopeFromContext returns the scope of the token the request was made
+// with, or nil if it's unrestricted.
+func scopeFromContext(ctx context.Context) *Scope {
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+func withScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// issuedIDs holds the digests of the IDs returned by the router in a
+// session. IDs aren't signed and embed everything needed to rebuild an
+// object, e.g. a container's execs, so scoped tokens may only pass IDs the
+// router issued rather than ones they made up, which would get around their
+// scope.
+type issuedIDs struct {
+	ids map[digest.Digest]struct{}
+	l   sync.RWMutex
+}
+
+func newIssuedIDs() *issuedIDs {
+	return &issuedIDs{ids: map[digest.Digest]struct{}{}}
+}
+
+func (s *issuedIDs) add(id string) {
+	s.l.Lock()
+	s.ids[digest.FromString(id)] = struct{}{}
+	s.l.Unlock()
+}
+
+func (s *issuedIDs) has(id string) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	_, ok := s.ids[digest.FromString(id)]
+	return ok
+}
+
+type issuedIDsKey struct{}
+
+func issuedIDsFromContext(ctx context.Context) *issuedIDs {
+	ids, _ := ctx.Value(issuedIDsKey{}).(*issuedIDs)
+	return ids
+}
+
+func withIssuedIDs(ctx context.Context, ids *issuedIDs) context.Context {
+	return context.WithValue(ctx, issuedIDsKey{}, ids)
+}
+
+// isIDType returns whether t is an ID scalar, e.g. ContainerID.
+func isIDType(t graphql.Type) bool {
+	scalar, ok := unwrapType(t).(*graphql.Scalar)
+	return ok && strings.HasSuffix(scalar.Name(), "ID")
+}
+
+func unwrapType(t graphql.Type) graphql.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
+
+// eachID calls fn with every ID in a value of type t, which may be a list or
+// an input object containing IDs.
+func eachID(t graphql.Type, val any, fn func(string) error) error {
+	if val == nil {
+		return nil
+	}
+	if list, ok := val.([]any); ok {
+		for _, v := range list {
+			if err := eachID(t, v, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch t := unwrapType(t).(type) {
+	case *graphql.Scalar:
+		if !isIDType(t) {
+			return nil
+		}
+		// parsed into their Go type, e.g. core.ContainerID
+		v := reflect.ValueOf(val)
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("unexpected %s value type %T", t.Name(), val)
+		}
+		return fn(v.String())
+	case *graphql.InputObject:
+		obj, ok := val.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for name, field := range t.Fields() {
+			if err := eachID(field.Type, obj[name], fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkIssuedIDs returns an error if any of the ID arguments of the field
+// wasn't issued by the router.
+func checkIssuedIDs(p graphql.ResolveParams, field string) error {
+	parent, ok := p.Info.ParentType.(*graphql.Object)
+	if !ok {
+		return nil
+	}
+	def, ok := parent.Fields()[p.Info.FieldName]
+	if !ok {
+		return nil
+	}
+	issued := issuedIDsFromContext(p.Context)
+	for _, arg := range def.Args {
+		err := eachID(arg.Type, p.Args[arg.Name()], func(id string) error {
+			if issued == nil || !issued.has(id) {
+				return &ForbiddenError{
+					Field:  field,
+					Reason: fmt.Sprintf("argument %q is not an ID issued by this session", arg.Name()),
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordIssuedIDs records the IDs returned by a field, so that scoped tokens
+// may pass them back.
+func recordIssuedIDs(p graphql.ResolveParams, res any) {
+	issued := issuedIDsFromContext(p.Context)
+	if issued == nil || !isIDType(p.Info.ReturnType) {
+		return
+	}
+	v := reflect.ValueOf(res)
+	switch v.Kind() {
+	case reflect.String:
+		issued.add(v.String())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if e := reflect.Indirect(v.Index(i)); e.Kind() == reflect.String {
+				issued.add(e.String())
+			}
+		}
+	}
+}
+
+// scoped wraps a field's resolver to check the field against the scope of
+// the request's token before resolving it. Scoped tokens may only pass IDs
+// which the router returned earlier in the session.
+func scoped(typeName, fieldName string, fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		if scope := scopeFromContext(p.Context); scope != nil {
+			if err := scope.Check(typeName, fieldName, p.Args); err != nil {
+				return nil, err
+			}
+			if err := checkIssuedIDs(p, typeName+"."+fieldName); err != nil {
+				return nil, err
+			}
+		}
+		res, err := fn(p)
+		if err == nil {
+			recordIssuedIDs(p, res)
+		}
+		return res, err
+	}
+}
@@ -0,0 +1,190 @@
+package router
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dagger/graphql"
+	"github.com/dagger/graphql/language/ast"
+	"github.com/stretchr/testify/require"
+	"github.com/vito/progrock"
+)
+
+func TestScopeCheck(t *testing.T) {
+	t.Parallel()
+
+	scope := &Scope{
+		Fields:     []string{"Query.container", "Container.*"},
+		DeniedArgs: []string{"Container.withExec.insecureRootCapabilities"},
+		ArgPrefixes: map[string][]string{
+			"Container.publish.address": {"registry.example.com/team/"},
+		},
+	}
+
+	require.NoError(t, scope.Check("Query", "container", nil))
+	require.NoError(t, scope.Check("Container", "from", map[string]any{"address": "alpine"}))
+	require.ErrorAs(t, scope.Check("Query", "host", nil), new(*ForbiddenError))
+
+	require.NoError(t, scope.Check("Container", "withExec", map[string]any{"insecureRootCapabilities": false}))
+	require.ErrorAs(t, scope.Check("Container", "withExec", map[string]any{"insecureRootCapabilities": true}), new(*ForbiddenError))
+
+	require.NoError(t, scope.Check("Container", "publish", map[string]any{"address": "registry.example.com/team/app"}))
+	require.ErrorAs(t, scope.Check("Container", "publish", map[string]any{"address": "docker.io/app"}), new(*ForbiddenError))
+
+	require.NoError(t, (&Scope{}).Check("Query", "host", nil))
+}
+
+func TestRouterTokens(t *testing.T) {
+	t.Parallel()
+
+	r := New("session", nil, Limits{}, map[string]Scope{
+		"scoped": {Fields: []string{"Query.allowed"}},
+	})
+	require.NoError(t, r.Add(StaticSchema(StaticSchemaParams{
+		Name: "test",
+		Schema: `
+		type Query {
+			allowed: String
+			denied: String
+		}
+		`,
+		Resolvers: Resolvers{
+			"Query": ObjectResolver{
+				"allowed": func(p graphql.ResolveParams) (any, error) { return "yes", nil },
+				"denied":  func(p graphql.ResolveParams) (any, error) { return "yes", nil },
+			},
+		},
+	})))
+
+	query := func(token string) (int, *graphql.Result) {
+		req := httptest.NewRequest("GET", "/query?query="+url.QueryEscape("{allowed denied}"), nil)
+		if token != "" {
+			req.SetBasicAuth(token, "")
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		if resp.Code != http.StatusOK {
+			return resp.Code, nil
+		}
+		var result graphql.Result
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		return resp.Code, &result
+	}
+
+	code, _ := query("")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = query("unknown")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	_, result := query("session")
+	require.Empty(t, result.Errors)
+	require.Equal(t, map[string]any{"allowed": "yes", "denied": "yes"}, result.Data)
+
+	_, result = query("scoped")
+	require.Len(t, result.Errors, 1)
+	require.Equal(t, "FORBIDDEN", result.Errors[0].Extensions["_type"])
+	require.Equal(t, map[string]any{"allowed": "yes", "denied": nil}, result.Data)
+}
+
+func TestRouterScopedForgedID(t *testing.T) {
+	t.Parallel()
+
+	type container struct {
+		Args     []string `json:"args"`
+		Insecure bool     `json:"insecure"`
+	}
+	encode := func(ctr container) string {
+		payload, err := json.Marshal(ctr)
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(payload)
+	}
+
+	r := New("session", progrock.NewRecorder(progrock.Discard{}), Limits{}, map[string]Scope{
+		"scoped": {
+			Fields:     []string{"Query.container", "Container.*"},
+			DeniedArgs: []string{"Container.withExec.insecure"},
+		},
+	})
+	require.NoError(t, r.Add(StaticSchema(StaticSchemaParams{
+		Name: "test",
+		Schema: `
+		scalar ContainerID
+
+		type Query {
+			container(id: ContainerID): Container!
+		}
+
+		type Container {
+			id: ContainerID!
+			withExec(args: [String!]!, insecure: Boolean): Container!
+			insecure: Boolean!
+		}
+		`,
+		Resolvers: Resolvers{
+			"ContainerID": ScalarResolver{
+				Serialize:  func(value any) any { return value },
+				ParseValue: func(value any) any { return value },
+				ParseLiteral: func(valueAST ast.Value) any {
+					return valueAST.GetValue()
+				},
+			},
+			"Query": ObjectResolver{
+				"container": ToResolver(func(ctx *Context, parent any, args struct{ ID string }) (container, error) {
+					var ctr container
+					if args.ID == "" {
+						return ctr, nil
+					}
+					payload, err := base64.StdEncoding.DecodeString(args.ID)
+					if err != nil {
+						return ctr, err
+					}
+					return ctr, json.Unmarshal(payload, &ctr)
+				}),
+			},
+			"Container": ObjectResolver{
+				"id": ToResolver(func(ctx *Context, parent container, args any) (string, error) {
+					return encode(parent), nil
+				}),
+				"withExec": ToResolver(func(ctx *Context, parent container, args container) (container, error) {
+					return args, nil
+				}),
+				"insecure": ToResolver(func(ctx *Context, parent container, args any) (bool, error) {
+					return parent.Insecure, nil
+				}),
+			},
+		},
+	})))
+
+	query := func(token, q string) *graphql.Result {
+		req := httptest.NewRequest("GET", "/query?query="+url.QueryEscape(q), nil)
+		req.SetBasicAuth(token, "")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+		var result graphql.Result
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		return &result
+	}
+
+	// IDs issued by the router can be passed back
+	result := query("scoped", `{container{withExec(args:["echo"]){id}}}`)
+	require.Empty(t, result.Errors)
+	id := result.Data.(map[string]any)["container"].(map[string]any)["withExec"].(map[string]any)["id"].(string)
+	result = query("scoped", `{container(id: "`+id+`"){insecure}}`)
+	require.Empty(t, result.Errors)
+
+	// a made up ID would get around the denied argument
+	forged := encode(container{Args: []string{"echo"}, Insecure: true})
+	result = query("scoped", `{container(id: "`+forged+`"){insecure}}`)
+	require.Len(t, result.Errors, 1)
+	require.Equal(t, "FORBIDDEN", result.Errors[0].Extensions["_type"])
+
+	// the session token isn't restricted
+	result = query("session", `{container(id: "`+forged+`"){insecure}}`)
+	require.Empty(t, result.Errors)
+	require.Equal(t, true, result.Data.(map[string]any)["container"].(map[string]any)["insecure"])
+}
@@ -47,6 +47,15 @@ type BuildArg struct {
 	Value string `json:"value"`
 }
 
+// A header to send with an HTTP request.
+type HTTPHeader struct {
+	// The header name.
+	Name string `json:"name"`
+
+	// The header value.
+	Value string `json:"value"`
+}
+
 // Key value object that represents a Pipeline label.
 type PipelineLabel struct {
 	// Label name.
@@ -61,7 +70,24 @@ type CacheVolume struct {
 	q *querybuilder.Selection
 	c graphql.Client
 
-	id *CacheID
+	checksum *string
+	id       *CacheID
+	lastUsed *int
+	prune    *CacheID
+	size     *int
+}
+
+// A checksum of the cache volume's keys, which identifies it in the engine.
+func (r *CacheVolume) Checksum(ctx context.Context) (string, error) {
+	if r.checksum != nil {
+		return *r.checksum, nil
+	}
+	q := r.q.Select("checksum")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
 }
 
 func (r *CacheVolume) ID(ctx context.Context) (CacheID, error) {
@@ -95,6 +121,134 @@ func (r *CacheVolume) XXX_GraphQLID(ctx context.Context) (string, error) {
 	return string(id), nil
 }
 
+// When the cache volume was last used, as a Unix timestamp.
+func (r *CacheVolume) LastUsed(ctx context.Context) (int, error) {
+	if r.lastUsed != nil {
+		return *r.lastUsed, nil
+	}
+	q := r.q.Select("lastUsed")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Deletes the cache volume's contents from the engine.
+//
+// Fails if the cache volume is mounted by a running container.
+func (r *CacheVolume) Prune(ctx context.Context) (*CacheVolume, error) {
+	q := r.q.Select("prune")
+
+	return r, q.Execute(ctx, r.c)
+}
+
+// The size of the cache volume's contents in bytes, or 0 if it doesn't exist yet.
+func (r *CacheVolume) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.q.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Retrieves a directory with the cache volume's current contents.
+//
+// The contents are copied each time the directory is evaluated, so they may
+// change between uses.
+func (r *CacheVolume) Snapshot() *Directory {
+	q := r.q.Select("snapshot")
+
+	return &Directory{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Retrieves this cache volume starting from the contents of a directory.
+//
+// Like mounting the cache volume with the directory as its source, the
+// contents are copied when the cache volume is mounted without a source of its
+// own. Each different directory gets a separate volume for the same key,
+// seeded with that directory's contents; mounting the cache volume again with
+// the same directory keeps the changes made to it since.
+func (r *CacheVolume) WithContents(source *Directory) *CacheVolume {
+	q := r.q.Select("withContents")
+	q = q.Arg("source", source)
+
+	return &CacheVolume{
+		q: q,
+		c: r.c,
+	}
+}
+
+// A change made to a path in a filesystem tree.
+type Change struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	kind    *ChangeKind
+	newSize *int
+	oldSize *int
+	path    *string
+}
+
+// The kind of change.
+func (r *Change) Kind(ctx context.Context) (ChangeKind, error) {
+	if r.kind != nil {
+		return *r.kind, nil
+	}
+	q := r.q.Select("kind")
+
+	var response ChangeKind
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Size of the file after the change, if it is a regular file.
+func (r *Change) NewSize(ctx context.Context) (int, error) {
+	if r.newSize != nil {
+		return *r.newSize, nil
+	}
+	q := r.q.Select("newSize")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Size of the file before the change, if it was a regular file.
+func (r *Change) OldSize(ctx context.Context) (int, error) {
+	if r.oldSize != nil {
+		return *r.oldSize, nil
+	}
+	q := r.q.Select("oldSize")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Location of the path that changed, relative to the root of the tree (e.g., "src/main.go").
+func (r *Change) Path(ctx context.Context) (string, error) {
+	if r.path != nil {
+		return *r.path, nil
+	}
+	q := r.q.Select("path")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // An OCI-compatible container, also known as a docker container.
 type Container struct {
 	q *querybuilder.Selection
@@ -139,6 +293,9 @@ type ContainerBuildOpts struct {
 }
 
 // Initializes this container from a Dockerfile build.
+//
+// Paths matched by a .dockerignore file in the context directory are excluded
+// from the build context, as with `docker build`.
 func (r *Container) Build(context *Directory, opts ...ContainerBuildOpts) *Container {
 	q := r.q.Select("build")
 	for i := len(opts) - 1; i >= 0; i-- {
@@ -1018,6 +1175,8 @@ type ContainerWithMountedCacheOpts struct {
 	//
 	// If the group is omitted, it defaults to the same as the user.
 	Owner string
+	// Mount as read-only, so that changes can't be made to it.
+	ReadOnly bool
 }
 
 // Retrieves this container plus a cache volume mounted at the given path.
@@ -1036,6 +1195,10 @@ func (r *Container) WithMountedCache(path string, cache *CacheVolume, opts ...Co
 		if !querybuilder.IsZeroValue(opts[i].Owner) {
 			q = q.Arg("owner", opts[i].Owner)
 		}
+		// `readOnly` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ReadOnly) {
+			q = q.Arg("readOnly", opts[i].ReadOnly)
+		}
 	}
 	q = q.Arg("path", path)
 	q = q.Arg("cache", cache)
@@ -1054,6 +1217,8 @@ type ContainerWithMountedDirectoryOpts struct {
 	//
 	// If the group is omitted, it defaults to the same as the user.
 	Owner string
+	// Mount as read-only, so that changes can't be made to it.
+	ReadOnly bool
 }
 
 // Retrieves this container plus a directory mounted at the given path.
@@ -1064,6 +1229,10 @@ func (r *Container) WithMountedDirectory(path string, source *Directory, opts ..
 		if !querybuilder.IsZeroValue(opts[i].Owner) {
 			q = q.Arg("owner", opts[i].Owner)
 		}
+		// `readOnly` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ReadOnly) {
+			q = q.Arg("readOnly", opts[i].ReadOnly)
+		}
 	}
 	q = q.Arg("path", path)
 	q = q.Arg("source", source)
@@ -1082,6 +1251,8 @@ type ContainerWithMountedFileOpts struct {
 	//
 	// If the group is omitted, it defaults to the same as the user.
 	Owner string
+	// Mount as read-only, so that changes can't be made to it.
+	ReadOnly bool
 }
 
 // Retrieves this container plus a file mounted at the given path.
@@ -1092,6 +1263,10 @@ func (r *Container) WithMountedFile(path string, source *File, opts ...Container
 		if !querybuilder.IsZeroValue(opts[i].Owner) {
 			q = q.Arg("owner", opts[i].Owner)
 		}
+		// `readOnly` optional argument
+		if !querybuilder.IsZeroValue(opts[i].ReadOnly) {
+			q = q.Arg("readOnly", opts[i].ReadOnly)
+		}
 	}
 	q = q.Arg("path", path)
 	q = q.Arg("source", source)
@@ -1130,9 +1305,21 @@ func (r *Container) WithMountedSecret(path string, source *Secret, opts ...Conta
 	}
 }
 
+// ContainerWithMountedTempOpts contains options for Container.WithMountedTemp
+type ContainerWithMountedTempOpts struct {
+	// Maximum size of the temporary directory in bytes. Unlimited by default.
+	Size int
+}
+
 // Retrieves this container plus a temporary directory mounted at the given path.
-func (r *Container) WithMountedTemp(path string) *Container {
+func (r *Container) WithMountedTemp(path string, opts ...ContainerWithMountedTempOpts) *Container {
 	q := r.q.Select("withMountedTemp")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `size` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Size) {
+			q = q.Arg("size", opts[i].Size)
+		}
+	}
 	q = q.Arg("path", path)
 
 	return &Container{
@@ -1396,6 +1583,55 @@ func (r *Directory) With(f WithDirectoryFunc) *Directory {
 	return f(r)
 }
 
+// Retrieves a file containing a unified diff of the changes from this directory
+// to another directory, in the same format as `git diff --binary`, suitable for
+// `git apply`.
+func (r *Directory) AsPatch(other *Directory) *File {
+	q := r.q.Select("asPatch")
+	q = q.Arg("other", other)
+
+	return &File{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Lists the paths that were added, modified or deleted in another directory
+// compared to this one.
+func (r *Directory) Changes(ctx context.Context, other *Directory) ([]Change, error) {
+	q := r.q.Select("changes")
+	q = q.Arg("other", other)
+
+	q = q.Select("kind newSize oldSize path")
+
+	type changes struct {
+		Kind    ChangeKind
+		NewSize int
+		OldSize int
+		Path    string
+	}
+
+	convert := func(fields []changes) []Change {
+		out := []Change{}
+
+		for i := range fields {
+			out = append(out, Change{kind: &fields[i].Kind, newSize: &fields[i].NewSize, oldSize: &fields[i].OldSize, path: &fields[i].Path})
+		}
+
+		return out
+	}
+	var response []changes
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Gets the difference between this directory and an another directory.
 func (r *Directory) Diff(other *Directory) *Directory {
 	q := r.q.Select("diff")
@@ -1437,6 +1673,9 @@ type DirectoryDockerBuildOpts struct {
 }
 
 // Builds a new Docker container from this directory.
+//
+// Paths matched by a .dockerignore file in this directory are excluded from the
+// build context, as with `docker build`.
 func (r *Directory) DockerBuild(opts ...DirectoryDockerBuildOpts) *Container {
 	q := r.q.Select("dockerBuild")
 	for i := len(opts) - 1; i >= 0; i-- {
@@ -1490,12 +1729,25 @@ func (r *Directory) Entries(ctx context.Context, opts ...DirectoryEntriesOpts) (
 	return response, q.Execute(ctx, r.c)
 }
 
+// DirectoryExportOpts contains options for Directory.Export
+type DirectoryExportOpts struct {
+	// Remove any files at the destination which are not present in this directory,
+	// so that it mirrors this directory exactly.
+	Wipe bool
+}
+
 // Writes the contents of the directory to a path on the host.
-func (r *Directory) Export(ctx context.Context, path string) (bool, error) {
+func (r *Directory) Export(ctx context.Context, path string, opts ...DirectoryExportOpts) (bool, error) {
 	if r.export != nil {
 		return *r.export, nil
 	}
 	q := r.q.Select("export")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `wipe` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Wipe) {
+			q = q.Arg("wipe", opts[i].Wipe)
+		}
+	}
 	q = q.Arg("path", path)
 
 	var response bool
@@ -1504,6 +1756,55 @@ func (r *Directory) Export(ctx context.Context, path string) (bool, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// DirectoryExportDryRunOpts contains options for Directory.ExportDryRun
+type DirectoryExportDryRunOpts struct {
+	// Include files at the destination which are not present in this directory,
+	// as they would be removed by an export with wipe set.
+	Wipe bool
+}
+
+// Lists the changes that writing the contents of the directory to a path on the
+// host would make, without writing anything.
+func (r *Directory) ExportDryRun(ctx context.Context, path string, opts ...DirectoryExportDryRunOpts) ([]Change, error) {
+	q := r.q.Select("exportDryRun")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `wipe` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Wipe) {
+			q = q.Arg("wipe", opts[i].Wipe)
+		}
+	}
+	q = q.Arg("path", path)
+
+	q = q.Select("kind newSize oldSize path")
+
+	type exportDryRun struct {
+		Kind    ChangeKind
+		NewSize int
+		OldSize int
+		Path    string
+	}
+
+	convert := func(fields []exportDryRun) []Change {
+		out := []Change{}
+
+		for i := range fields {
+			out = append(out, Change{kind: &fields[i].Kind, newSize: &fields[i].NewSize, oldSize: &fields[i].OldSize, path: &fields[i].Path})
+		}
+
+		return out
+	}
+	var response []exportDryRun
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Retrieves a file at the given path.
 func (r *Directory) File(path string) *File {
 	q := r.q.Select("file")
@@ -1716,6 +2017,55 @@ func (r *Directory) WithoutFile(path string) *Directory {
 	}
 }
 
+// A record in the engine's build cache.
+type EngineCacheRecord struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	description *string
+	recordType  *string
+	size        *int
+}
+
+// A description of the record's contents.
+func (r *EngineCacheRecord) Description(ctx context.Context) (string, error) {
+	if r.description != nil {
+		return *r.description, nil
+	}
+	q := r.q.Select("description")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The type of the record, e.g. regular or exec.cachemount.
+func (r *EngineCacheRecord) RecordType(ctx context.Context) (string, error) {
+	if r.recordType != nil {
+		return *r.recordType, nil
+	}
+	q := r.q.Select("recordType")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The size of the record in bytes.
+func (r *EngineCacheRecord) Size(ctx context.Context) (int, error) {
+	if r.size != nil {
+		return *r.size, nil
+	}
+	q := r.q.Select("size")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A simple key value object that represents an environment variable.
 type EnvVariable struct {
 	q *querybuilder.Selection
@@ -1870,6 +2220,69 @@ func (r *File) WithTimestamps(timestamp int) *File {
 	}
 }
 
+// A commit in a git repository's history.
+type GitCommit struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	author    *string
+	message   *string
+	sha       *string
+	timestamp *int
+}
+
+// The author of the commit (e.g., "Jane Doe <jane@example.com>").
+func (r *GitCommit) Author(ctx context.Context) (string, error) {
+	if r.author != nil {
+		return *r.author, nil
+	}
+	q := r.q.Select("author")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The full commit message.
+func (r *GitCommit) Message(ctx context.Context) (string, error) {
+	if r.message != nil {
+		return *r.message, nil
+	}
+	q := r.q.Select("message")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The SHA of the commit.
+func (r *GitCommit) Sha(ctx context.Context) (string, error) {
+	if r.sha != nil {
+		return *r.sha, nil
+	}
+	q := r.q.Select("sha")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The time the commit was authored, in seconds since the Unix epoch.
+func (r *GitCommit) Timestamp(ctx context.Context) (int, error) {
+	if r.timestamp != nil {
+		return *r.timestamp, nil
+	}
+	q := r.q.Select("timestamp")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A git ref (tag, branch or commit).
 type GitRef struct {
 	q *querybuilder.Selection
@@ -1878,6 +2291,16 @@ type GitRef struct {
 	digest *string
 }
 
+// The commit this ref points to.
+func (r *GitRef) Commit() *GitCommit {
+	q := r.q.Select("commit")
+
+	return &GitCommit{
+		q: q,
+		c: r.c,
+	}
+}
+
 // The digest of the current value of this ref.
 func (r *GitRef) Digest(ctx context.Context) (string, error) {
 	if r.digest != nil {
@@ -1891,11 +2314,66 @@ func (r *GitRef) Digest(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx, r.c)
 }
 
+// GitRefLogOpts contains options for GitRef.Log
+type GitRefLogOpts struct {
+	// Maximum number of commits to list. Defaults to the entire history.
+	Limit int
+}
+
+// Lists the history of this ref, newest first.
+func (r *GitRef) Log(ctx context.Context, opts ...GitRefLogOpts) ([]GitCommit, error) {
+	q := r.q.Select("log")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `limit` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Limit) {
+			q = q.Arg("limit", opts[i].Limit)
+		}
+	}
+
+	q = q.Select("author message sha timestamp")
+
+	type log struct {
+		Author    string
+		Message   string
+		Sha       string
+		Timestamp int
+	}
+
+	convert := func(fields []log) []GitCommit {
+		out := []GitCommit{}
+
+		for i := range fields {
+			out = append(out, GitCommit{author: &fields[i].Author, message: &fields[i].Message, sha: &fields[i].Sha, timestamp: &fields[i].Timestamp})
+		}
+
+		return out
+	}
+	var response []log
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // GitRefTreeOpts contains options for GitRef.Tree
 type GitRefTreeOpts struct {
+	// Overrides the repository's sshKnownHosts.
 	SSHKnownHosts string
-
+	// Overrides the repository's sshAuthSocket.
 	SSHAuthSocket *Socket
+	// Overrides the repository's sshKey.
+	SSHKey *Secret
+	// Use the working tree as it is, including uncommitted changes, rather
+	// than the committed tree. Ignored files and the .git directory are
+	// excluded.
+	//
+	// Only supported for the head of a repository on the host.
+	IncludeUncommitted bool
 }
 
 // The filesystem tree at this ref.
@@ -1910,6 +2388,14 @@ func (r *GitRef) Tree(opts ...GitRefTreeOpts) *Directory {
 		if !querybuilder.IsZeroValue(opts[i].SSHAuthSocket) {
 			q = q.Arg("sshAuthSocket", opts[i].SSHAuthSocket)
 		}
+		// `sshKey` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSHKey) {
+			q = q.Arg("sshKey", opts[i].SSHKey)
+		}
+		// `includeUncommitted` optional argument
+		if !querybuilder.IsZeroValue(opts[i].IncludeUncommitted) {
+			q = q.Arg("includeUncommitted", opts[i].IncludeUncommitted)
+		}
 	}
 
 	return &Directory{
@@ -1918,10 +2404,47 @@ func (r *GitRef) Tree(opts ...GitRefTreeOpts) *Directory {
 	}
 }
 
+// A ref advertised by a remote git repository.
+type GitRemoteRef struct {
+	q *querybuilder.Selection
+	c graphql.Client
+
+	name *string
+	sha  *string
+}
+
+// The full name of the ref (e.g., "refs/tags/v0.3.9").
+func (r *GitRemoteRef) Name(ctx context.Context) (string, error) {
+	if r.name != nil {
+		return *r.name, nil
+	}
+	q := r.q.Select("name")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// The SHA of the commit the ref points to.
+func (r *GitRemoteRef) Sha(ctx context.Context) (string, error) {
+	if r.sha != nil {
+		return *r.sha, nil
+	}
+	q := r.q.Select("sha")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
 // A git repository.
 type GitRepository struct {
 	q *querybuilder.Selection
 	c graphql.Client
+
+	dirty *bool
 }
 
 // Returns details on one branch.
@@ -1956,6 +2479,88 @@ func (r *GitRepository) Commit(id string) *GitRef {
 	}
 }
 
+// Whether the working tree has uncommitted changes, including untracked
+// files which are not ignored.
+//
+// Only repositories on the host have a working tree.
+func (r *GitRepository) Dirty(ctx context.Context) (bool, error) {
+	if r.dirty != nil {
+		return *r.dirty, nil
+	}
+	q := r.q.Select("dirty")
+
+	var response bool
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx, r.c)
+}
+
+// Returns details on the repository's default branch (i.e. HEAD).
+func (r *GitRepository) Head() *GitRef {
+	q := r.q.Select("head")
+
+	return &GitRef{
+		q: q,
+		c: r.c,
+	}
+}
+
+// Returns details on any ref.
+func (r *GitRepository) Ref(name string) *GitRef {
+	q := r.q.Select("ref")
+	q = q.Arg("name", name)
+
+	return &GitRef{
+		q: q,
+		c: r.c,
+	}
+}
+
+// GitRepositoryRefsOpts contains options for GitRepository.Refs
+type GitRepositoryRefsOpts struct {
+	// Only list refs matching the pattern (e.g., "refs/tags/v*").
+	Pattern string
+}
+
+// Lists the refs on the repository, in the same way as `git ls-remote`.
+// Annotated tags are resolved to the commit they point to.
+func (r *GitRepository) Refs(ctx context.Context, opts ...GitRepositoryRefsOpts) ([]GitRemoteRef, error) {
+	q := r.q.Select("refs")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `pattern` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Pattern) {
+			q = q.Arg("pattern", opts[i].Pattern)
+		}
+	}
+
+	q = q.Select("name sha")
+
+	type refs struct {
+		Name string
+		Sha  string
+	}
+
+	convert := func(fields []refs) []GitRemoteRef {
+		out := []GitRemoteRef{}
+
+		for i := range fields {
+			out = append(out, GitRemoteRef{name: &fields[i].Name, sha: &fields[i].Sha})
+		}
+
+		return out
+	}
+	var response []refs
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Returns details on one tag.
 func (r *GitRepository) Tag(name string) *GitRef {
 	q := r.q.Select("tag")
@@ -1989,6 +2594,11 @@ type HostDirectoryOpts struct {
 	Exclude []string
 	// Include only artifacts that match the given pattern (e.g., ["app/", "package.*"]).
 	Include []string
+	// Exclude artifacts ignored by .gitignore files in the directory and its git repository.
+	Gitignore bool
+	// Watch the directory for changes, so that it is re-synced when loaded again
+	// after it has changed (e.g., by a pipeline re-run by `dagger watch`).
+	Watch bool
 }
 
 // Accesses a directory on the host.
@@ -2003,6 +2613,14 @@ func (r *Host) Directory(path string, opts ...HostDirectoryOpts) *Directory {
 		if !querybuilder.IsZeroValue(opts[i].Include) {
 			q = q.Arg("include", opts[i].Include)
 		}
+		// `gitignore` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Gitignore) {
+			q = q.Arg("gitignore", opts[i].Gitignore)
+		}
+		// `watch` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Watch) {
+			q = q.Arg("watch", opts[i].Watch)
+		}
 	}
 	q = q.Arg("path", path)
 
@@ -2034,6 +2652,20 @@ func (r *Host) File(path string) *File {
 	}
 }
 
+// Accesses a git repository on the host.
+//
+// Refs, history and trees are read from the repository's local .git directory
+// rather than fetched, and the repository's dirty state is known.
+func (r *Host) GitRepository(path string) *GitRepository {
+	q := r.q.Select("gitRepository")
+	q = q.Arg("path", path)
+
+	return &GitRepository{
+		q: q,
+		c: r.c,
+	}
+}
+
 // Accesses a Unix socket on the host.
 func (r *Host) UnixSocket(path string) *Socket {
 	q := r.q.Select("unixSocket")
@@ -2486,6 +3118,54 @@ func (r *Client) CacheVolume(key string) *CacheVolume {
 	}
 }
 
+// CacheVolumesOpts contains options for Query.CacheVolumes
+type CacheVolumesOpts struct {
+	// Only list the cache volumes with these checksums.
+	Checksums []string
+}
+
+// Lists the cache volumes which exist in the engine.
+//
+// Their keys aren't known, so they're identified by their checksum.
+func (r *Client) CacheVolumes(ctx context.Context, opts ...CacheVolumesOpts) ([]CacheVolume, error) {
+	q := r.q.Select("cacheVolumes")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `checksums` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Checksums) {
+			q = q.Arg("checksums", opts[i].Checksums)
+		}
+	}
+
+	q = q.Select("checksum id lastUsed size")
+
+	type cacheVolumes struct {
+		Checksum string
+		Id       CacheID
+		LastUsed int
+		Size     int
+	}
+
+	convert := func(fields []cacheVolumes) []CacheVolume {
+		out := []CacheVolume{}
+
+		for i := range fields {
+			out = append(out, CacheVolume{checksum: &fields[i].Checksum, id: &fields[i].Id, lastUsed: &fields[i].LastUsed, size: &fields[i].Size})
+		}
+
+		return out
+	}
+	var response []cacheVolumes
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // ContainerOpts contains options for Query.Container
 type ContainerOpts struct {
 	ID ContainerID
@@ -2563,6 +3243,25 @@ func (r *Client) File(id FileID) *File {
 type GitOpts struct {
 	// Set to true to keep .git directory.
 	KeepGitDir bool
+	// Number of commits of history to fetch, along with any tags, for tools like
+	// `git describe`. By default only the commit itself is fetched.
+	Depth int
+	// Set to true to leave submodules uninitialized rather than fetching them.
+	SkipSubmodules bool
+	// A secret token used to authenticate fetches over HTTPS.
+	HTTPAuthToken *Secret
+	// A secret containing the value of the Authorization header used to
+	// authenticate fetches over HTTPS (e.g., "Bearer <token>"), instead of
+	// httpAuthToken.
+	HTTPAuthHeader *Secret
+	// The SSH known hosts used to verify the host key of the repository when
+	// fetching over SSH. The host key isn't verified otherwise.
+	SSHKnownHosts string
+	// An SSH agent's socket used to authenticate fetches over SSH.
+	SSHAuthSocket *Socket
+	// A secret containing an SSH private key used to authenticate fetches over
+	// SSH, instead of an SSH agent's socket.
+	SSHKey *Secret
 	// A service which must be started before the repo is fetched.
 	ExperimentalServiceHost *Container
 }
@@ -2575,6 +3274,34 @@ func (r *Client) Git(url string, opts ...GitOpts) *GitRepository {
 		if !querybuilder.IsZeroValue(opts[i].KeepGitDir) {
 			q = q.Arg("keepGitDir", opts[i].KeepGitDir)
 		}
+		// `depth` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Depth) {
+			q = q.Arg("depth", opts[i].Depth)
+		}
+		// `skipSubmodules` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SkipSubmodules) {
+			q = q.Arg("skipSubmodules", opts[i].SkipSubmodules)
+		}
+		// `httpAuthToken` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPAuthToken) {
+			q = q.Arg("httpAuthToken", opts[i].HTTPAuthToken)
+		}
+		// `httpAuthHeader` optional argument
+		if !querybuilder.IsZeroValue(opts[i].HTTPAuthHeader) {
+			q = q.Arg("httpAuthHeader", opts[i].HTTPAuthHeader)
+		}
+		// `sshKnownHosts` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSHKnownHosts) {
+			q = q.Arg("sshKnownHosts", opts[i].SSHKnownHosts)
+		}
+		// `sshAuthSocket` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSHAuthSocket) {
+			q = q.Arg("sshAuthSocket", opts[i].SSHAuthSocket)
+		}
+		// `sshKey` optional argument
+		if !querybuilder.IsZeroValue(opts[i].SSHKey) {
+			q = q.Arg("sshKey", opts[i].SSHKey)
+		}
 		// `experimentalServiceHost` optional argument
 		if !querybuilder.IsZeroValue(opts[i].ExperimentalServiceHost) {
 			q = q.Arg("experimentalServiceHost", opts[i].ExperimentalServiceHost)
@@ -2600,6 +3327,18 @@ func (r *Client) Host() *Host {
 
 // HTTPOpts contains options for Query.HTTP
 type HTTPOpts struct {
+	// Digest of the content, which is verified once fetched, using sha256 or sha512
+	// (e.g., "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855").
+	Checksum string
+	// Additional headers to send with the request.
+	Headers []HTTPHeader
+	// A secret containing the value of the Authorization header to send with
+	// the request (e.g., "Bearer <token>").
+	AuthHeader *Secret
+	// Permissions of the file, as a decimal value between 0 and 511 (e.g., 493 for 0755). Defaults to 384 (0600).
+	Permissions int
+	// Name of the file. Defaults to a name derived from the URL.
+	Name string
 	// A service which must be started before the URL is fetched.
 	ExperimentalServiceHost *Container
 }
@@ -2608,6 +3347,26 @@ type HTTPOpts struct {
 func (r *Client) HTTP(url string, opts ...HTTPOpts) *File {
 	q := r.q.Select("http")
 	for i := len(opts) - 1; i >= 0; i-- {
+		// `checksum` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Checksum) {
+			q = q.Arg("checksum", opts[i].Checksum)
+		}
+		// `headers` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Headers) {
+			q = q.Arg("headers", opts[i].Headers)
+		}
+		// `authHeader` optional argument
+		if !querybuilder.IsZeroValue(opts[i].AuthHeader) {
+			q = q.Arg("authHeader", opts[i].AuthHeader)
+		}
+		// `permissions` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Permissions) {
+			q = q.Arg("permissions", opts[i].Permissions)
+		}
+		// `name` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Name) {
+			q = q.Arg("name", opts[i].Name)
+		}
 		// `experimentalServiceHost` optional argument
 		if !querybuilder.IsZeroValue(opts[i].ExperimentalServiceHost) {
 			q = q.Arg("experimentalServiceHost", opts[i].ExperimentalServiceHost)
@@ -2692,6 +3451,77 @@ func (r *Client) ProjectCommand(opts ...ProjectCommandOpts) *ProjectCommand {
 	}
 }
 
+// PruneEngineCacheOpts contains options for Query.PruneEngineCache
+type PruneEngineCacheOpts struct {
+	// Only delete records matching any of these filters.
+	//
+	// A filter is a comma-separated list of conditions which must all match,
+	// e.g. "type==exec.cachemount". The record types are regular (e.g. exec
+	// results and image layers), source.local, source.git.checkout,
+	// exec.cachemount, frontend and internal.
+	Filters []string
+	// Only delete records which haven't been used for this many seconds.
+	KeepDuration int
+	// Stop deleting records once the build cache takes up less than this many bytes.
+	KeepBytes int
+	// Also delete internal records, which are kept otherwise.
+	All bool
+}
+
+// Deletes records from the engine's build cache, the same way the engine's
+// garbage collection does, and returns the deleted records.
+//
+// Records which are in use are never deleted.
+func (r *Client) PruneEngineCache(ctx context.Context, opts ...PruneEngineCacheOpts) ([]EngineCacheRecord, error) {
+	q := r.q.Select("pruneEngineCache")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `filters` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Filters) {
+			q = q.Arg("filters", opts[i].Filters)
+		}
+		// `keepDuration` optional argument
+		if !querybuilder.IsZeroValue(opts[i].KeepDuration) {
+			q = q.Arg("keepDuration", opts[i].KeepDuration)
+		}
+		// `keepBytes` optional argument
+		if !querybuilder.IsZeroValue(opts[i].KeepBytes) {
+			q = q.Arg("keepBytes", opts[i].KeepBytes)
+		}
+		// `all` optional argument
+		if !querybuilder.IsZeroValue(opts[i].All) {
+			q = q.Arg("all", opts[i].All)
+		}
+	}
+
+	q = q.Select("description recordType size")
+
+	type pruneEngineCache struct {
+		Description string
+		RecordType  string
+		Size        int
+	}
+
+	convert := func(fields []pruneEngineCache) []EngineCacheRecord {
+		out := []EngineCacheRecord{}
+
+		for i := range fields {
+			out = append(out, EngineCacheRecord{description: &fields[i].Description, recordType: &fields[i].RecordType, size: &fields[i].Size})
+		}
+
+		return out
+	}
+	var response []pruneEngineCache
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx, r.c)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // Loads a secret from its ID.
 func (r *Client) Secret(id SecretID) *Secret {
 	q := r.q.Select("secret")
@@ -2838,6 +3668,14 @@ const (
 	Shared  CacheSharingMode = "SHARED"
 )
 
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "ADDED"
+	Deleted  ChangeKind = "DELETED"
+	Modified ChangeKind = "MODIFIED"
+)
+
 type ImageLayerCompression string
 
 const (
@@ -24,6 +24,7 @@ type Config struct {
 
 type ConnectParams struct {
 	Port         int    `json:"port"`
+	SocketPath   string `json:"socket_path,omitempty"`
 	SessionToken string `json:"session_token"`
 }
 
@@ -33,7 +34,7 @@ func Get(ctx context.Context, cfg *Config) (EngineConn, error) {
 		return cfg.Conn, nil
 	}
 
-	// Try DAGGER_SESSION_PORT next
+	// Try DAGGER_SESSION_SOCKET or DAGGER_SESSION_PORT next
 	conn, ok, err := FromSessionEnv()
 	if err != nil {
 		return nil, err
@@ -65,6 +66,9 @@ func Get(ctx context.Context, cfg *Config) (EngineConn, error) {
 func defaultHTTPClient(p *ConnectParams) *http.Client {
 	dialTransport := &http.Transport{
 		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			if p.SocketPath != "" {
+				return net.Dial("unix", p.SocketPath)
+			}
 			return net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", p.Port))
 		},
 	}
@@ -8,28 +8,34 @@ import (
 )
 
 func FromSessionEnv() (EngineConn, bool, error) {
-	portStr, ok := os.LookupEnv("DAGGER_SESSION_PORT")
-	if !ok {
-		return nil, false, nil
-	}
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return nil, false, fmt.Errorf("invalid port in DAGGER_SESSION_PORT: %w", err)
+	params := &ConnectParams{}
+	host := "dagger"
+
+	envVar := "DAGGER_SESSION_SOCKET"
+	if socketPath, ok := os.LookupEnv(envVar); ok {
+		params.SocketPath = socketPath
+	} else {
+		envVar = "DAGGER_SESSION_PORT"
+		portStr, ok := os.LookupEnv(envVar)
+		if !ok {
+			return nil, false, nil
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid port in DAGGER_SESSION_PORT: %w", err)
+		}
+		params.Port = port
+		host = fmt.Sprintf("127.0.0.1:%d", port)
 	}
 
-	sessionToken := os.Getenv("DAGGER_SESSION_TOKEN")
-	if sessionToken == "" {
-		return nil, false, fmt.Errorf("DAGGER_SESSION_TOKEN must be set when using DAGGER_SESSION_PORT")
+	params.SessionToken = os.Getenv("DAGGER_SESSION_TOKEN")
+	if params.SessionToken == "" {
+		return nil, false, fmt.Errorf("DAGGER_SESSION_TOKEN must be set when using %s", envVar)
 	}
 
-	httpClient := defaultHTTPClient(&ConnectParams{
-		Port:         port,
-		SessionToken: sessionToken,
-	})
-
 	return &sessionEnvConn{
-		Client: httpClient,
-		host:   fmt.Sprintf("127.0.0.1:%d", port),
+		Client: defaultHTTPClient(params),
+		host:   host,
 	}, true, nil
 }
 
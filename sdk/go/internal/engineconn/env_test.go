@@ -0,0 +1,61 @@
+package engineconn
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromSessionEnvSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "session.sock")
+	l, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	defer l.Close()
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, ok := r.BasicAuth()
+			if !ok || token != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, r.URL.Path)
+		}),
+	}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	t.Setenv("DAGGER_SESSION_PORT", "1234")
+	t.Setenv("DAGGER_SESSION_SOCKET", socketPath)
+	t.Setenv("DAGGER_SESSION_TOKEN", "hunter2")
+
+	conn, ok, err := FromSessionEnv()
+	require.NoError(t, err)
+	require.True(t, ok)
+	defer conn.Close()
+
+	// the socket takes precedence over the port
+	require.Equal(t, "dagger", conn.Host())
+
+	req, err := http.NewRequest("GET", "http://"+conn.Host()+"/query", nil)
+	require.NoError(t, err)
+	resp, err := conn.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "/query", string(body))
+}
+
+func TestFromSessionEnvSocketWithoutToken(t *testing.T) {
+	t.Setenv("DAGGER_SESSION_SOCKET", filepath.Join(t.TempDir(), "session.sock"))
+	t.Setenv("DAGGER_SESSION_TOKEN", "")
+
+	_, _, err := FromSessionEnv()
+	require.ErrorContains(t, err, "DAGGER_SESSION_SOCKET")
+}
@@ -47,6 +47,13 @@ func TestProvision(t *testing.T) {
 	}
 	os.Unsetenv("DAGGER_SESSION_PORT")
 
+	// ignore DAGGER_SESSION_SOCKET
+	origSessionSocket, sessionSocketSet := os.LookupEnv("DAGGER_SESSION_SOCKET")
+	if sessionSocketSet {
+		defer os.Setenv("DAGGER_SESSION_SOCKET", origSessionSocket)
+	}
+	os.Unsetenv("DAGGER_SESSION_SOCKET")
+
 	if cliURL := os.Getenv("_INTERNAL_DAGGER_TEST_CLI_URL"); cliURL != "" {
 		// If explicitly requested to test against a certain URL, use that
 		engineconn.OverrideCLIArchiveURL = cliURL
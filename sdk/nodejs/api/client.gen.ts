@@ -89,6 +89,25 @@ export enum CacheSharingMode {
    */
   Shared,
 }
+/**
+ * A kind of change made to a path.
+ */
+export enum ChangeKind {
+  /**
+   * The path was added.
+   */
+  Added,
+
+  /**
+   * The path was deleted.
+   */
+  Deleted,
+
+  /**
+   * The path was modified.
+   */
+  Modified,
+}
 export type ContainerBuildOpts = {
   /**
    * Path to the Dockerfile to use.
@@ -337,6 +356,11 @@ export type ContainerWithMountedCacheOpts = {
    * If the group is omitted, it defaults to the same as the user.
    */
   owner?: string
+
+  /**
+   * Mount as read-only, so that changes can't be made to it.
+   */
+  readOnly?: boolean
 }
 
 export type ContainerWithMountedDirectoryOpts = {
@@ -348,6 +372,11 @@ export type ContainerWithMountedDirectoryOpts = {
    * If the group is omitted, it defaults to the same as the user.
    */
   owner?: string
+
+  /**
+   * Mount as read-only, so that changes can't be made to it.
+   */
+  readOnly?: boolean
 }
 
 export type ContainerWithMountedFileOpts = {
@@ -359,6 +388,11 @@ export type ContainerWithMountedFileOpts = {
    * If the group is omitted, it defaults to the same as the user.
    */
   owner?: string
+
+  /**
+   * Mount as read-only, so that changes can't be made to it.
+   */
+  readOnly?: boolean
 }
 
 export type ContainerWithMountedSecretOpts = {
@@ -372,6 +406,13 @@ export type ContainerWithMountedSecretOpts = {
   owner?: string
 }
 
+export type ContainerWithMountedTempOpts = {
+  /**
+   * Maximum size of the temporary directory in bytes. Unlimited by default.
+   */
+  size?: number
+}
+
 export type ContainerWithNewFileOpts = {
   /**
    * Content of the file to write (e.g., "Hello world!").
@@ -461,6 +502,22 @@ export type DirectoryEntriesOpts = {
   path?: string
 }
 
+export type DirectoryExportOpts = {
+  /**
+   * Remove any files at the destination which are not present in this directory,
+   * so that it mirrors this directory exactly.
+   */
+  wipe?: boolean
+}
+
+export type DirectoryExportDryRunOpts = {
+  /**
+   * Include files at the destination which are not present in this directory,
+   * as they would be removed by an export with wipe set.
+   */
+  wipe?: boolean
+}
+
 export type DirectoryPipelineOpts = {
   /**
    * Pipeline description.
@@ -530,9 +587,56 @@ export type FileExportOpts = {
  */
 export type FileID = string & { __FileID: never }
 
+export type GitRefLogOpts = {
+  /**
+   * Maximum number of commits to list. Defaults to the entire history.
+   */
+  limit?: number
+}
+
 export type GitRefTreeOpts = {
+  /**
+   * Overrides the repository's sshKnownHosts.
+   */
   sshKnownHosts?: string
+
+  /**
+   * Overrides the repository's sshAuthSocket.
+   */
   sshAuthSocket?: Socket
+
+  /**
+   * Overrides the repository's sshKey.
+   */
+  sshKey?: Secret
+
+  /**
+   * Use the working tree as it is, including uncommitted changes, rather
+   * than the committed tree. Ignored files and the .git directory are
+   * excluded.
+   *
+   * Only supported for the head of a repository on the host.
+   */
+  includeUncommitted?: boolean
+}
+
+export type GitRepositoryRefsOpts = {
+  /**
+   * Only list refs matching the pattern (e.g., "refs/tags/v*").
+   */
+  pattern?: string
+}
+
+export type HTTPHeader = {
+  /**
+   * The header name.
+   */
+  name: string
+
+  /**
+   * The header value.
+   */
+  value: string
 }
 
 export type HostDirectoryOpts = {
@@ -545,6 +649,17 @@ export type HostDirectoryOpts = {
    * Include only artifacts that match the given pattern (e.g., ["app/", "package.*"]).
    */
   include?: string[]
+
+  /**
+   * Exclude artifacts ignored by .gitignore files in the directory and its git repository.
+   */
+  gitignore?: boolean
+
+  /**
+   * Watch the directory for changes, so that it is re-synced when loaded again
+   * after it has changed (e.g., by a pipeline re-run by `dagger watch`).
+   */
+  watch?: boolean
 }
 
 export type HostWorkdirOpts = {
@@ -616,6 +731,13 @@ export type ProjectCommandID = string & { __ProjectCommandID: never }
  */
 export type ProjectID = string & { __ProjectID: never }
 
+export type ClientCacheVolumesOpts = {
+  /**
+   * Only list the cache volumes with these checksums.
+   */
+  checksums?: string[]
+}
+
 export type ClientContainerOpts = {
   id?: ContainerID
   platform?: Platform
@@ -631,6 +753,46 @@ export type ClientGitOpts = {
    */
   keepGitDir?: boolean
 
+  /**
+   * Number of commits of history to fetch, along with any tags, for tools like
+   * `git describe`. By default only the commit itself is fetched.
+   */
+  depth?: number
+
+  /**
+   * Set to true to leave submodules uninitialized rather than fetching them.
+   */
+  skipSubmodules?: boolean
+
+  /**
+   * A secret token used to authenticate fetches over HTTPS.
+   */
+  httpAuthToken?: Secret
+
+  /**
+   * A secret containing the value of the Authorization header used to
+   * authenticate fetches over HTTPS (e.g., "Bearer <token>"), instead of
+   * httpAuthToken.
+   */
+  httpAuthHeader?: Secret
+
+  /**
+   * The SSH known hosts used to verify the host key of the repository when
+   * fetching over SSH. The host key isn't verified otherwise.
+   */
+  sshKnownHosts?: string
+
+  /**
+   * An SSH agent's socket used to authenticate fetches over SSH.
+   */
+  sshAuthSocket?: Socket
+
+  /**
+   * A secret containing an SSH private key used to authenticate fetches over
+   * SSH, instead of an SSH agent's socket.
+   */
+  sshKey?: Secret
+
   /**
    * A service which must be started before the repo is fetched.
    */
@@ -638,6 +800,33 @@ export type ClientGitOpts = {
 }
 
 export type ClientHttpOpts = {
+  /**
+   * Digest of the content, which is verified once fetched, using sha256 or sha512
+   * (e.g., "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855").
+   */
+  checksum?: string
+
+  /**
+   * Additional headers to send with the request.
+   */
+  headers?: HTTPHeader[]
+
+  /**
+   * A secret containing the value of the Authorization header to send with
+   * the request (e.g., "Bearer <token>").
+   */
+  authHeader?: Secret
+
+  /**
+   * Permissions of the file, as a decimal value between 0 and 511 (e.g., 493 for 0755). Defaults to 384 (0600).
+   */
+  permissions?: number
+
+  /**
+   * Name of the file. Defaults to a name derived from the URL.
+   */
+  name?: string
+
   /**
    * A service which must be started before the URL is fetched.
    */
@@ -664,6 +853,33 @@ export type ClientProjectCommandOpts = {
   id?: ProjectCommandID
 }
 
+export type ClientPruneEngineCacheOpts = {
+  /**
+   * Only delete records matching any of these filters.
+   *
+   * A filter is a comma-separated list of conditions which must all match,
+   * e.g. "type==exec.cachemount". The record types are regular (e.g. exec
+   * results and image layers), source.local, source.git.checkout,
+   * exec.cachemount, frontend and internal.
+   */
+  filters?: string[]
+
+  /**
+   * Only delete records which haven't been used for this many seconds.
+   */
+  keepDuration?: number
+
+  /**
+   * Stop deleting records once the build cache takes up less than this many bytes.
+   */
+  keepBytes?: number
+
+  /**
+   * Also delete internal records, which are kept otherwise.
+   */
+  all?: boolean
+}
+
 export type ClientSocketOpts = {
   id?: SocketID
 }
@@ -691,6 +907,22 @@ export type __TypeFieldsOpts = {
  */
 
 export class CacheVolume extends BaseClient {
+  /**
+   * A checksum of the cache volume's keys, which identifies it in the engine.
+   */
+  async checksum(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "checksum",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
   async id(): Promise<CacheID> {
     const response: Awaited<CacheID> = await computeQuery(
       [
@@ -705,6 +937,102 @@ export class CacheVolume extends BaseClient {
     return response
   }
 
+  /**
+   * When the cache volume was last used, as a Unix timestamp.
+   */
+  async lastUsed(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "lastUsed",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Deletes the cache volume's contents from the engine.
+   *
+   * Fails if the cache volume is mounted by a running container.
+   */
+  async prune(): Promise<CacheVolume> {
+    await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "prune",
+        },
+      ],
+      this.client
+    )
+
+    return this
+  }
+
+  /**
+   * The size of the cache volume's contents in bytes, or 0 if it doesn't exist yet.
+   */
+  async size(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "size",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Retrieves a directory with the cache volume's current contents.
+   *
+   * The contents are copied each time the directory is evaluated, so they may
+   * change between uses.
+   */
+  snapshot(): Directory {
+    return new Directory({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "snapshot",
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Retrieves this cache volume starting from the contents of a directory.
+   *
+   * Like mounting the cache volume with the directory as its source, the
+   * contents are copied when the cache volume is mounted without a source of its
+   * own. Each different directory gets a separate volume for the same key,
+   * seeded with that directory's contents; mounting the cache volume again with
+   * the same directory keeps the changes made to it since.
+   * @param source Identifier of the directory to copy into the cache volume.
+   */
+  withContents(source: Directory): CacheVolume {
+    return new CacheVolume({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "withContents",
+          args: { source },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
   /**
    * Chain objects together
    * @example
@@ -732,45 +1060,36 @@ export class CacheVolume extends BaseClient {
 }
 
 /**
- * An OCI-compatible container, also known as a docker container.
+ * A change made to a path in a filesystem tree.
  */
 
-export class Container extends BaseClient {
+export class Change extends BaseClient {
   /**
-   * Initializes this container from a Dockerfile build.
-   * @param context Directory context used by the Dockerfile.
-   * @param opts.dockerfile Path to the Dockerfile to use.
-   *
-   * Default: './Dockerfile'.
-   * @param opts.buildArgs Additional build arguments.
-   * @param opts.target Target build stage to build.
-   * @param opts.secrets Secrets to pass to the build.
-   *
-   * They will be mounted at /run/secrets/[secret-name].
+   * The kind of change.
    */
-  build(context: Directory, opts?: ContainerBuildOpts): Container {
-    return new Container({
-      queryTree: [
+  async kind(): Promise<ChangeKind> {
+    const response: Awaited<ChangeKind> = await computeQuery(
+      [
         ...this._queryTree,
         {
-          operation: "build",
-          args: { context, ...opts },
+          operation: "kind",
         },
       ],
-      host: this.clientHost,
-      sessionToken: this.sessionToken,
-    })
+      this.client
+    )
+
+    return response
   }
 
   /**
-   * Retrieves default arguments for future commands.
+   * Size of the file after the change, if it is a regular file.
    */
-  async defaultArgs(): Promise<string[]> {
-    const response: Awaited<string[]> = await computeQuery(
+  async newSize(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
       [
         ...this._queryTree,
         {
-          operation: "defaultArgs",
+          operation: "newSize",
         },
       ],
       this.client
@@ -780,24 +1099,135 @@ export class Container extends BaseClient {
   }
 
   /**
-   * Retrieves a directory at the given path.
-   *
-   * Mounts are included.
-   * @param path The path of the directory to retrieve (e.g., "./src").
+   * Size of the file before the change, if it was a regular file.
    */
-  directory(path: string): Directory {
-    return new Directory({
-      queryTree: [
+  async oldSize(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
+      [
         ...this._queryTree,
         {
-          operation: "directory",
-          args: { path },
+          operation: "oldSize",
         },
       ],
-      host: this.clientHost,
-      sessionToken: this.sessionToken,
-    })
-  }
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Location of the path that changed, relative to the root of the tree (e.g., "src/main.go").
+   */
+  async path(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "path",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Chain objects together
+   * @example
+   * ```ts
+   *	function AddAFewMounts(c) {
+   *			return c
+   *			.withMountedDirectory("/foo", new Client().host().directory("/Users/slumbering/forks/dagger"))
+   *			.withMountedDirectory("/bar", new Client().host().directory("/Users/slumbering/forks/dagger/sdk/nodejs"))
+   *	}
+   *
+   * connect(async (client) => {
+   *		const tree = await client
+   *			.container()
+   *			.from("alpine")
+   *			.withWorkdir("/foo")
+   *			.with(AddAFewMounts)
+   *			.withExec(["ls", "-lh"])
+   *			.stdout()
+   * })
+   *```
+   */
+  with(arg: (param: Change) => Change) {
+    return arg(this)
+  }
+}
+
+/**
+ * An OCI-compatible container, also known as a docker container.
+ */
+
+export class Container extends BaseClient {
+  /**
+   * Initializes this container from a Dockerfile build.
+   *
+   * Paths matched by a .dockerignore file in the context directory are excluded
+   * from the build context, as with `docker build`.
+   * @param context Directory context used by the Dockerfile.
+   * @param opts.dockerfile Path to the Dockerfile to use.
+   *
+   * Default: './Dockerfile'.
+   * @param opts.buildArgs Additional build arguments.
+   * @param opts.target Target build stage to build.
+   * @param opts.secrets Secrets to pass to the build.
+   *
+   * They will be mounted at /run/secrets/[secret-name].
+   */
+  build(context: Directory, opts?: ContainerBuildOpts): Container {
+    return new Container({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "build",
+          args: { context, ...opts },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Retrieves default arguments for future commands.
+   */
+  async defaultArgs(): Promise<string[]> {
+    const response: Awaited<string[]> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "defaultArgs",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Retrieves a directory at the given path.
+   *
+   * Mounts are included.
+   * @param path The path of the directory to retrieve (e.g., "./src").
+   */
+  directory(path: string): Directory {
+    return new Directory({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "directory",
+          args: { path },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
 
   /**
    * Retrieves an endpoint that clients can use to reach this container.
@@ -1552,6 +1982,7 @@ export class Container extends BaseClient {
    * The user and group can either be an ID (1000:1000) or a name (foo:bar).
    *
    * If the group is omitted, it defaults to the same as the user.
+   * @param opts.readOnly Mount as read-only, so that changes can't be made to it.
    */
   withMountedCache(
     path: string,
@@ -1580,6 +2011,7 @@ export class Container extends BaseClient {
    * The user and group can either be an ID (1000:1000) or a name (foo:bar).
    *
    * If the group is omitted, it defaults to the same as the user.
+   * @param opts.readOnly Mount as read-only, so that changes can't be made to it.
    */
   withMountedDirectory(
     path: string,
@@ -1608,6 +2040,7 @@ export class Container extends BaseClient {
    * The user and group can either be an ID (1000:1000) or a name (foo:bar).
    *
    * If the group is omitted, it defaults to the same as the user.
+   * @param opts.readOnly Mount as read-only, so that changes can't be made to it.
    */
   withMountedFile(
     path: string,
@@ -1658,14 +2091,18 @@ export class Container extends BaseClient {
   /**
    * Retrieves this container plus a temporary directory mounted at the given path.
    * @param path Location of the temporary directory (e.g., "/tmp/temp_dir").
+   * @param opts.size Maximum size of the temporary directory in bytes. Unlimited by default.
    */
-  withMountedTemp(path: string): Container {
+  withMountedTemp(
+    path: string,
+    opts?: ContainerWithMountedTempOpts
+  ): Container {
     return new Container({
       queryTree: [
         ...this._queryTree,
         {
           operation: "withMountedTemp",
-          args: { path },
+          args: { path, ...opts },
         },
       ],
       host: this.clientHost,
@@ -2016,6 +2453,46 @@ export class Container extends BaseClient {
  */
 
 export class Directory extends BaseClient {
+  /**
+   * Retrieves a file containing a unified diff of the changes from this directory
+   * to another directory, in the same format as `git diff --binary`, suitable for
+   * `git apply`.
+   * @param other Identifier of the directory to compare.
+   */
+  asPatch(other: Directory): File {
+    return new File({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "asPatch",
+          args: { other },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Lists the paths that were added, modified or deleted in another directory
+   * compared to this one.
+   * @param other Identifier of the directory to compare.
+   */
+  async changes(other: Directory): Promise<Change[]> {
+    const response: Awaited<Change[]> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "changes",
+          args: { other },
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
   /**
    * Gets the difference between this directory and an another directory.
    * @param other Identifier of the directory to compare.
@@ -2054,6 +2531,9 @@ export class Directory extends BaseClient {
 
   /**
    * Builds a new Docker container from this directory.
+   *
+   * Paths matched by a .dockerignore file in this directory are excluded from the
+   * build context, as with `docker build`.
    * @param opts.dockerfile Path to the Dockerfile to use (e.g., "frontend.Dockerfile").
    *
    * Defaults: './Dockerfile'.
@@ -2100,14 +2580,38 @@ export class Directory extends BaseClient {
   /**
    * Writes the contents of the directory to a path on the host.
    * @param path Location of the copied directory (e.g., "logs/").
+   * @param opts.wipe Remove any files at the destination which are not present in this directory,
+   * so that it mirrors this directory exactly.
    */
-  async export(path: string): Promise<boolean> {
+  async export(path: string, opts?: DirectoryExportOpts): Promise<boolean> {
     const response: Awaited<boolean> = await computeQuery(
       [
         ...this._queryTree,
         {
           operation: "export",
-          args: { path },
+          args: { path, ...opts },
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Lists the changes that writing the contents of the directory to a path on the
+   * host would make, without writing anything.
+   * @param path Location of the copied directory (e.g., "logs/").
+   * @param opts.wipe Include files at the destination which are not present in this directory,
+   * as they would be removed by an export with wipe set.
+   */
+  async exportDryRun(path: string, opts?: DirectoryExportDryRunOpts): Promise<Change[]> {
+    const response: Awaited<Change[]> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "exportDryRun",
+          args: { path, ...opts },
         },
       ],
       this.client
@@ -2314,13 +2818,300 @@ export class Directory extends BaseClient {
    * Retrieves this directory with the file at the given path removed.
    * @param path Location of the file to remove (e.g., "/file.txt").
    */
-  withoutFile(path: string): Directory {
-    return new Directory({
+  withoutFile(path: string): Directory {
+    return new Directory({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "withoutFile",
+          args: { path },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Chain objects together
+   * @example
+   * ```ts
+   *	function AddAFewMounts(c) {
+   *			return c
+   *			.withMountedDirectory("/foo", new Client().host().directory("/Users/slumbering/forks/dagger"))
+   *			.withMountedDirectory("/bar", new Client().host().directory("/Users/slumbering/forks/dagger/sdk/nodejs"))
+   *	}
+   *
+   * connect(async (client) => {
+   *		const tree = await client
+   *			.container()
+   *			.from("alpine")
+   *			.withWorkdir("/foo")
+   *			.with(AddAFewMounts)
+   *			.withExec(["ls", "-lh"])
+   *			.stdout()
+   * })
+   *```
+   */
+  with(arg: (param: Directory) => Directory) {
+    return arg(this)
+  }
+}
+
+/**
+ * A record in the engine's build cache.
+ */
+
+export class EngineCacheRecord extends BaseClient {
+  /**
+   * A description of the record's contents.
+   */
+  async description(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "description",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * The type of the record, e.g. regular or exec.cachemount.
+   */
+  async recordType(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "recordType",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * The size of the record in bytes.
+   */
+  async size(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "size",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Chain objects together
+   * @example
+   * ```ts
+   *	function AddAFewMounts(c) {
+   *			return c
+   *			.withMountedDirectory("/foo", new Client().host().directory("/Users/slumbering/forks/dagger"))
+   *			.withMountedDirectory("/bar", new Client().host().directory("/Users/slumbering/forks/dagger/sdk/nodejs"))
+   *	}
+   *
+   * connect(async (client) => {
+   *		const tree = await client
+   *			.container()
+   *			.from("alpine")
+   *			.withWorkdir("/foo")
+   *			.with(AddAFewMounts)
+   *			.withExec(["ls", "-lh"])
+   *			.stdout()
+   * })
+   *```
+   */
+  with(arg: (param: EngineCacheRecord) => EngineCacheRecord) {
+    return arg(this)
+  }
+}
+
+/**
+ * A simple key value object that represents an environment variable.
+ */
+
+export class EnvVariable extends BaseClient {
+  /**
+   * The environment variable name.
+   */
+  async name(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "name",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * The environment variable value.
+   */
+  async value(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "value",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Chain objects together
+   * @example
+   * ```ts
+   *	function AddAFewMounts(c) {
+   *			return c
+   *			.withMountedDirectory("/foo", new Client().host().directory("/Users/slumbering/forks/dagger"))
+   *			.withMountedDirectory("/bar", new Client().host().directory("/Users/slumbering/forks/dagger/sdk/nodejs"))
+   *	}
+   *
+   * connect(async (client) => {
+   *		const tree = await client
+   *			.container()
+   *			.from("alpine")
+   *			.withWorkdir("/foo")
+   *			.with(AddAFewMounts)
+   *			.withExec(["ls", "-lh"])
+   *			.stdout()
+   * })
+   *```
+   */
+  with(arg: (param: EnvVariable) => EnvVariable) {
+    return arg(this)
+  }
+}
+
+/**
+ * A file.
+ */
+
+export class File extends BaseClient {
+  /**
+   * Retrieves the contents of the file.
+   */
+  async contents(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "contents",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Writes the file to a file path on the host.
+   * @param path Location of the written directory (e.g., "output.txt").
+   * @param opts.allowParentDirPath If allowParentDirPath is true, the path argument can be a directory path, in which case
+   * the file will be created in that directory.
+   */
+  async export(path: string, opts?: FileExportOpts): Promise<boolean> {
+    const response: Awaited<boolean> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "export",
+          args: { path, ...opts },
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Retrieves the content-addressed identifier of the file.
+   */
+  async id(): Promise<FileID> {
+    const response: Awaited<FileID> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "id",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Retrieves a secret referencing the contents of this file.
+   * @deprecated insecure, leaves secret in cache. Superseded by setSecret
+   */
+  secret(): Secret {
+    return new Secret({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "secret",
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Gets the size of the file, in bytes.
+   */
+  async size(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "size",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Retrieves this file with its created/modified timestamps set to the given time.
+   * @param timestamp Timestamp to set dir/files in.
+   *
+   * Formatted in seconds following Unix epoch (e.g., 1672531199).
+   */
+  withTimestamps(timestamp: number): File {
+    return new File({
       queryTree: [
         ...this._queryTree,
         {
-          operation: "withoutFile",
-          args: { path },
+          operation: "withTimestamps",
+          args: { timestamp },
         },
       ],
       host: this.clientHost,
@@ -2349,25 +3140,25 @@ export class Directory extends BaseClient {
    * })
    *```
    */
-  with(arg: (param: Directory) => Directory) {
+  with(arg: (param: File) => File) {
     return arg(this)
   }
 }
 
 /**
- * A simple key value object that represents an environment variable.
+ * A commit in a git repository's history.
  */
 
-export class EnvVariable extends BaseClient {
+export class GitCommit extends BaseClient {
   /**
-   * The environment variable name.
+   * The author of the commit (e.g., "Jane Doe <jane@example.com>").
    */
-  async name(): Promise<string> {
+  async author(): Promise<string> {
     const response: Awaited<string> = await computeQuery(
       [
         ...this._queryTree,
         {
-          operation: "name",
+          operation: "author",
         },
       ],
       this.client
@@ -2377,14 +3168,48 @@ export class EnvVariable extends BaseClient {
   }
 
   /**
-   * The environment variable value.
+   * The full commit message.
    */
-  async value(): Promise<string> {
+  async message(): Promise<string> {
     const response: Awaited<string> = await computeQuery(
       [
         ...this._queryTree,
         {
-          operation: "value",
+          operation: "message",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * The SHA of the commit.
+   */
+  async sha(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "sha",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * The time the commit was authored, in seconds since the Unix epoch.
+   */
+  async timestamp(): Promise<number> {
+    const response: Awaited<number> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "timestamp",
         },
       ],
       this.client
@@ -2414,63 +3239,41 @@ export class EnvVariable extends BaseClient {
    * })
    *```
    */
-  with(arg: (param: EnvVariable) => EnvVariable) {
+  with(arg: (param: GitCommit) => GitCommit) {
     return arg(this)
   }
 }
 
 /**
- * A file.
+ * A git ref (tag, branch or commit).
  */
 
-export class File extends BaseClient {
-  /**
-   * Retrieves the contents of the file.
-   */
-  async contents(): Promise<string> {
-    const response: Awaited<string> = await computeQuery(
-      [
-        ...this._queryTree,
-        {
-          operation: "contents",
-        },
-      ],
-      this.client
-    )
-
-    return response
-  }
-
+export class GitRef extends BaseClient {
   /**
-   * Writes the file to a file path on the host.
-   * @param path Location of the written directory (e.g., "output.txt").
-   * @param opts.allowParentDirPath If allowParentDirPath is true, the path argument can be a directory path, in which case
-   * the file will be created in that directory.
+   * The commit this ref points to.
    */
-  async export(path: string, opts?: FileExportOpts): Promise<boolean> {
-    const response: Awaited<boolean> = await computeQuery(
-      [
+  commit(): GitCommit {
+    return new GitCommit({
+      queryTree: [
         ...this._queryTree,
         {
-          operation: "export",
-          args: { path, ...opts },
+          operation: "commit",
         },
       ],
-      this.client
-    )
-
-    return response
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
   }
 
   /**
-   * Retrieves the content-addressed identifier of the file.
+   * The digest of the current value of this ref.
    */
-  async id(): Promise<FileID> {
-    const response: Awaited<FileID> = await computeQuery(
+  async digest(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
       [
         ...this._queryTree,
         {
-          operation: "id",
+          operation: "digest",
         },
       ],
       this.client
@@ -2480,31 +3283,16 @@ export class File extends BaseClient {
   }
 
   /**
-   * Retrieves a secret referencing the contents of this file.
-   * @deprecated insecure, leaves secret in cache. Superseded by setSecret
-   */
-  secret(): Secret {
-    return new Secret({
-      queryTree: [
-        ...this._queryTree,
-        {
-          operation: "secret",
-        },
-      ],
-      host: this.clientHost,
-      sessionToken: this.sessionToken,
-    })
-  }
-
-  /**
-   * Gets the size of the file, in bytes.
+   * Lists the history of this ref, newest first.
+   * @param opts.limit Maximum number of commits to list. Defaults to the entire history.
    */
-  async size(): Promise<number> {
-    const response: Awaited<number> = await computeQuery(
+  async log(opts?: GitRefLogOpts): Promise<GitCommit[]> {
+    const response: Awaited<GitCommit[]> = await computeQuery(
       [
         ...this._queryTree,
         {
-          operation: "size",
+          operation: "log",
+          args: { ...opts },
         },
       ],
       this.client
@@ -2514,18 +3302,23 @@ export class File extends BaseClient {
   }
 
   /**
-   * Retrieves this file with its created/modified timestamps set to the given time.
-   * @param timestamp Timestamp to set dir/files in.
+   * The filesystem tree at this ref.
+   * @param opts.sshKnownHosts Overrides the repository's sshKnownHosts.
+   * @param opts.sshAuthSocket Overrides the repository's sshAuthSocket.
+   * @param opts.sshKey Overrides the repository's sshKey.
+   * @param opts.includeUncommitted Use the working tree as it is, including uncommitted changes, rather
+   * than the committed tree. Ignored files and the .git directory are
+   * excluded.
    *
-   * Formatted in seconds following Unix epoch (e.g., 1672531199).
+   * Only supported for the head of a repository on the host.
    */
-  withTimestamps(timestamp: number): File {
-    return new File({
+  tree(opts?: GitRefTreeOpts): Directory {
+    return new Directory({
       queryTree: [
         ...this._queryTree,
         {
-          operation: "withTimestamps",
-          args: { timestamp },
+          operation: "tree",
+          args: { ...opts },
         },
       ],
       host: this.clientHost,
@@ -2554,25 +3347,25 @@ export class File extends BaseClient {
    * })
    *```
    */
-  with(arg: (param: File) => File) {
+  with(arg: (param: GitRef) => GitRef) {
     return arg(this)
   }
 }
 
 /**
- * A git ref (tag, branch or commit).
+ * A ref advertised by a remote git repository.
  */
 
-export class GitRef extends BaseClient {
+export class GitRemoteRef extends BaseClient {
   /**
-   * The digest of the current value of this ref.
+   * The full name of the ref (e.g., "refs/tags/v0.3.9").
    */
-  async digest(): Promise<string> {
+  async name(): Promise<string> {
     const response: Awaited<string> = await computeQuery(
       [
         ...this._queryTree,
         {
-          operation: "digest",
+          operation: "name",
         },
       ],
       this.client
@@ -2582,20 +3375,20 @@ export class GitRef extends BaseClient {
   }
 
   /**
-   * The filesystem tree at this ref.
+   * The SHA of the commit the ref points to.
    */
-  tree(opts?: GitRefTreeOpts): Directory {
-    return new Directory({
-      queryTree: [
+  async sha(): Promise<string> {
+    const response: Awaited<string> = await computeQuery(
+      [
         ...this._queryTree,
         {
-          operation: "tree",
-          args: { ...opts },
+          operation: "sha",
         },
       ],
-      host: this.clientHost,
-      sessionToken: this.sessionToken,
-    })
+      this.client
+    )
+
+    return response
   }
 
   /**
@@ -2619,7 +3412,7 @@ export class GitRef extends BaseClient {
    * })
    *```
    */
-  with(arg: (param: GitRef) => GitRef) {
+  with(arg: (param: GitRemoteRef) => GitRemoteRef) {
     return arg(this)
   }
 }
@@ -2682,6 +3475,80 @@ export class GitRepository extends BaseClient {
     })
   }
 
+  /**
+   * Whether the working tree has uncommitted changes, including untracked
+   * files which are not ignored.
+   *
+   * Only repositories on the host have a working tree.
+   */
+  async dirty(): Promise<boolean> {
+    const response: Awaited<boolean> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "dirty",
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
+  /**
+   * Returns details on the repository's default branch (i.e. HEAD).
+   */
+  head(): GitRef {
+    return new GitRef({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "head",
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Returns details on any ref.
+   * @param name Fully qualified name of the ref (e.g., "refs/pull/123/head").
+   */
+  ref(name: string): GitRef {
+    return new GitRef({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "ref",
+          args: { name },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
+  /**
+   * Lists the refs on the repository, in the same way as `git ls-remote`.
+   * Annotated tags are resolved to the commit they point to.
+   * @param opts.pattern Only list refs matching the pattern (e.g., "refs/tags/v*").
+   */
+  async refs(opts?: GitRepositoryRefsOpts): Promise<GitRemoteRef[]> {
+    const response: Awaited<GitRemoteRef[]> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "refs",
+          args: { ...opts },
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
   /**
    * Returns details on one tag.
    * @param name Tag's name (e.g., "v0.3.9").
@@ -2753,6 +3620,9 @@ export class Host extends BaseClient {
    * @param path Location of the directory to access (e.g., ".").
    * @param opts.exclude Exclude artifacts that match the given pattern (e.g., ["node_modules/", ".git*"]).
    * @param opts.include Include only artifacts that match the given pattern (e.g., ["app/", "package.*"]).
+   * @param opts.gitignore Exclude artifacts ignored by .gitignore files in the directory and its git repository.
+   * @param opts.watch Watch the directory for changes, so that it is re-synced when loaded again
+   * after it has changed (e.g., by a pipeline re-run by `dagger watch`).
    */
   directory(path: string, opts?: HostDirectoryOpts): Directory {
     return new Directory({
@@ -2804,6 +3674,27 @@ export class Host extends BaseClient {
     })
   }
 
+  /**
+   * Accesses a git repository on the host.
+   *
+   * Refs, history and trees are read from the repository's local .git directory
+   * rather than fetched, and the repository's dirty state is known.
+   * @param path Location of the repository, or any directory within it (e.g., ".").
+   */
+  gitRepository(path: string): GitRepository {
+    return new GitRepository({
+      queryTree: [
+        ...this._queryTree,
+        {
+          operation: "gitRepository",
+          args: { path },
+        },
+      ],
+      host: this.clientHost,
+      sessionToken: this.sessionToken,
+    })
+  }
+
   /**
    * Accesses a Unix socket on the host.
    * @param path Location of the Unix socket (e.g., "/var/run/docker.sock").
@@ -3396,6 +4287,27 @@ export default class Client extends BaseClient {
     })
   }
 
+  /**
+   * Lists the cache volumes which exist in the engine.
+   *
+   * Their keys aren't known, so they're identified by their checksum.
+   * @param opts.checksums Only list the cache volumes with these checksums.
+   */
+  async cacheVolumes(opts?: ClientCacheVolumesOpts): Promise<CacheVolume[]> {
+    const response: Awaited<CacheVolume[]> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "cacheVolumes",
+          args: { ...opts },
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
   /**
    * Loads a container from ID.
    *
@@ -3474,6 +4386,18 @@ export default class Client extends BaseClient {
    * Can be formatted as https://{host}/{owner}/{repo}, git@{host}/{owner}/{repo}
    * Suffix ".git" is optional.
    * @param opts.keepGitDir Set to true to keep .git directory.
+   * @param opts.depth Number of commits of history to fetch, along with any tags, for tools like
+   * `git describe`. By default only the commit itself is fetched.
+   * @param opts.skipSubmodules Set to true to leave submodules uninitialized rather than fetching them.
+   * @param opts.httpAuthToken A secret token used to authenticate fetches over HTTPS.
+   * @param opts.httpAuthHeader A secret containing the value of the Authorization header used to
+   * authenticate fetches over HTTPS (e.g., "Bearer <token>"), instead of
+   * httpAuthToken.
+   * @param opts.sshKnownHosts The SSH known hosts used to verify the host key of the repository when
+   * fetching over SSH. The host key isn't verified otherwise.
+   * @param opts.sshAuthSocket An SSH agent's socket used to authenticate fetches over SSH.
+   * @param opts.sshKey A secret containing an SSH private key used to authenticate fetches over
+   * SSH, instead of an SSH agent's socket.
    * @param opts.experimentalServiceHost A service which must be started before the repo is fetched.
    */
   git(url: string, opts?: ClientGitOpts): GitRepository {
@@ -3509,6 +4433,13 @@ export default class Client extends BaseClient {
   /**
    * Returns a file containing an http remote url content.
    * @param url HTTP url to get the content from (e.g., "https://docs.dagger.io").
+   * @param opts.checksum Digest of the content, which is verified once fetched, using sha256 or sha512
+   * (e.g., "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855").
+   * @param opts.headers Additional headers to send with the request.
+   * @param opts.authHeader A secret containing the value of the Authorization header to send with
+   * the request (e.g., "Bearer <token>").
+   * @param opts.permissions Permissions of the file, as a decimal value between 0 and 511 (e.g., 493 for 0755). Defaults to 384 (0600).
+   * @param opts.name Name of the file. Defaults to a name derived from the URL.
    * @param opts.experimentalServiceHost A service which must be started before the URL is fetched.
    */
   http(url: string, opts?: ClientHttpOpts): File {
@@ -3579,6 +4510,36 @@ export default class Client extends BaseClient {
     })
   }
 
+  /**
+   * Deletes records from the engine's build cache, the same way the engine's
+   * garbage collection does, and returns the deleted records.
+   *
+   * Records which are in use are never deleted.
+   * @param opts.filters Only delete records matching any of these filters.
+   *
+   * A filter is a comma-separated list of conditions which must all match,
+   * e.g. "type==exec.cachemount". The record types are regular (e.g. exec
+   * results and image layers), source.local, source.git.checkout,
+   * exec.cachemount, frontend and internal.
+   * @param opts.keepDuration Only delete records which haven't been used for this many seconds.
+   * @param opts.keepBytes Stop deleting records once the build cache takes up less than this many bytes.
+   * @param opts.all Also delete internal records, which are kept otherwise.
+   */
+  async pruneEngineCache(opts?: ClientPruneEngineCacheOpts): Promise<EngineCacheRecord[]> {
+    const response: Awaited<EngineCacheRecord[]> = await computeQuery(
+      [
+        ...this._queryTree,
+        {
+          operation: "pruneEngineCache",
+          args: { ...opts },
+        },
+      ],
+      this.client
+    )
+
+    return response
+  }
+
   /**
    * Loads a secret from its ID.
    */
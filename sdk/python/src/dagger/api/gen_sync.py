@@ -63,6 +63,19 @@ class CacheSharingMode(Enum):
     """Shares the cache volume amongst many build pipelines"""
 
 
+class ChangeKind(Enum):
+    """A kind of change made to a path."""
+
+    ADDED = "ADDED"
+    """The path was added."""
+
+    DELETED = "DELETED"
+    """The path was deleted."""
+
+    MODIFIED = "MODIFIED"
+    """The path was modified."""
+
+
 class ImageLayerCompression(Enum):
     """Compression algorithm to use for image layers"""
 
@@ -96,6 +109,17 @@ class BuildArg(Input):
     """The build argument value."""
 
 
+@attrs.define
+class HTTPHeader(Input):
+    """A header to send with an HTTP request."""
+
+    name: str
+    """The header name."""
+
+    value: str
+    """The header value."""
+
+
 @attrs.define
 class PipelineLabel(Input):
     """Key value object that represents a Pipeline label."""
@@ -110,6 +134,29 @@ class PipelineLabel(Input):
 class CacheVolume(Type):
     """A directory whose contents persist across runs."""
 
+    @typecheck
+    def checksum(self) -> str:
+        """A checksum of the cache volume's keys, which identifies it in the
+        engine.
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("checksum", _args)
+        return _ctx.execute_sync(str)
+
     @typecheck
     def id(self) -> CacheID:
         """Note
@@ -132,6 +179,195 @@ class CacheVolume(Type):
         _ctx = self._select("id", _args)
         return _ctx.execute_sync(CacheID)
 
+    @typecheck
+    def last_used(self) -> Optional[int]:
+        """When the cache volume was last used, as a Unix timestamp.
+
+        Returns
+        -------
+        Optional[int]
+            The `Int` scalar type represents non-fractional signed whole
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("lastUsed", _args)
+        return _ctx.execute_sync(Optional[int])
+
+    @typecheck
+    def prune(self) -> "CacheVolume":
+        """Deletes the cache volume's contents from the engine.
+
+        Fails if the cache volume is mounted by a running container.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("prune", _args)
+        _ctx.execute_sync()
+        return self
+
+    @typecheck
+    def size(self) -> int:
+        """The size of the cache volume's contents in bytes, or 0 if it doesn't
+        exist yet.
+
+        Returns
+        -------
+        int
+            The `Int` scalar type represents non-fractional signed whole
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("size", _args)
+        return _ctx.execute_sync(int)
+
+    @typecheck
+    def snapshot(self) -> "Directory":
+        """Retrieves a directory with the cache volume's current contents.
+
+        The contents are copied each time the directory is evaluated, so they
+        may
+        change between uses.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("snapshot", _args)
+        return Directory(_ctx)
+
+    @typecheck
+    def with_contents(self, source: "Directory") -> "CacheVolume":
+        """Retrieves this cache volume starting from the contents of a directory.
+
+        Like mounting the cache volume with the directory as its source, the
+        contents are copied when the cache volume is mounted without a source
+        of its
+        own. Each different directory gets a separate volume for the same key,
+        seeded with that directory's contents; mounting the cache volume again
+        with
+        the same directory keeps the changes made to it since.
+
+        Parameters
+        ----------
+        source:
+            Identifier of the directory to copy into the cache volume.
+        """
+        _args = [
+            Arg("source", source),
+        ]
+        _ctx = self._select("withContents", _args)
+        return CacheVolume(_ctx)
+
+
+class Change(Type):
+    """A change made to a path in a filesystem tree."""
+
+    @typecheck
+    def kind(self) -> ChangeKind:
+        """The kind of change.
+
+        Returns
+        -------
+        ChangeKind
+            A kind of change made to a path.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("kind", _args)
+        return _ctx.execute_sync(ChangeKind)
+
+    @typecheck
+    def new_size(self) -> Optional[int]:
+        """Size of the file after the change, if it is a regular file.
+
+        Returns
+        -------
+        Optional[int]
+            The `Int` scalar type represents non-fractional signed whole
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("newSize", _args)
+        return _ctx.execute_sync(Optional[int])
+
+    @typecheck
+    def old_size(self) -> Optional[int]:
+        """Size of the file before the change, if it was a regular file.
+
+        Returns
+        -------
+        Optional[int]
+            The `Int` scalar type represents non-fractional signed whole
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("oldSize", _args)
+        return _ctx.execute_sync(Optional[int])
+
+    @typecheck
+    def path(self) -> str:
+        """Location of the path that changed, relative to the root of the tree
+        (e.g., "src/main.go").
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("path", _args)
+        return _ctx.execute_sync(str)
+
 
 class Container(Type):
     """An OCI-compatible container, also known as a docker container."""
@@ -147,6 +383,10 @@ class Container(Type):
     ) -> "Container":
         """Initializes this container from a Dockerfile build.
 
+        Paths matched by a .dockerignore file in the context directory are
+        excluded
+        from the build context, as with `docker build`.
+
         Parameters
         ----------
         context:
@@ -376,8 +616,8 @@ class Container(Type):
         -------
         int
             The `Int` scalar type represents non-fractional signed whole
-            numeric values. Int can represent values between -(2^31) and 2^31
-            - 1.
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
 
         Raises
         ------
@@ -1134,6 +1374,7 @@ class Container(Type):
         source: Optional["Directory"] = None,
         sharing: Optional[CacheSharingMode] = None,
         owner: Optional[str] = None,
+        read_only: Optional[bool] = None,
     ) -> "Container":
         """Retrieves this container plus a cache volume mounted at the given
         path.
@@ -1158,6 +1399,8 @@ class Container(Type):
             The user and group can either be an ID (1000:1000) or a name
             (foo:bar).
             If the group is omitted, it defaults to the same as the user.
+        read_only:
+            Mount as read-only, so that changes can't be made to it.
         """
         _args = [
             Arg("path", path),
@@ -1165,6 +1408,7 @@ class Container(Type):
             Arg("source", source, None),
             Arg("sharing", sharing, None),
             Arg("owner", owner, None),
+            Arg("readOnly", read_only, None),
         ]
         _ctx = self._select("withMountedCache", _args)
         return Container(_ctx)
@@ -1175,6 +1419,7 @@ class Container(Type):
         path: str,
         source: "Directory",
         owner: Optional[str] = None,
+        read_only: Optional[bool] = None,
     ) -> "Container":
         """Retrieves this container plus a directory mounted at the given path.
 
@@ -1189,11 +1434,14 @@ class Container(Type):
             The user and group can either be an ID (1000:1000) or a name
             (foo:bar).
             If the group is omitted, it defaults to the same as the user.
+        read_only:
+            Mount as read-only, so that changes can't be made to it.
         """
         _args = [
             Arg("path", path),
             Arg("source", source),
             Arg("owner", owner, None),
+            Arg("readOnly", read_only, None),
         ]
         _ctx = self._select("withMountedDirectory", _args)
         return Container(_ctx)
@@ -1204,6 +1452,7 @@ class Container(Type):
         path: str,
         source: "File",
         owner: Optional[str] = None,
+        read_only: Optional[bool] = None,
     ) -> "Container":
         """Retrieves this container plus a file mounted at the given path.
 
@@ -1218,11 +1467,14 @@ class Container(Type):
             The user and group can either be an ID (1000:1000) or a name
             (foo:bar).
             If the group is omitted, it defaults to the same as the user.
+        read_only:
+            Mount as read-only, so that changes can't be made to it.
         """
         _args = [
             Arg("path", path),
             Arg("source", source),
             Arg("owner", owner, None),
+            Arg("readOnly", read_only, None),
         ]
         _ctx = self._select("withMountedFile", _args)
         return Container(_ctx)
@@ -1258,7 +1510,11 @@ class Container(Type):
         return Container(_ctx)
 
     @typecheck
-    def with_mounted_temp(self, path: str) -> "Container":
+    def with_mounted_temp(
+        self,
+        path: str,
+        size: Optional[int] = None,
+    ) -> "Container":
         """Retrieves this container plus a temporary directory mounted at the
         given path.
 
@@ -1266,9 +1522,13 @@ class Container(Type):
         ----------
         path:
             Location of the temporary directory (e.g., "/tmp/temp_dir").
+        size:
+            Maximum size of the temporary directory in bytes. Unlimited by
+            default.
         """
         _args = [
             Arg("path", path),
+            Arg("size", size, None),
         ]
         _ctx = self._select("withMountedTemp", _args)
         return Container(_ctx)
@@ -1586,6 +1846,42 @@ class Container(Type):
 class Directory(Type):
     """A directory."""
 
+    @typecheck
+    def as_patch(self, other: "Directory") -> "File":
+        """Retrieves a file containing a unified diff of the changes from this
+        directory
+        to another directory, in the same format as `git diff --binary`,
+        suitable for
+        `git apply`.
+
+        Parameters
+        ----------
+        other:
+            Identifier of the directory to compare.
+        """
+        _args = [
+            Arg("other", other),
+        ]
+        _ctx = self._select("asPatch", _args)
+        return File(_ctx)
+
+    @typecheck
+    def changes(self, other: "Directory") -> Change:
+        """Lists the paths that were added, modified or deleted in another
+        directory
+        compared to this one.
+
+        Parameters
+        ----------
+        other:
+            Identifier of the directory to compare.
+        """
+        _args = [
+            Arg("other", other),
+        ]
+        _ctx = self._select("changes", _args)
+        return Change(_ctx)
+
     @typecheck
     def diff(self, other: "Directory") -> "Directory":
         """Gets the difference between this directory and an another directory.
@@ -1627,6 +1923,10 @@ class Directory(Type):
     ) -> Container:
         """Builds a new Docker container from this directory.
 
+        Paths matched by a .dockerignore file in this directory are excluded
+        from the
+        build context, as with `docker build`.
+
         Parameters
         ----------
         dockerfile:
@@ -1682,13 +1982,21 @@ class Directory(Type):
         return _ctx.execute_sync(list[str])
 
     @typecheck
-    def export(self, path: str) -> bool:
+    def export(
+        self,
+        path: str,
+        wipe: Optional[bool] = None,
+    ) -> bool:
         """Writes the contents of the directory to a path on the host.
 
         Parameters
         ----------
         path:
             Location of the copied directory (e.g., "logs/").
+        wipe:
+            Remove any files at the destination which are not present in this
+            directory,
+            so that it mirrors this directory exactly.
 
         Returns
         -------
@@ -1704,10 +2012,37 @@ class Directory(Type):
         """
         _args = [
             Arg("path", path),
+            Arg("wipe", wipe, None),
         ]
         _ctx = self._select("export", _args)
         return _ctx.execute_sync(bool)
 
+    @typecheck
+    def export_dry_run(
+        self,
+        path: str,
+        wipe: Optional[bool] = None,
+    ) -> Change:
+        """Lists the changes that writing the contents of the directory to a path
+        on the
+        host would make, without writing anything.
+
+        Parameters
+        ----------
+        path:
+            Location of the copied directory (e.g., "logs/").
+        wipe:
+            Include files at the destination which are not present in this
+            directory,
+            as they would be removed by an export with wipe set.
+        """
+        _args = [
+            Arg("path", path),
+            Arg("wipe", wipe, None),
+        ]
+        _ctx = self._select("exportDryRun", _args)
+        return Change(_ctx)
+
     @typecheck
     def file(self, path: str) -> "File":
         """Retrieves a file at the given path.
@@ -1932,6 +2267,76 @@ class Directory(Type):
         return Directory(_ctx)
 
 
+class EngineCacheRecord(Type):
+    """A record in the engine's build cache."""
+
+    @typecheck
+    def description(self) -> str:
+        """A description of the record's contents.
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("description", _args)
+        return _ctx.execute_sync(str)
+
+    @typecheck
+    def record_type(self) -> str:
+        """The type of the record, e.g. regular or exec.cachemount.
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("recordType", _args)
+        return _ctx.execute_sync(str)
+
+    @typecheck
+    def size(self) -> int:
+        """The size of the record in bytes.
+
+        Returns
+        -------
+        int
+            The `Int` scalar type represents non-fractional signed whole
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("size", _args)
+        return _ctx.execute_sync(int)
+
+
 class EnvVariable(Type):
     """A simple key value object that represents an environment
     variable."""
@@ -2094,8 +2499,8 @@ class File(Type):
         -------
         int
             The `Int` scalar type represents non-fractional signed whole
-            numeric values. Int can represent values between -(2^31) and 2^31
-            - 1.
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
 
         Raises
         ------
@@ -2126,9 +2531,108 @@ class File(Type):
         return File(_ctx)
 
 
+class GitCommit(Type):
+    """A commit in a git repository's history."""
+
+    @typecheck
+    def author(self) -> str:
+        """The author of the commit (e.g., "Jane Doe <jane@example.com>").
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("author", _args)
+        return _ctx.execute_sync(str)
+
+    @typecheck
+    def message(self) -> str:
+        """The full commit message.
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("message", _args)
+        return _ctx.execute_sync(str)
+
+    @typecheck
+    def sha(self) -> str:
+        """The SHA of the commit.
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("sha", _args)
+        return _ctx.execute_sync(str)
+
+    @typecheck
+    def timestamp(self) -> int:
+        """The time the commit was authored, in seconds since the Unix epoch.
+
+        Returns
+        -------
+        int
+            The `Int` scalar type represents non-fractional signed whole
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("timestamp", _args)
+        return _ctx.execute_sync(int)
+
+
 class GitRef(Type):
     """A git ref (tag, branch or commit)."""
 
+    @typecheck
+    def commit(self) -> GitCommit:
+        """The commit this ref points to."""
+        _args: list[Arg] = []
+        _ctx = self._select("commit", _args)
+        return GitCommit(_ctx)
+
     @typecheck
     def digest(self) -> str:
         """The digest of the current value of this ref.
@@ -2151,21 +2655,104 @@ class GitRef(Type):
         _ctx = self._select("digest", _args)
         return _ctx.execute_sync(str)
 
+    @typecheck
+    def log(self, limit: Optional[int] = None) -> GitCommit:
+        """Lists the history of this ref, newest first.
+
+        Parameters
+        ----------
+        limit:
+            Maximum number of commits to list. Defaults to the entire history.
+        """
+        _args = [
+            Arg("limit", limit, None),
+        ]
+        _ctx = self._select("log", _args)
+        return GitCommit(_ctx)
+
     @typecheck
     def tree(
         self,
         ssh_known_hosts: Optional[str] = None,
         ssh_auth_socket: Optional["Socket"] = None,
+        ssh_key: Optional["Secret"] = None,
+        include_uncommitted: Optional[bool] = None,
     ) -> Directory:
-        """The filesystem tree at this ref."""
+        """The filesystem tree at this ref.
+
+        Parameters
+        ----------
+        ssh_known_hosts:
+            Overrides the repository's sshKnownHosts.
+        ssh_auth_socket:
+            Overrides the repository's sshAuthSocket.
+        ssh_key:
+            Overrides the repository's sshKey.
+        include_uncommitted:
+            Use the working tree as it is, including uncommitted changes,
+            rather
+            than the committed tree. Ignored files and the .git directory are
+            excluded.
+            Only supported for the head of a repository on the host.
+        """
         _args = [
             Arg("sshKnownHosts", ssh_known_hosts, None),
             Arg("sshAuthSocket", ssh_auth_socket, None),
+            Arg("sshKey", ssh_key, None),
+            Arg("includeUncommitted", include_uncommitted, None),
         ]
         _ctx = self._select("tree", _args)
         return Directory(_ctx)
 
 
+class GitRemoteRef(Type):
+    """A ref advertised by a remote git repository."""
+
+    @typecheck
+    def name(self) -> str:
+        """The full name of the ref (e.g., "refs/tags/v0.3.9").
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("name", _args)
+        return _ctx.execute_sync(str)
+
+    @typecheck
+    def sha(self) -> str:
+        """The SHA of the commit the ref points to.
+
+        Returns
+        -------
+        str
+            The `String` scalar type represents textual data, represented as
+            UTF-8 character sequences. The String type is most often used by
+            GraphQL to represent free-form human-readable text.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("sha", _args)
+        return _ctx.execute_sync(str)
+
+
 class GitRepository(Type):
     """A git repository."""
 
@@ -2222,6 +2809,67 @@ class GitRepository(Type):
         _ctx = self._select("commit", _args)
         return GitRef(_ctx)
 
+    @typecheck
+    def dirty(self) -> bool:
+        """Whether the working tree has uncommitted changes, including untracked
+        files which are not ignored.
+
+        Only repositories on the host have a working tree.
+
+        Returns
+        -------
+        bool
+            The `Boolean` scalar type represents `true` or `false`.
+
+        Raises
+        ------
+        ExecuteTimeoutError
+            If the time to execute the query exceeds the configured timeout.
+        QueryError
+            If the API returns an error.
+        """
+        _args: list[Arg] = []
+        _ctx = self._select("dirty", _args)
+        return _ctx.execute_sync(bool)
+
+    @typecheck
+    def head(self) -> GitRef:
+        """Returns details on the repository's default branch (i.e. HEAD)."""
+        _args: list[Arg] = []
+        _ctx = self._select("head", _args)
+        return GitRef(_ctx)
+
+    @typecheck
+    def ref(self, name: str) -> GitRef:
+        """Returns details on any ref.
+
+        Parameters
+        ----------
+        name:
+            Fully qualified name of the ref (e.g., "refs/pull/123/head").
+        """
+        _args = [
+            Arg("name", name),
+        ]
+        _ctx = self._select("ref", _args)
+        return GitRef(_ctx)
+
+    @typecheck
+    def refs(self, pattern: Optional[str] = None) -> GitRemoteRef:
+        """Lists the refs on the repository, in the same way as `git ls-remote`.
+        Annotated tags are resolved to the commit they point to.
+
+        Parameters
+        ----------
+        pattern:
+            Only list refs matching the pattern (e.g., "refs/tags/v*").
+        """
+        _args = [
+            Arg("pattern", pattern, None),
+        ]
+        _ctx = self._select("refs", _args)
+        return GitRemoteRef(_ctx)
+
     @typecheck
     def tag(self, name: str) -> GitRef:
         """Returns details on one tag.
@@ -2269,6 +2917,8 @@ class Host(Type):
         path: str,
         exclude: Optional[Sequence[str]] = None,
         include: Optional[Sequence[str]] = None,
+        gitignore: Optional[bool] = None,
+        watch: Optional[bool] = None,
     ) -> Directory:
         """Accesses a directory on the host.
 
@@ -2282,11 +2932,21 @@ class Host(Type):
         include:
             Include only artifacts that match the given pattern (e.g.,
             ["app/", "package.*"]).
+        gitignore:
+            Exclude artifacts ignored by .gitignore files in the directory and
+            its git repository.
+        watch:
+            Watch the directory for changes, so that it is re-synced when
+            loaded again
+            after it has changed (e.g., by a pipeline re-run by `dagger
+            watch`).
         """
         _args = [
             Arg("path", path),
             Arg("exclude", exclude, None),
             Arg("include", include, None),
+            Arg("gitignore", gitignore, None),
+            Arg("watch", watch, None),
         ]
         _ctx = self._select("directory", _args)
         return Directory(_ctx)
@@ -2321,6 +2981,26 @@ class Host(Type):
         _ctx = self._select("file", _args)
         return File(_ctx)
 
+    @typecheck
+    def git_repository(self, path: str) -> GitRepository:
+        """Accesses a git repository on the host.
+
+        Refs, history and trees are read from the repository's local .git
+        directory
+        rather than fetched, and the repository's dirty state is known.
+
+        Parameters
+        ----------
+        path:
+            Location of the repository, or any directory within it (e.g.,
+            ".").
+        """
+        _args = [
+            Arg("path", path),
+        ]
+        _ctx = self._select("gitRepository", _args)
+        return GitRepository(_ctx)
+
     @typecheck
     def unix_socket(self, path: str) -> "Socket":
         """Accesses a Unix socket on the host.
@@ -2495,8 +3175,8 @@ class Port(Type):
         -------
         int
             The `Int` scalar type represents non-fractional signed whole
-            numeric values. Int can represent values between -(2^31) and 2^31
-            - 1.
+            numeric values. Int can represent values between  -(2^53  1) and
+            2^53 - 1.
 
         Raises
         ------
@@ -2775,6 +3455,26 @@ class Client(Root):
         _ctx = self._select("cacheVolume", _args)
         return CacheVolume(_ctx)
 
+    @typecheck
+    def cache_volumes(
+        self,
+        checksums: Optional[Sequence[str]] = None,
+    ) -> CacheVolume:
+        """Lists the cache volumes which exist in the engine.
+
+        Their keys aren't known, so they're identified by their checksum.
+
+        Parameters
+        ----------
+        checksums:
+            Only list the cache volumes with these checksums.
+        """
+        _args = [
+            Arg("checksums", checksums, None),
+        ]
+        _ctx = self._select("cacheVolumes", _args)
+        return CacheVolume(_ctx)
+
     @typecheck
     def container(
         self,
@@ -2840,6 +3540,13 @@ class Client(Root):
         self,
         url: str,
         keep_git_dir: Optional[bool] = None,
+        depth: Optional[int] = None,
+        skip_submodules: Optional[bool] = None,
+        http_auth_token: Optional["Secret"] = None,
+        http_auth_header: Optional["Secret"] = None,
+        ssh_known_hosts: Optional[str] = None,
+        ssh_auth_socket: Optional["Socket"] = None,
+        ssh_key: Optional["Secret"] = None,
         experimental_service_host: Optional[Container] = None,
     ) -> GitRepository:
         """Queries a git repository.
@@ -2853,12 +3560,43 @@ class Client(Root):
             Suffix ".git" is optional.
         keep_git_dir:
             Set to true to keep .git directory.
+        depth:
+            Number of commits of history to fetch, along with any tags, for
+            tools like
+            `git describe`. By default only the commit itself is fetched.
+        skip_submodules:
+            Set to true to leave submodules uninitialized rather than fetching
+            them.
+        http_auth_token:
+            A secret token used to authenticate fetches over HTTPS.
+        http_auth_header:
+            A secret containing the value of the Authorization header used to
+            authenticate fetches over HTTPS (e.g., "Bearer <token>"), instead
+            of
+            httpAuthToken.
+        ssh_known_hosts:
+            The SSH known hosts used to verify the host key of the repository
+            when
+            fetching over SSH. The host key isn't verified otherwise.
+        ssh_auth_socket:
+            An SSH agent's socket used to authenticate fetches over SSH.
+        ssh_key:
+            A secret containing an SSH private key used to authenticate
+            fetches over
+            SSH, instead of an SSH agent's socket.
         experimental_service_host:
             A service which must be started before the repo is fetched.
         """
         _args = [
             Arg("url", url),
             Arg("keepGitDir", keep_git_dir, None),
+            Arg("depth", depth, None),
+            Arg("skipSubmodules", skip_submodules, None),
+            Arg("httpAuthToken", http_auth_token, None),
+            Arg("httpAuthHeader", http_auth_header, None),
+            Arg("sshKnownHosts", ssh_known_hosts, None),
+            Arg("sshAuthSocket", ssh_auth_socket, None),
+            Arg("sshKey", ssh_key, None),
             Arg("experimentalServiceHost", experimental_service_host, None),
         ]
         _ctx = self._select("git", _args)
@@ -2875,6 +3613,11 @@ class Client(Root):
     def http(
         self,
         url: str,
+        checksum: Optional[str] = None,
+        headers: Optional[Sequence[HTTPHeader]] = None,
+        auth_header: Optional["Secret"] = None,
+        permissions: Optional[int] = None,
+        name: Optional[str] = None,
         experimental_service_host: Optional[Container] = None,
     ) -> File:
         """Returns a file containing an http remote url content.
@@ -2883,11 +3626,32 @@ class Client(Root):
         ----------
         url:
             HTTP url to get the content from (e.g., "https://docs.dagger.io").
+        checksum:
+            Digest of the content, which is verified once fetched, using
+            sha256 or sha512
+            (e.g., "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca49
+            5991b7852b855").
+        headers:
+            Additional headers to send with the request.
+        auth_header:
+            A secret containing the value of the Authorization header to send
+            with
+            the request (e.g., "Bearer <token>").
+        permissions:
+            Permissions of the file, as a decimal value between 0 and 511
+            (e.g., 493 for 0755). Defaults to 384 (0600).
+        name:
+            Name of the file. Defaults to a name derived from the URL.
         experimental_service_host:
             A service which must be started before the URL is fetched.
         """
         _args = [
             Arg("url", url),
+            Arg("checksum", checksum, None),
+            Arg("headers", headers, None),
+            Arg("authHeader", auth_header, None),
+            Arg("permissions", permissions, None),
+            Arg("name", name, None),
             Arg("experimentalServiceHost", experimental_service_host, None),
         ]
         _ctx = self._select("http", _args)
@@ -2940,6 +3704,47 @@ class Client(Root):
         _ctx = self._select("projectCommand", _args)
         return ProjectCommand(_ctx)
 
+    @typecheck
+    def prune_engine_cache(
+        self,
+        filters: Optional[Sequence[str]] = None,
+        keep_duration: Optional[int] = None,
+        keep_bytes: Optional[int] = None,
+        all: Optional[bool] = None,
+    ) -> EngineCacheRecord:
+        """Deletes records from the engine's build cache, the same way the
+        engine's
+        garbage collection does, and returns the deleted records.
+
+        Records which are in use are never deleted.
+
+        Parameters
+        ----------
+        filters:
+            Only delete records matching any of these filters.
+            A filter is a comma-separated list of conditions which must all
+            match,
+            e.g. "type==exec.cachemount". The record types are regular (e.g.
+            exec
+            results and image layers), source.local, source.git.checkout,
+            exec.cachemount, frontend and internal.
+        keep_duration:
+            Only delete records which haven't been used for this many seconds.
+        keep_bytes:
+            Stop deleting records once the build cache takes up less than this
+            many bytes.
+        all:
+            Also delete internal records, which are kept otherwise.
+        """
+        _args = [
+            Arg("filters", filters, None),
+            Arg("keepDuration", keep_duration, None),
+            Arg("keepBytes", keep_bytes, None),
+            Arg("all", all, None),
+        ]
+        _ctx = self._select("pruneEngineCache", _args)
+        return EngineCacheRecord(_ctx)
+
     @typecheck
     def secret(self, id: SecretID) -> "Secret":
         """Loads a secret from its ID."""
@@ -3067,16 +3872,22 @@ __all__ = [
     "SecretID",
     "SocketID",
     "CacheSharingMode",
+    "ChangeKind",
     "ImageLayerCompression",
     "NetworkProtocol",
     "BuildArg",
+    "HTTPHeader",
     "PipelineLabel",
     "CacheVolume",
+    "Change",
     "Container",
     "Directory",
+    "EngineCacheRecord",
     "EnvVariable",
     "File",
+    "GitCommit",
     "GitRef",
+    "GitRemoteRef",
     "GitRepository",
     "Host",
     "HostVariable",
@@ -2,7 +2,7 @@ package secret
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/dagger/dagger/core"
@@ -11,7 +11,10 @@ import (
 )
 
 // ErrNotFound indicates a secret can not be found.
-var ErrNotFound = errors.New("secret not found")
+//
+// It wraps buildkit's error so that lookups which are allowed to fail, like
+// the per-host git auth secrets, are reported as such across the session.
+var ErrNotFound = fmt.Errorf("secret %w", secrets.ErrNotFound)
 
 func NewStore() *Store {
 	return &Store{